@@ -1,7 +1,8 @@
 const { createApp, reactive } = Vue;
+const hasPermission = (user, permission) => !!(user && user.role && (user.role.permissions || []).includes(permission));
 const api = {
     baseURL: 'http://localhost:8080/api',
-    async request(endpoint, options = {}) {
+    async request(endpoint, options = {}, retried = false) {
         const config = { headers: { 'Content-Type': 'application/json', ...options.headers }, ...options };
         const token = localStorage.getItem('authToken');
         if (token) config.headers['Authorization'] = `Bearer ${token}`;
@@ -9,7 +10,25 @@ const api = {
             const response = await axios({ url: `${this.baseURL}${endpoint}`, ...config });
             return response.data;
         } catch (error) {
-            throw new Error(error.response?.data?.message || error.message);
+            if (error.response?.status === 401 && !retried && await this.refresh()) {
+                return this.request(endpoint, options, true);
+            }
+            const data = error.response?.data;
+            const details = data?.details ? Object.values(data.details).join('; ') : null;
+            throw new Error(details || data?.message || error.message);
+        }
+    },
+    async refresh() {
+        const refreshToken = localStorage.getItem('refreshToken');
+        if (!refreshToken) return false;
+        try {
+            const response = await axios.post(`${this.baseURL}/auth/refresh`, { refresh_token: refreshToken });
+            localStorage.setItem('authToken', response.data.token);
+            localStorage.setItem('refreshToken', response.data.refresh_token);
+            return true;
+        } catch (error) {
+            localStorage.removeItem('authToken'); localStorage.removeItem('refreshToken');
+            return false;
         }
     },
     async get(endpoint) { return this.request(endpoint); },
@@ -23,13 +42,14 @@ const ArticleCard = {
 	emits: ['delete', 'edit'],
 	template: `<div class="article-card">
 		<h4>{{ article.title }}</h4>
-		<p><strong>Автор:</strong> {{ article.author_name }}</p>
+		<p><strong>Автор:</strong> {{ article.author_deleted ? 'Удаленный пользователь' : article.author_name }}</p>
 		<p><strong>Создана:</strong> {{ formatDate(article.created_at) }}</p>
-		<p><strong>Содержимое:</strong> {{ truncateText(article.content, 200) }}</p>
+		<p v-if="article.status && article.status !== 'published'"><strong>Статус:</strong> {{ statusLabel }}<span v-if="article.status === 'scheduled'"> на {{ formatDate(article.publish_at) }}</span></p>
+		<p><strong>Содержимое:</strong> {{ article.excerpt || truncateText(article.content, 200) }}</p>
 		<p v-if="hasRating"><strong>Рейтинг:</strong> {{ article.rating_avg.toFixed(1) }} ({{ article.rating_count }})</p>
 		<div class="article-actions">
 			<button v-if="canEdit" class="btn-small btn-warning" @click="$emit('edit', article)">Редактировать</button>
-			<button v-if="canEdit" class="btn-small btn-danger" @click="confirmDelete">Удалить</button>
+			<button v-if="canDelete" class="btn-small btn-danger" @click="confirmDelete">Удалить</button>
 			<button class="btn-small btn-secondary" @click="toggleComments">{{ showComments ? 'Скрыть' : 'Комментарии' }}</button>
 		</div>
 		<div v-if="showComments" class="comments">
@@ -46,10 +66,10 @@ const ArticleCard = {
 			<div v-else-if="comments.length === 0" class="no-data">Нет комментариев</div>
 			<div v-else>
 				<div v-for="c in comments" :key="c.id" class="comment">
-					<div class="comment-meta"><strong>Пользователь #{{ c.user_id }}</strong> · {{ formatDate(c.created_at) }} · ★ {{ c.rating }}</div>
+					<div class="comment-meta"><strong>{{ c.user_deleted ? 'Удаленный пользователь' : 'Пользователь #' + c.user_id }}</strong> · {{ formatDate(c.created_at) }} · ★ {{ c.rating }}</div>
 					<div class="comment-text">{{ c.text }}</div>
 					<div class="comment-actions">
-						<button v-if="currentUser && currentUser.id === c.user_id" class="btn-small btn-danger" @click="deleteComment(c.id)" :disabled="deletingIds.has(c.id)">
+						<button v-if="currentUser && (currentUser.id === c.user_id || canDeleteAnyComment)" class="btn-small btn-danger" @click="deleteComment(c.id)" :disabled="deletingIds.has(c.id)">
 							Удалить
 						</button>
 					</div>
@@ -70,11 +90,20 @@ const ArticleCard = {
 	},
 	computed: {
 		canEdit() {
-			return this.currentUser && (this.currentUser.role.name === 'admin' || this.currentUser.id === this.article.author_id);
+			return this.currentUser && (this.currentUser.id === this.article.author_id || hasPermission(this.currentUser, 'article.update.any'));
+		},
+		canDelete() {
+			return this.currentUser && (this.currentUser.id === this.article.author_id || hasPermission(this.currentUser, 'article.delete.any'));
+		},
+		canDeleteAnyComment() {
+			return hasPermission(this.currentUser, 'comment.delete.any');
 		},
 		hasRating() {
 			return typeof this.article.rating_count === 'number' && this.article.rating_count > 0;
 		},
+		statusLabel() {
+			return { draft: 'черновик', scheduled: 'запланирована', archived: 'в архиве' }[this.article.status] || this.article.status;
+		},
 		canSend() {
 			return this.newCommentText.trim().length > 0 && this.newCommentRating >= 1 && this.newCommentRating <= 5;
 		}
@@ -92,7 +121,7 @@ const ArticleCard = {
 		async loadComments() {
 			this.loadingComments = true;
 			try {
-				this.comments = await api.get(`/articles/${this.article.id}/comments?limit=50`);
+				this.comments = (await api.get(`/articles/${this.article.id}/comments?limit=50`)).items;
 			} catch (e) {
 				alert(`Ошибка загрузки комментариев: ${e.message}`);
 			} finally {
@@ -145,7 +174,7 @@ const UserCard = {
 const appData = reactive({
     loginForm: { login: 'admin', password: 'password' },
     registerForm: { name: '', email: '', login: '', password: '', confirmPassword: '' },
-    isAuthenticated: false, currentUser: null, authToken: null, showRegisterForm: false,
+    isAuthenticated: false, currentUser: null, authToken: null, showRegisterForm: false, ssoProviders: [],
     isLoading: false, authStatus: null, articleForm: { title: '', content: '' },
     editForm: { id: null, title: '', content: '' }, showEditModal: false,
     articles: [], users: [], logs: []
@@ -155,7 +184,7 @@ const app = createApp({
     components: { ArticleCard, UserCard },
     data() { return appData; },
     computed: {
-        isAdmin() { return this.currentUser && this.currentUser.role && this.currentUser.role.name === 'admin'; }
+        isAdmin() { return hasPermission(this.currentUser, 'user.manage'); }
     },
     methods: {
         async login() {
@@ -166,13 +195,7 @@ const app = createApp({
             this.isLoading = true;
             try {
                 const response = await api.post('/auth/login', { login: this.loginForm.login, password: this.loginForm.password });
-                this.authToken = response.token;
-                this.currentUser = response.user;
-                this.isAuthenticated = true;
-                localStorage.setItem('authToken', this.authToken);
-                localStorage.setItem('currentUser', JSON.stringify(this.currentUser));
-                this.showStatus(`Добро пожаловать, ${this.currentUser.name}!`, 'success');
-                this.addLog('Успешная авторизация', 'success');
+                await this.completeLogin(response);
             } catch (error) {
                 this.showStatus(`Ошибка авторизации: ${error.message}`, 'error');
                 this.addLog(`Ошибка авторизации: ${error.message}`, 'error');
@@ -181,9 +204,51 @@ const app = createApp({
             }
         },
 
-        logout() {
+        async completeLogin(response) {
+            if (response.two_factor_required) {
+                const code = prompt('Введите код из приложения-аутентификатора или код восстановления');
+                if (!code) throw new Error('Вход отменен');
+                response = await api.post('/auth/login/2fa', { challenge_token: response.challenge_token, code: code.trim() });
+            }
+            this.authToken = response.token;
+            this.currentUser = response.user;
+            this.isAuthenticated = true;
+            localStorage.setItem('authToken', this.authToken);
+            localStorage.setItem('refreshToken', response.refresh_token);
+            localStorage.setItem('currentUser', JSON.stringify(this.currentUser));
+            this.showStatus(`Добро пожаловать, ${this.currentUser.name}!`, 'success');
+            this.addLog('Успешная авторизация', 'success');
+        },
+
+        async loadSSOProviders() {
+            try { this.ssoProviders = await api.get('/auth/oidc/providers'); } catch (error) { this.ssoProviders = []; }
+        },
+
+        loginWithSSO(provider) { window.location.href = provider.login_url; },
+
+        async handleSSORedirect() {
+            const params = new URLSearchParams(window.location.hash.slice(1));
+            const code = params.get('sso_code');
+            const error = params.get('sso_error');
+            if (!code && !error) return;
+            history.replaceState(null, '', window.location.pathname + window.location.search);
+            if (error) {
+                this.showStatus(`Ошибка входа через SSO: ${error}`, 'error');
+                this.addLog(`Ошибка входа через SSO: ${error}`, 'error');
+                return;
+            }
+            try {
+                await this.completeLogin(await api.post('/auth/oidc/exchange', { code }));
+            } catch (err) {
+                this.showStatus(`Ошибка входа через SSO: ${err.message}`, 'error');
+                this.addLog(`Ошибка входа через SSO: ${err.message}`, 'error');
+            }
+        },
+
+        async logout() {
+            try { await api.post('/auth/logout'); } catch (error) { /* сессия уже недействительна */ }
             this.authToken = null; this.currentUser = null; this.isAuthenticated = false;
-            localStorage.removeItem('authToken'); localStorage.removeItem('currentUser');
+            localStorage.removeItem('authToken'); localStorage.removeItem('refreshToken'); localStorage.removeItem('currentUser');
             this.showStatus('Вы вышли из системы', 'info');
             this.articles = []; this.users = []; this.addLog('Пользователь вышел из системы', 'info');
         },
@@ -195,9 +260,6 @@ const app = createApp({
             if (this.registerForm.password !== this.registerForm.confirmPassword) {
                 this.showStatus('Пароли не совпадают', 'error'); return;
             }
-            if (this.registerForm.password.length < 6) {
-                this.showStatus('Пароль должен содержать минимум 6 символов', 'error'); return;
-            }
             this.isLoading = true;
             try {
                 await api.post('/users', { name: this.registerForm.name, email: this.registerForm.email, login: this.registerForm.login, password: this.registerForm.password });
@@ -233,7 +295,7 @@ const app = createApp({
             if (!this.isAuthenticated) { this.showStatus('Необходимо авторизоваться', 'error'); return; }
             this.isLoading = true;
             try {
-                this.articles = await api.get('/articles?limit=50');
+                this.articles = (await api.get('/articles?limit=50')).items;
                 this.addLog(`Загружено ${this.articles.length} статей`, 'success');
             } catch (error) {
                 this.showStatus(`Ошибка загрузки статей: ${error.message}`, 'error');
@@ -260,7 +322,7 @@ const app = createApp({
             if (!this.isAdmin) { this.showStatus('Доступ только для администраторов', 'error'); return; }
             this.isLoading = true;
             try {
-                this.users = await api.get('/admin/users?limit=50');
+                this.users = (await api.get('/admin/users?limit=50')).items;
                 this.addLog(`Загружено ${this.users.length} пользователей`, 'success');
             } catch (error) {
                 this.showStatus(`Ошибка загрузки пользователей: ${error.message}`, 'error');
@@ -316,6 +378,8 @@ const app = createApp({
             this.isAuthenticated = true;
         }
         this.addLog('Vue.js фронтенд загружен и готов к работе', 'info');
+        this.loadSSOProviders();
+        this.handleSSORedirect();
     }
 });
 
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -10,15 +13,22 @@ import (
 	"goida/internal/config"
 	"goida/internal/database"
 	"goida/internal/handlers"
+	"goida/internal/jwtkeys"
+	"goida/internal/mail"
 	"goida/internal/middleware"
+	"goida/internal/password"
 	"goida/internal/repository"
 	"goida/internal/services"
 )
 
 type App struct {
-	config *config.Config
-	db     *database.Database
-	router *mux.Router
+	config   *config.Config
+	db       *database.Database
+	router   *mux.Router
+	accounts services.AccountService
+	exports  services.DataExportService
+	articles services.ArticleService
+	done     chan struct{}
 }
 
 func New() (*App, error) {
@@ -36,6 +46,7 @@ func New() (*App, error) {
 		config: cfg,
 		db:     db,
 		router: mux.NewRouter(),
+		done:   make(chan struct{}),
 	}
 
 	if err := app.setup(); err != nil {
@@ -47,41 +58,256 @@ func New() (*App, error) {
 
 func (a *App) setup() error {
 	userRepo := repository.NewUserRepository(a.db.DB)
-	articleRepo := repository.NewArticleRepository(a.db.DB)
+	hideDeletedAuthors, err := a.hideDeletedUserContent()
+	if err != nil {
+		return err
+	}
+	articleRepo := repository.NewArticleRepository(a.db.DB, hideDeletedAuthors)
 	roleRepo := repository.NewRoleRepository(a.db.DB)
 	authCredentialsRepo := repository.NewAuthCredentialsRepository(a.db.DB)
-	commentRepo := repository.NewCommentRepository(a.db.DB)
+	commentRepo := repository.NewCommentRepository(a.db.DB, hideDeletedAuthors)
+	sessionRepo := repository.NewCachedSessionRepository(repository.NewSessionRepository(a.db.DB), a.config.Auth.SessionCacheTTL, a.config.Auth.SessionTouch)
+	twoFactorRepo := repository.NewTwoFactorRepository(a.db.DB)
+	settingsRepo := repository.NewSettingsRepository(a.db.DB)
+	passwordResetRepo := repository.NewPasswordResetRepository(a.db.DB)
+	auditRepo := repository.NewAuditRepository(a.db.DB)
+	tokenRepo := repository.NewPersonalAccessTokenRepository(a.db.DB)
+	identityRepo := repository.NewUserIdentityRepository(a.db.DB)
+	permissionRepo := repository.NewPermissionRepository(a.db.DB)
+	exportRepo := repository.NewDataExportRepository(a.db.DB)
+	categoryRepo := repository.NewCategoryRepository(a.db.DB)
+	loginAttemptRepo, err := a.newLoginAttemptStore()
+	if err != nil {
+		return err
+	}
+
+	keys, err := a.loadSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	mailer, err := a.newMailer()
+	if err != nil {
+		return err
+	}
+
+	passwordPolicy, err := a.newPasswordPolicy()
+	if err != nil {
+		return err
+	}
+
+	hasher, err := password.NewHasher(password.Params{
+		Algorithm:   a.config.Password.Algorithm,
+		Memory:      uint32(a.config.Password.ArgonMemory),
+		Iterations:  uint32(a.config.Password.ArgonIterations),
+		Parallelism: uint8(a.config.Password.ArgonParallelism),
+		BcryptCost:  a.config.Password.BcryptCost,
+	})
+	if err != nil {
+		return err
+	}
 
-	userService := services.NewUserService(userRepo, roleRepo, authCredentialsRepo)
-	authService := services.NewAuthService(userRepo, authCredentialsRepo, a.config.JWTSecret)
-	articleService := services.NewArticleService(articleRepo, userRepo, commentRepo)
-	commentService := services.NewCommentService(commentRepo, articleRepo)
+	authorizer := services.NewAuthorizer(permissionRepo, userRepo)
+	emailVerificationService := services.NewEmailVerificationService(userRepo, auditRepo, authorizer, keys, mailer, a.config.Server.APIURL, a.config.Auth.VerifyTokenTTL, a.config.Auth.VerifyResend)
+	userService := services.NewUserService(userRepo, roleRepo, authCredentialsRepo, hasher, passwordPolicy, emailVerificationService)
+	loginLimiter := services.NewLoginLimiter(loginAttemptRepo, a.config.Login)
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, settingsRepo, authorizer, a.config.Auth.TOTPIssuer)
+	credentialsService := services.NewCredentialsService(userRepo, authCredentialsRepo, sessionRepo, auditRepo, loginLimiter, hasher, passwordPolicy, authorizer)
+	accountService := services.NewAccountService(userRepo, sessionRepo, permissionRepo, auditRepo, authorizer, credentialsService, emailVerificationService, a.config.Account.DeletionGrace)
+	authService := services.NewAuthService(userRepo, authCredentialsRepo, sessionRepo, loginLimiter, twoFactorService, authorizer, accountService, hasher, keys, a.config.Auth)
+	passwordResetService := services.NewPasswordResetService(userRepo, authCredentialsRepo, passwordResetRepo, sessionRepo, auditRepo, loginLimiter, hasher, passwordPolicy, mailer, a.config.Server.PublicURL, a.config.Auth)
+	articleService := services.NewArticleService(articleRepo, userRepo, commentRepo, categoryRepo, authorizer)
+	categoryService := services.NewCategoryService(categoryRepo)
+	commentService := services.NewCommentService(commentRepo, articleRepo, userRepo, authorizer)
+	tokenService := services.NewPersonalAccessTokenService(tokenRepo, userRepo, auditRepo)
+	moderationService := services.NewUserModerationService(userRepo, sessionRepo, permissionRepo, auditRepo, authorizer)
+	roleService := services.NewRoleService(roleRepo, permissionRepo, userRepo, auditRepo, authorizer)
+	exportService := services.NewDataExportService(exportRepo, userRepo, authCredentialsRepo, identityRepo, tokenRepo, articleRepo, commentRepo, sessionRepo, auditRepo, twoFactorService, a.config.Export)
+	oidcService := services.NewOIDCService(a.config.OIDC, nil, userRepo, roleRepo, identityRepo, permissionRepo, auditRepo, authorizer, keys, a.config.Server.APIURL)
 
-	authMiddleware := middleware.NewAuthMiddleware(authService)
+	impersonationService := services.NewImpersonationService(userRepo, auditRepo, authorizer, keys, a.config.Auth.TokenAudience, a.config.Auth.ImpersonationTTL)
+
+	authMiddleware := middleware.NewAuthMiddleware(authService, tokenService, authorizer, impersonationService)
 	validator := middleware.NewValidator()
 
 	userHandler := handlers.NewUserHandler(userService, validator)
 	authHandler := handlers.NewAuthHandler(authService, validator)
 	articleHandler := handlers.NewArticleHandler(articleService, validator)
 	commentHandler := handlers.NewCommentHandler(commentService, validator)
-	roleHandler := handlers.NewRoleHandler(roleRepo)
-	authCredentialsHandler := handlers.NewAuthCredentialsHandler(authCredentialsRepo, validator)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, validator)
+	roleHandler := handlers.NewRoleHandler(roleService, validator)
+	authCredentialsHandler := handlers.NewAuthCredentialsHandler(credentialsService, validator)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	lockoutHandler := handlers.NewLockoutHandler(loginLimiter)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, validator)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService, validator)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(tokenService, validator)
+	userAdminHandler := handlers.NewUserAdminHandler(moderationService, validator)
+	accountHandler := handlers.NewAccountHandler(accountService, validator)
+	exportHandler := handlers.NewDataExportHandler(exportService)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService, validator)
+	a.accounts = accountService
+	a.exports = exportService
+	a.articles = articleService
+	oidcHandler := handlers.NewOIDCHandler(oidcService, authService, validator, a.config.Server.PublicURL, a.config.Server.APIURL)
 
-	a.setupRoutes(userHandler, authHandler, articleHandler, roleHandler, authCredentialsHandler, commentHandler, authMiddleware)
+	a.setupRoutes(userHandler, authHandler, articleHandler, categoryHandler, roleHandler, authCredentialsHandler, commentHandler, jwksHandler, lockoutHandler, twoFactorHandler, passwordResetHandler, emailVerificationHandler, auditHandler, tokenHandler, oidcHandler, userAdminHandler, accountHandler, exportHandler, impersonationHandler, authMiddleware)
 
 	return nil
 }
 
+func (a *App) newLoginAttemptStore() (repository.LoginAttemptRepository, error) {
+	switch a.config.Login.Store {
+	case "memory":
+		return repository.NewMemoryLoginAttemptRepository(), nil
+	case "postgres":
+		return repository.NewLoginAttemptRepository(a.db.DB), nil
+	default:
+		return nil, fmt.Errorf("unknown LOGIN_ATTEMPT_STORE %q", a.config.Login.Store)
+	}
+}
+
+// hideDeletedUserContent разбирает DELETED_USER_CONTENT_POLICY.
+func (a *App) hideDeletedUserContent() (bool, error) {
+	switch a.config.Content.DeletedUserContent {
+	case "anonymize":
+		return false, nil
+	case "hide":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown DELETED_USER_CONTENT_POLICY %q", a.config.Content.DeletedUserContent)
+	}
+}
+
+func (a *App) newMailer() (mail.Mailer, error) {
+	cfg := a.config.Mail
+	switch cfg.Driver {
+	case "log":
+		return mail.NewLogMailer(cfg.OutboxDir), nil
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.Driver)
+	}
+}
+
+// newPasswordPolicy ограничивает длину пароля 72 байтами при bcrypt: более
+// длинный пароль bcrypt молча обрезал бы.
+func (a *App) newPasswordPolicy() (*password.Policy, error) {
+	cfg := a.config.Policy
+	policyCfg := password.PolicyConfig{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
+	if a.config.Password.Algorithm == password.AlgorithmBcrypt && (policyCfg.MaxLength <= 0 || policyCfg.MaxLength > password.BcryptMaxLength) {
+		policyCfg.MaxLength = password.BcryptMaxLength
+	}
+
+	var breached *password.BreachedList
+	if cfg.BreachedList != "" {
+		list, err := password.LoadBreachedList(cfg.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Loaded %d breached passwords from %s", list.Len(), cfg.BreachedList)
+		breached = list
+	}
+
+	return password.NewPolicy(policyCfg, breached), nil
+}
+
+func (a *App) loadSigningKeys() (*jwtkeys.KeySet, error) {
+	if a.config.Auth.KeysDir == "" {
+		logrus.Warn("JWT_KEYS_DIR is not set, using an ephemeral signing key: tokens will not survive a restart")
+		return jwtkeys.NewEphemeral(a.config.Auth.TokenIssuer)
+	}
+
+	keys, err := jwtkeys.LoadDir(a.config.Auth.KeysDir, a.config.Auth.ActiveKeyID, a.config.Auth.TokenIssuer)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("JWT signing key %s loaded from %s", keys.ActiveKeyID(), a.config.Auth.KeysDir)
+	return keys, nil
+}
+
 func (a *App) Run() error {
 	port := a.config.Server.Port
 	if envPort := os.Getenv("PORT"); envPort != "" {
 		port = envPort
 	}
 
+	go a.runCleanup()
+	go a.runPublisher()
+
 	logrus.Infof("Server starting on port %s", port)
 	return http.ListenAndServe(":"+port, a.router)
 }
 
+// runCleanup периодически удаляет учетные записи, у которых истек срок
+// отсрочки удаления, и устаревшие выгрузки персональных данных.
+func (a *App) runCleanup() {
+	if a.config.Account.PurgeInterval <= 0 {
+		logrus.Warn("ACCOUNT_PURGE_INTERVAL is not positive, deleted accounts and expired exports will not be purged")
+		return
+	}
+
+	ticker := time.NewTicker(a.config.Account.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := a.accounts.PurgeDeleted(context.Background())
+		if err != nil {
+			logrus.Errorf("Failed to purge deleted accounts: %v", err)
+		} else if purged > 0 {
+			logrus.Infof("Purged %d deleted accounts", purged)
+		}
+		if _, err := a.exports.Cleanup(context.Background()); err != nil {
+			logrus.Errorf("Failed to clean up data exports: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// runPublisher публикует запланированные статьи. До очередного запуска
+// наступившие статьи уже видны читателям, планировщик лишь меняет их статус.
+func (a *App) runPublisher() {
+	if a.config.Content.PublishInterval <= 0 {
+		logrus.Warn("ARTICLE_PUBLISH_INTERVAL is not positive, scheduled articles will keep the scheduled status")
+		return
+	}
+
+	ticker := time.NewTicker(a.config.Content.PublishInterval)
+	defer ticker.Stop()
+
+	for {
+		published, err := a.articles.PublishScheduled(context.Background())
+		if err != nil {
+			logrus.Errorf("Failed to publish scheduled articles: %v", err)
+		} else if published > 0 {
+			logrus.Infof("Published %d scheduled articles", published)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.done:
+			return
+		}
+	}
+}
+
 func (a *App) Close() error {
+	close(a.done)
 	return a.db.Close()
 }
@@ -5,15 +5,29 @@ import (
 
 	"goida/internal/handlers"
 	"goida/internal/middleware"
+	"goida/internal/models"
 )
 
 func (a *App) setupRoutes(
 	userHandler *handlers.UserHandler,
 	authHandler *handlers.AuthHandler,
 	articleHandler *handlers.ArticleHandler,
+	categoryHandler *handlers.CategoryHandler,
 	roleHandler *handlers.RoleHandler,
 	authCredentialsHandler *handlers.AuthCredentialsHandler,
 	commentHandler *handlers.CommentHandler,
+	jwksHandler *handlers.JWKSHandler,
+	lockoutHandler *handlers.LockoutHandler,
+	twoFactorHandler *handlers.TwoFactorHandler,
+	passwordResetHandler *handlers.PasswordResetHandler,
+	emailVerificationHandler *handlers.EmailVerificationHandler,
+	auditHandler *handlers.AuditHandler,
+	tokenHandler *handlers.PersonalAccessTokenHandler,
+	oidcHandler *handlers.OIDCHandler,
+	userAdminHandler *handlers.UserAdminHandler,
+	accountHandler *handlers.AccountHandler,
+	exportHandler *handlers.DataExportHandler,
+	impersonationHandler *handlers.ImpersonationHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) {
 	a.router.Use(middleware.CORSMiddleware)
@@ -23,35 +37,107 @@ func (a *App) setupRoutes(
 		w.WriteHeader(http.StatusOK)
 	})
 
-	a.setupPublicRoutes(userHandler, authHandler, articleHandler, roleHandler, authCredentialsHandler, commentHandler)
-	a.setupProtectedRoutes(authHandler, articleHandler, userHandler, commentHandler, authMiddleware)
-	a.setupAdminRoutes(userHandler, roleHandler, authMiddleware)
+	a.router.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+
+	a.setupPublicRoutes(userHandler, authHandler, articleHandler, categoryHandler, roleHandler, commentHandler, passwordResetHandler, emailVerificationHandler, oidcHandler, authMiddleware)
+	a.setupAccountRoutes(authHandler, accountHandler, exportHandler, authCredentialsHandler, twoFactorHandler, emailVerificationHandler, tokenHandler, authMiddleware)
+	a.setupProtectedRoutes(articleHandler, userHandler, commentHandler, authMiddleware)
+	a.setupAdminRoutes(userHandler, userAdminHandler, exportHandler, impersonationHandler, categoryHandler, roleHandler, authCredentialsHandler, lockoutHandler, twoFactorHandler, emailVerificationHandler, auditHandler, tokenHandler, authMiddleware)
 }
 
 func (a *App) setupPublicRoutes(
 	userHandler *handlers.UserHandler,
 	authHandler *handlers.AuthHandler,
 	articleHandler *handlers.ArticleHandler,
+	categoryHandler *handlers.CategoryHandler,
 	roleHandler *handlers.RoleHandler,
-	authCredentialsHandler *handlers.AuthCredentialsHandler,
 	commentHandler *handlers.CommentHandler,
+	passwordResetHandler *handlers.PasswordResetHandler,
+	emailVerificationHandler *handlers.EmailVerificationHandler,
+	oidcHandler *handlers.OIDCHandler,
+	authMiddleware *middleware.AuthMiddleware,
 ) {
 	a.router.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
+	a.router.HandleFunc("/api/auth/login/2fa", authHandler.LoginTwoFactor).Methods("POST")
+	a.router.HandleFunc("/api/auth/oidc/providers", oidcHandler.ListProviders).Methods("GET")
+	a.router.HandleFunc("/api/auth/oidc/exchange", oidcHandler.Exchange).Methods("POST")
+	a.router.HandleFunc("/api/auth/oidc/{provider}/login", oidcHandler.Login).Methods("GET")
+	a.router.HandleFunc("/api/auth/oidc/{provider}/callback", oidcHandler.Callback).Methods("GET")
+	a.router.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods("POST")
+	a.router.HandleFunc("/api/auth/password/forgot", passwordResetHandler.ForgotPassword).Methods("POST")
+	a.router.HandleFunc("/api/auth/password/reset", passwordResetHandler.ResetPassword).Methods("POST")
+	a.router.HandleFunc("/api/auth/verify", emailVerificationHandler.Verify).Methods("GET")
 	a.router.HandleFunc("/api/users", userHandler.CreateUser).Methods("POST")
-	a.router.HandleFunc("/api/articles", articleHandler.ListArticles).Methods("GET")
-	a.router.HandleFunc("/api/articles/{id}", articleHandler.GetArticle).Methods("GET")
-	a.router.HandleFunc("/api/users/{authorId}/articles", articleHandler.GetUserArticles).Methods("GET")
 
-	a.router.HandleFunc("/api/articles/{id}/comments", commentHandler.List).Methods("GET")
+	// Статьи доступны анонимно, но автор и модераторы с токеном видят
+	// также неопубликованные.
+	optional := func(handler http.HandlerFunc) http.Handler {
+		return authMiddleware.OptionalAuth(handler)
+	}
+	a.router.Handle("/api/articles", optional(articleHandler.ListArticles)).Methods("GET")
+	a.router.Handle("/api/articles/search", optional(articleHandler.SearchArticles)).Methods("GET")
+	a.router.Handle("/api/articles/by-slug/{slug}", optional(articleHandler.GetArticleBySlug)).Methods("GET")
+	a.router.Handle("/api/articles/{id}", optional(articleHandler.GetArticle)).Methods("GET")
+	a.router.Handle("/api/users/{authorId}/articles", optional(articleHandler.GetUserArticles)).Methods("GET")
+	a.router.HandleFunc("/api/tags", articleHandler.ListTags).Methods("GET")
+	a.router.HandleFunc("/api/categories", categoryHandler.ListCategories).Methods("GET")
 
-	// Эндпоинты для управления учетными данными
-	a.router.HandleFunc("/api/auth/credentials", authCredentialsHandler.CreateCredentials).Methods("POST")
-	a.router.HandleFunc("/api/auth/credentials/{userId}", authCredentialsHandler.GetCredentials).Methods("GET")
-	a.router.HandleFunc("/api/auth/credentials/{userId}", authCredentialsHandler.UpdateCredentials).Methods("PUT")
+	a.router.Handle("/api/articles/{id}/comments", optional(commentHandler.List)).Methods("GET")
 }
 
-func (a *App) setupProtectedRoutes(
+// setupAccountRoutes - управление учетной записью доступно только по сессии,
+// персональные токены здесь не принимаются.
+func (a *App) setupAccountRoutes(
 	authHandler *handlers.AuthHandler,
+	accountHandler *handlers.AccountHandler,
+	exportHandler *handlers.DataExportHandler,
+	authCredentialsHandler *handlers.AuthCredentialsHandler,
+	twoFactorHandler *handlers.TwoFactorHandler,
+	emailVerificationHandler *handlers.EmailVerificationHandler,
+	tokenHandler *handlers.PersonalAccessTokenHandler,
+	authMiddleware *middleware.AuthMiddleware,
+) {
+	accountRouter := a.router.PathPrefix("/api/auth").Subrouter()
+	accountRouter.Use(authMiddleware.RequireAuth)
+	accountRouter.Use(authMiddleware.RequireSession)
+
+	accountRouter.HandleFunc("/profile", authHandler.GetProfile).Methods("GET")
+	accountRouter.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	accountRouter.HandleFunc("/sessions", authHandler.ListSessions).Methods("GET")
+	accountRouter.HandleFunc("/sessions", authHandler.RevokeOtherSessions).Methods("DELETE")
+	accountRouter.HandleFunc("/sessions/{id}", authHandler.RevokeSession).Methods("DELETE")
+	accountRouter.HandleFunc("/tokens", tokenHandler.List).Methods("GET")
+	accountRouter.HandleFunc("/tokens", tokenHandler.Create).Methods("POST")
+	accountRouter.HandleFunc("/tokens/{tokenId}", tokenHandler.Revoke).Methods("DELETE")
+	accountRouter.HandleFunc("/credentials", authCredentialsHandler.GetOwnCredentials).Methods("GET")
+	accountRouter.HandleFunc("/credentials/password", authCredentialsHandler.ChangePassword).Methods("PUT")
+	accountRouter.HandleFunc("/credentials/login", authCredentialsHandler.ChangeLogin).Methods("PUT")
+	accountRouter.HandleFunc("/verify/resend", emailVerificationHandler.Resend).Methods("POST")
+	accountRouter.HandleFunc("/2fa", twoFactorHandler.GetStatus).Methods("GET")
+	accountRouter.HandleFunc("/2fa/enroll", twoFactorHandler.Enroll).Methods("POST")
+	accountRouter.HandleFunc("/2fa/confirm", twoFactorHandler.Confirm).Methods("POST")
+	accountRouter.HandleFunc("/2fa/disable", twoFactorHandler.Disable).Methods("POST")
+	accountRouter.HandleFunc("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
+
+	meRouter := a.router.PathPrefix("/api/users/me").Subrouter()
+	meRouter.Use(authMiddleware.RequireAuth)
+	meRouter.Use(authMiddleware.RequireSession)
+	// Профиль, удаление и выгрузку данных сотрудник от имени пользователя
+	// не трогает.
+	meRouter.Use(authMiddleware.DenyImpersonation)
+
+	meRouter.HandleFunc("", accountHandler.UpdateProfile).Methods("PATCH")
+	meRouter.HandleFunc("", accountHandler.DeleteAccount).Methods("DELETE")
+	meRouter.HandleFunc("/deactivate", accountHandler.Deactivate).Methods("POST")
+	meRouter.HandleFunc("/deletion/cancel", accountHandler.CancelDeletion).Methods("POST")
+	meRouter.HandleFunc("/export", exportHandler.Export).Methods("GET")
+	meRouter.HandleFunc("/exports/{exportId}", exportHandler.GetExport).Methods("GET")
+	meRouter.HandleFunc("/exports/{exportId}/download", exportHandler.Download).Methods("GET")
+}
+
+// setupProtectedRoutes - маршруты, доступные и по сессии, и по персональному
+// токену с соответствующей областью доступа.
+func (a *App) setupProtectedRoutes(
 	articleHandler *handlers.ArticleHandler,
 	userHandler *handlers.UserHandler,
 	commentHandler *handlers.CommentHandler,
@@ -59,27 +145,68 @@ func (a *App) setupProtectedRoutes(
 ) {
 	authRouter := a.router.PathPrefix("/api").Subrouter()
 	authRouter.Use(authMiddleware.RequireAuth)
+	scope := authMiddleware.RequireScope
 
-	authRouter.HandleFunc("/auth/profile", authHandler.GetProfile).Methods("GET")
-	authRouter.HandleFunc("/articles", articleHandler.CreateArticle).Methods("POST")
-	authRouter.HandleFunc("/articles/{id}", articleHandler.UpdateArticle).Methods("PUT")
-	authRouter.HandleFunc("/articles/{id}", articleHandler.DeleteArticle).Methods("DELETE")
-	authRouter.HandleFunc("/users/{id}", userHandler.GetUser).Methods("GET")
+	authRouter.HandleFunc("/articles", scope(models.ScopeArticlesWrite, articleHandler.CreateArticle)).Methods("POST")
+	authRouter.HandleFunc("/articles/{id}", scope(models.ScopeArticlesWrite, articleHandler.UpdateArticle)).Methods("PUT")
+	authRouter.HandleFunc("/articles/{id}", scope(models.ScopeArticlesWrite, articleHandler.DeleteArticle)).Methods("DELETE")
+	authRouter.HandleFunc("/articles/{id}/revisions", scope(models.ScopeArticlesWrite, articleHandler.ListRevisions)).Methods("GET")
+	authRouter.HandleFunc("/articles/{id}/revisions/diff", scope(models.ScopeArticlesWrite, articleHandler.DiffRevisions)).Methods("GET")
+	authRouter.HandleFunc("/articles/{id}/revisions/{revision:[0-9]+}", scope(models.ScopeArticlesWrite, articleHandler.GetRevision)).Methods("GET")
+	authRouter.HandleFunc("/articles/{id}/revisions/{revision:[0-9]+}/restore", scope(models.ScopeArticlesWrite, articleHandler.RestoreRevision)).Methods("POST")
+	authRouter.HandleFunc("/users/{id}", scope(models.ScopeUsersRead, userHandler.GetUser)).Methods("GET")
 
-	authRouter.HandleFunc("/articles/{id}/comments", commentHandler.Create).Methods("POST")
-	authRouter.HandleFunc("/comments/{id}", commentHandler.Update).Methods("PUT")
-	authRouter.HandleFunc("/comments/{id}", commentHandler.Delete).Methods("DELETE")
+	authRouter.HandleFunc("/articles/{id}/comments", scope(models.ScopeCommentsWrite, commentHandler.Create)).Methods("POST")
+	authRouter.HandleFunc("/comments/{id}", scope(models.ScopeCommentsWrite, commentHandler.Update)).Methods("PUT")
+	authRouter.HandleFunc("/comments/{id}", scope(models.ScopeCommentsWrite, commentHandler.Delete)).Methods("DELETE")
 }
 
 func (a *App) setupAdminRoutes(
 	userHandler *handlers.UserHandler,
+	userAdminHandler *handlers.UserAdminHandler,
+	exportHandler *handlers.DataExportHandler,
+	impersonationHandler *handlers.ImpersonationHandler,
+	categoryHandler *handlers.CategoryHandler,
 	roleHandler *handlers.RoleHandler,
+	authCredentialsHandler *handlers.AuthCredentialsHandler,
+	lockoutHandler *handlers.LockoutHandler,
+	twoFactorHandler *handlers.TwoFactorHandler,
+	emailVerificationHandler *handlers.EmailVerificationHandler,
+	auditHandler *handlers.AuditHandler,
+	tokenHandler *handlers.PersonalAccessTokenHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) {
 	adminRouter := a.router.PathPrefix("/api/admin").Subrouter()
 	adminRouter.Use(authMiddleware.RequireAdmin)
+	perm := authMiddleware.RequirePermission
 
-	adminRouter.HandleFunc("/users", userHandler.ListUsers).Methods("GET")
-	adminRouter.HandleFunc("/roles", roleHandler.ListRoles).Methods("GET")
-	adminRouter.HandleFunc("/roles/{id}", roleHandler.GetRole).Methods("GET")
+	adminRouter.HandleFunc("/users", perm(models.PermUserManage, userHandler.ListUsers)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}", perm(models.PermUserManage, userAdminHandler.UpdateUser)).Methods("PATCH")
+	adminRouter.HandleFunc("/users/{id}", perm(models.PermUserManage, userAdminHandler.DeleteUser)).Methods("DELETE")
+	adminRouter.HandleFunc("/users/{id}/restore", perm(models.PermUserManage, userAdminHandler.RestoreUser)).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/suspension", perm(models.PermUserManage, userAdminHandler.SuspendUser)).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id}/suspension", perm(models.PermUserManage, userAdminHandler.UnsuspendUser)).Methods("DELETE")
+	adminRouter.HandleFunc("/users/{id}/export", perm(models.PermUserManage, exportHandler.ExportUser)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/exports/{exportId}", perm(models.PermUserManage, exportHandler.GetUserExport)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/exports/{exportId}/download", perm(models.PermUserManage, exportHandler.DownloadUserExport)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/impersonate", perm(models.PermUserImpersonate, impersonationHandler.Impersonate)).Methods("POST")
+	adminRouter.HandleFunc("/users/{id}/verification", perm(models.PermUserManage, emailVerificationHandler.SetVerified)).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id}/credentials", perm(models.PermUserManage, authCredentialsHandler.GetUserCredentials)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/credentials", perm(models.PermUserManage, authCredentialsHandler.UpdateUserCredentials)).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id}/role", perm(models.PermRoleManage, roleHandler.AssignUserRole)).Methods("PUT")
+	adminRouter.HandleFunc("/users/{id}/audit", perm(models.PermAuditRead, auditHandler.ListUserEvents)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/tokens", perm(models.PermUserManage, tokenHandler.ListUserTokens)).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/tokens/{tokenId}", perm(models.PermUserManage, tokenHandler.RevokeUserToken)).Methods("DELETE")
+	adminRouter.HandleFunc("/roles", perm(models.PermRoleManage, roleHandler.ListRoles)).Methods("GET")
+	adminRouter.HandleFunc("/roles", perm(models.PermRoleManage, roleHandler.CreateRole)).Methods("POST")
+	adminRouter.HandleFunc("/roles/{id}", perm(models.PermRoleManage, roleHandler.GetRole)).Methods("GET")
+	adminRouter.HandleFunc("/roles/{id}", perm(models.PermRoleManage, roleHandler.UpdateRole)).Methods("PUT")
+	adminRouter.HandleFunc("/roles/{id}", perm(models.PermRoleManage, roleHandler.DeleteRole)).Methods("DELETE")
+	adminRouter.HandleFunc("/categories", perm(models.PermCategoryManage, categoryHandler.CreateCategory)).Methods("POST")
+	adminRouter.HandleFunc("/categories/{id}", perm(models.PermCategoryManage, categoryHandler.UpdateCategory)).Methods("PUT")
+	adminRouter.HandleFunc("/categories/{id}", perm(models.PermCategoryManage, categoryHandler.DeleteCategory)).Methods("DELETE")
+	adminRouter.HandleFunc("/lockouts", perm(models.PermSecurityManage, lockoutHandler.ListLockouts)).Methods("GET")
+	adminRouter.HandleFunc("/lockouts/{key}", perm(models.PermSecurityManage, lockoutHandler.ClearLockout)).Methods("DELETE")
+	adminRouter.HandleFunc("/security/2fa-policy", perm(models.PermSecurityManage, twoFactorHandler.GetPolicy)).Methods("GET")
+	adminRouter.HandleFunc("/security/2fa-policy", perm(models.PermSecurityManage, twoFactorHandler.UpdatePolicy)).Methods("PUT")
 }
@@ -3,15 +3,25 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
-	Database  DatabaseConfig
-	Server    ServerConfig
-	JWTSecret string
+	Database DatabaseConfig
+	Server   ServerConfig
+	Auth     AuthConfig
+	Login    LoginThrottleConfig
+	Mail     MailConfig
+	Password PasswordHashConfig
+	Policy   PasswordPolicyConfig
+	OIDC     OIDCConfig
+	Content  ContentConfig
+	Account  AccountConfig
+	Export   ExportConfig
 }
 
 type DatabaseConfig struct {
@@ -23,8 +33,125 @@ type DatabaseConfig struct {
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
+	Host      string
+	Port      string
+	PublicURL string
+	APIURL    string
+}
+
+type AuthConfig struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	KeysDir         string
+	ActiveKeyID     string
+	// TokenIssuer и TokenAudience - iss и aud access-токенов. Служебные
+	// токены (2FA, подтверждение email, вход через OIDC) выдаются с
+	// аудиторией TokenIssuer.
+	TokenIssuer   string
+	TokenAudience string
+	TOTPIssuer    string
+	ResetTokenTTL time.Duration
+	// ResetEmailLimit и ResetIPLimit - число запросов сброса пароля на один
+	// email и на один адрес клиента за ResetLimitWindow.
+	ResetEmailLimit  int
+	ResetIPLimit     int
+	ResetLimitWindow time.Duration
+	VerifyTokenTTL   time.Duration
+	VerifyResend     time.Duration
+	SessionCacheTTL  time.Duration
+	SessionTouch     time.Duration
+	// ImpersonationTTL - срок жизни токена имперсонации.
+	ImpersonationTTL time.Duration
+}
+
+type LoginThrottleConfig struct {
+	Store            string
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	MaxLoginFailures int
+	MaxIPFailures    int
+	LockoutDuration  time.Duration
+	FailureWindow    time.Duration
+}
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	OutboxDir    string
+}
+
+type PasswordHashConfig struct {
+	Algorithm        string
+	ArgonMemory      int
+	ArgonIterations  int
+	ArgonParallelism int
+	BcryptCost       int
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	BreachedList  string
+}
+
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+	StateTTL  time.Duration
+}
+
+// OIDCProviderConfig описывает внешнего провайдера. Переменные окружения
+// имеют вид OIDC_<ID>_ISSUER, OIDC_<ID>_CLIENT_ID и т.д., где ID - имя из
+// списка OIDC_PROVIDERS в верхнем регистре.
+type OIDCProviderConfig struct {
+	ID            string
+	Name          string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	GroupsClaim   string
+	RoleMap       []OIDCRoleMapping
+	DefaultRole   string
+	AutoProvision bool
+}
+
+// OIDCRoleMapping сопоставляет группу провайдера роли из таблицы roles.
+// Порядок важен: берется первое совпадение.
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
+}
+
+// ContentConfig - DeletedUserContent определяет, что видно от удаленных
+// пользователей: "anonymize" - статьи и комментарии остаются с подписью
+// "Deleted user", "hide" - скрываются. PublishInterval - как часто
+// запланированные статьи переводятся в опубликованные.
+type ContentConfig struct {
+	DeletedUserContent string
+	PublishInterval    time.Duration
+}
+
+// AccountConfig - DeletionGrace: через сколько после запроса учетная запись
+// удаляется окончательно, PurgeInterval: как часто это проверяется.
+type AccountConfig struct {
+	DeletionGrace time.Duration
+	PurgeInterval time.Duration
+}
+
+// ExportConfig - выгрузка персональных данных: при числе записей больше
+// SyncLimit архив формируется в фоне, готовый архив хранится TTL.
+type ExportConfig struct {
+	SyncLimit int
+	TTL       time.Duration
 }
 
 func Load() (*Config, error) {
@@ -43,16 +170,170 @@ func Load() (*Config, error) {
 			DBName:   getEnv("DB_NAME", "goida"),
 		},
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "localhost"),
-			Port: getEnv("SERVER_PORT", "8080"),
+			Host:      getEnv("SERVER_HOST", "localhost"),
+			Port:      getEnv("SERVER_PORT", "8080"),
+			PublicURL: getEnv("APP_PUBLIC_URL", "http://localhost:3000"),
+			APIURL:    getEnv("API_PUBLIC_URL", "http://localhost:8080"),
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:   getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+			RefreshTokenTTL:  getEnvDuration("JWT_REFRESH_TTL", 30*24*time.Hour),
+			KeysDir:          getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:      getEnv("JWT_ACTIVE_KID", ""),
+			TokenIssuer:      getEnv("JWT_ISSUER", getEnv("API_PUBLIC_URL", "http://localhost:8080")),
+			TokenAudience:    getEnv("JWT_AUDIENCE", "goida-api"),
+			TOTPIssuer:       getEnv("TOTP_ISSUER", "GoIda"),
+			ResetTokenTTL:    getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+			ResetEmailLimit:  getEnvInt("PASSWORD_RESET_EMAIL_LIMIT", 3),
+			ResetIPLimit:     getEnvInt("PASSWORD_RESET_IP_LIMIT", 20),
+			ResetLimitWindow: getEnvDuration("PASSWORD_RESET_LIMIT_WINDOW", time.Hour),
+			VerifyTokenTTL:   getEnvDuration("EMAIL_VERIFY_TTL", 48*time.Hour),
+			VerifyResend:     getEnvDuration("EMAIL_VERIFY_RESEND_INTERVAL", time.Minute),
+			SessionCacheTTL:  getEnvDuration("SESSION_CACHE_TTL", 30*time.Second),
+			SessionTouch:     getEnvDuration("SESSION_TOUCH_INTERVAL", time.Minute),
+			ImpersonationTTL: getEnvDuration("IMPERSONATION_TTL", 15*time.Minute),
+		},
+		Login: LoginThrottleConfig{
+			Store:            getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+			FreeAttempts:     getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+			BaseDelay:        getEnvDuration("LOGIN_BASE_DELAY", time.Second),
+			MaxDelay:         getEnvDuration("LOGIN_MAX_DELAY", 5*time.Minute),
+			MaxLoginFailures: getEnvInt("LOGIN_MAX_FAILURES", 10),
+			MaxIPFailures:    getEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+			LockoutDuration:  getEnvDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+			FailureWindow:    getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "GoIda <noreply@goida.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			OutboxDir:    getEnv("MAIL_OUTBOX_DIR", ""),
+		},
+		Password: PasswordHashConfig{
+			Algorithm:        getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			ArgonMemory:      getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+			ArgonIterations:  getEnvInt("ARGON2_ITERATIONS", 3),
+			ArgonParallelism: getEnvInt("ARGON2_PARALLELISM", 2),
+			BcryptCost:       getEnvInt("BCRYPT_COST", 10),
+		},
+		Policy: PasswordPolicyConfig{
+			MinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:     getEnvInt("PASSWORD_MAX_LENGTH", 72),
+			RequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:  getEnvBool("PASSWORD_REQUIRE_LOWER", true),
+			RequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			BreachedList:  getEnv("PASSWORD_BREACHED_LIST", ""),
+		},
+		OIDC: OIDCConfig{
+			Providers: loadOIDCProviders(),
+			StateTTL:  getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		},
+		Content: ContentConfig{
+			DeletedUserContent: getEnv("DELETED_USER_CONTENT_POLICY", "anonymize"),
+			PublishInterval:    getEnvDuration("ARTICLE_PUBLISH_INTERVAL", time.Minute),
+		},
+		Account: AccountConfig{
+			DeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+			PurgeInterval: getEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+		},
+		Export: ExportConfig{
+			SyncLimit: getEnvInt("EXPORT_SYNC_LIMIT", 1000),
+			TTL:       getEnvDuration("EXPORT_TTL", 72*time.Hour),
 		},
-		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
 	}, nil
 }
 
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, id := range getEnvList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_")) + "_"
+
+		var roleMap []OIDCRoleMapping
+		for _, pair := range getEnvList(prefix + "ROLE_MAP") {
+			group, role, ok := strings.Cut(pair, "=")
+			if !ok || group == "" || role == "" {
+				logrus.Warnf("Invalid entry %q in %sROLE_MAP, expected group=role", pair, prefix)
+				continue
+			}
+			roleMap = append(roleMap, OIDCRoleMapping{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+		}
+
+		providers = append(providers, OIDCProviderConfig{
+			ID:            id,
+			Name:          getEnv(prefix+"NAME", id),
+			Issuer:        getEnv(prefix+"ISSUER", ""),
+			ClientID:      getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:  getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:        strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			GroupsClaim:   getEnv(prefix+"GROUPS_CLAIM", "groups"),
+			RoleMap:       roleMap,
+			DefaultRole:   getEnv(prefix+"DEFAULT_ROLE", "user"),
+			AutoProvision: getEnvBool(prefix+"AUTO_PROVISION", true),
+		})
+	}
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvList читает список через запятую, пропуская пустые элементы.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.Warnf("Invalid integer in %s: %v, using %d", key, err, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Warnf("Invalid duration in %s: %v, using %s", key, err, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Invalid boolean in %s: %v, using %t", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
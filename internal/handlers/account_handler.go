@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+// AccountHandler - самообслуживание учетной записи по маршрутам
+// /api/users/me.
+type AccountHandler struct {
+	accountService services.AccountService
+	validator      *middleware.Validator
+}
+
+func NewAccountHandler(accountService services.AccountService, validator *middleware.Validator) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		validator:      validator,
+	}
+}
+
+func (h *AccountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateProfileRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.accountService.UpdateProfile(r.Context(), claims.UserID, &req, clientIP(r))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AccountHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	var req models.ConfirmPasswordRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.accountService.Deactivate(r.Context(), claims.UserID, &req, clientIP(r)); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.ConfirmPasswordRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	deleteAt, err := h.accountService.ScheduleDeletion(r.Context(), claims.UserID, &req, clientIP(r))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]time.Time{
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+// CancelDeletion отменяет запланированное удаление своей учетной записи.
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(r.Context(), claims.UserID, clientIP(r)); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	var limitErr *services.TooManyAttemptsError
+	switch {
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		http.Error(w, "Too many login attempts", http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrWrongPassword):
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrEmailTaken), errors.Is(err, services.ErrLastAdmin),
+		errors.Is(err, services.ErrDeletionNotScheduled):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		logrus.Errorf("Account operation failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -54,6 +57,18 @@ func (h *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request) {
 
 	article, err := h.articleService.CreateArticle(&req, claims.UserID)
 	if err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(w, "Email verification required", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, services.ErrPublishAtInvalid) {
+			writeArticleFieldError(w, "publish_at", err)
+			return
+		}
+		if errors.Is(err, services.ErrUnknownCategory) {
+			writeArticleFieldError(w, "category", err)
+			return
+		}
 		logrus.Errorf("Failed to create article: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -72,7 +87,7 @@ func (h *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	article, err := h.articleService.GetArticle(id)
+	article, err := h.articleService.GetArticle(id, viewer(r))
 	if err != nil {
 		logrus.Errorf("Failed to get article: %v", err)
 		http.Error(w, "Article not found", http.StatusNotFound)
@@ -83,6 +98,35 @@ func (h *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(article)
 }
 
+// GetArticleBySlug - по прежнему slug переименованной статьи отвечает 301 с
+// Location на текущий адрес.
+func (h *ArticleHandler) GetArticleBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	article, err := h.articleService.GetArticleBySlug(r.Context(), slug, viewer(r))
+	if err != nil {
+		if !errors.Is(err, services.ErrArticleNotFound) {
+			logrus.Errorf("Failed to get article by slug: %v", err)
+		}
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if article.Slug != slug {
+		location := "/api/articles/by-slug/" + url.PathEscape(article.Slug)
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       article.ID,
+			"slug":     article.Slug,
+			"location": location,
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(article)
+}
+
 func (h *ArticleHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -120,6 +164,14 @@ func (h *ArticleHandler) UpdateArticle(w http.ResponseWriter, r *http.Request) {
 	article, err := h.articleService.UpdateArticle(id, &req, claims.UserID, claims.Role)
 	if err != nil {
 		logrus.Errorf("Failed to update article: %v", err)
+		if errors.Is(err, services.ErrPublishAtInvalid) {
+			writeArticleFieldError(w, "publish_at", err)
+			return
+		}
+		if errors.Is(err, services.ErrUnknownCategory) {
+			writeArticleFieldError(w, "category", err)
+			return
+		}
 		if err.Error() == "access denied" {
 			http.Error(w, "Access denied", http.StatusForbidden)
 			return
@@ -162,33 +214,25 @@ func (h *ArticleHandler) DeleteArticle(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ArticleHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10
-	offset := 0
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	page, ok := pageParams(w, r, 10)
+	if !ok {
+		return
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	// ?tag=go&tag=sql или ?tag=go,sql - статьи со всеми тегами сразу.
+	filter := models.ArticleFilter{Category: r.URL.Query().Get("category")}
+	for _, value := range r.URL.Query()["tag"] {
+		filter.Tags = append(filter.Tags, strings.Split(value, ",")...)
 	}
 
-	articles, err := h.articleService.ListArticles(limit, offset)
+	articles, err := h.articleService.ListArticles(page, filter, viewer(r))
 	if err != nil {
 		logrus.Errorf("Failed to list articles: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(articles)
+	writePage(w, r, articles)
 }
 
 func (h *ArticleHandler) GetUserArticles(w http.ResponseWriter, r *http.Request) {
@@ -199,31 +243,52 @@ func (h *ArticleHandler) GetUserArticles(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10
-	offset := 0
+	page, ok := pageParams(w, r, 10)
+	if !ok {
+		return
+	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	articles, err := h.articleService.GetArticlesByAuthor(authorID, page, viewer(r))
+	if err != nil {
+		logrus.Errorf("Failed to get user articles: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	writePage(w, r, articles)
+}
+
+// ListTags - теги опубликованных статей с числом статей.
+func (h *ArticleHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	page, ok := offsetPageParams(w, r, 50)
+	if !ok {
+		return
 	}
 
-	articles, err := h.articleService.GetArticlesByAuthor(authorID, limit, offset)
+	tags, err := h.articleService.ListTags(r.Context(), page)
 	if err != nil {
-		logrus.Errorf("Failed to get user articles: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logrus.Errorf("Failed to list tags: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	writePage(w, r, tags)
+}
+
+// viewer - читатель из OptionalAuth; nil для анонимного запроса.
+func viewer(r *http.Request) *services.Claims {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
+func writeArticleFieldError(w http.ResponseWriter, field string, err error) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(articles)
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "Validation failed",
+		"details": map[string]string{field: err.Error()},
+	})
 }
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/services"
+)
+
+func (h *ArticleHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	articleID, claims, ok := articleAndClaims(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := pageParams(w, r, 20)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.articleService.ListRevisions(r.Context(), articleID, claims.UserID, claims.Role, page)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	writePage(w, r, revisions)
+}
+
+func (h *ArticleHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	articleID, claims, ok := articleAndClaims(w, r)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.articleService.GetRevision(r.Context(), articleID, revision, claims.UserID, claims.Role)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DiffRevisions - ?from=1&to=3; без to сравнение идет с последней версией.
+func (h *ArticleHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	articleID, claims, ok := articleAndClaims(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from <= 0 {
+		http.Error(w, "Invalid from revision", http.StatusBadRequest)
+		return
+	}
+	to := 0
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil || to <= 0 {
+			http.Error(w, "Invalid to revision", http.StatusBadRequest)
+			return
+		}
+	}
+
+	diff, err := h.articleService.DiffRevisions(r.Context(), articleID, from, to, claims.UserID, claims.Role)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+func (h *ArticleHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	articleID, claims, ok := articleAndClaims(w, r)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	article, err := h.articleService.RestoreRevision(r.Context(), articleID, revision, claims.UserID, claims.Role)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(article)
+}
+
+func articleAndClaims(w http.ResponseWriter, r *http.Request) (int, *services.Claims, bool) {
+	articleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid article ID", http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return 0, nil, false
+	}
+	return articleID, claims, true
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrArticleNotFound):
+		http.Error(w, "Article not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrRevisionNotFound):
+		http.Error(w, "Revision not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrArticleAccessDenied):
+		http.Error(w, "Access denied", http.StatusForbidden)
+	case errors.Is(err, services.ErrDiffTooLarge):
+		http.Error(w, "Revisions are too large to compare", http.StatusUnprocessableEntity)
+	default:
+		logrus.Errorf("Article revision operation failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+)
+
+// SearchArticles - GET /api/articles/search?q=...; постраничный вывод, как у
+// списка статей.
+func (h *ArticleHandler) SearchArticles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := models.ArticleSearchRequest{
+		Q:      strings.TrimSpace(query.Get("q")),
+		Lang:   query.Get("lang"),
+		Limit:  10,
+		Offset: 0,
+	}
+
+	details := map[string]string{}
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		details = h.validator.FormatValidationErrors(err)
+	}
+	if v := query.Get("author_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			req.AuthorID = id
+		} else {
+			details["author_id"] = "Invalid value"
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if from, _, ok := parseSearchDate(v); ok {
+			req.From = &from
+		} else {
+			details["from"] = "Must be a date (YYYY-MM-DD) or RFC 3339 time"
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, dateOnly, ok := parseSearchDate(v); ok {
+			// Дата без времени включает весь день.
+			if dateOnly {
+				to = to.AddDate(0, 0, 1)
+			}
+			req.To = &to
+		} else {
+			details["to"] = "Must be a date (YYYY-MM-DD) or RFC 3339 time"
+		}
+	}
+	if len(details) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Validation failed",
+			"details": details,
+		})
+		return
+	}
+
+	page, ok := offsetPageParams(w, r, req.Limit)
+	if !ok {
+		return
+	}
+	req.Limit, req.Offset = page.Limit, page.Offset
+
+	results, err := h.articleService.SearchArticles(r.Context(), &req, viewer(r))
+	if err != nil {
+		logrus.Errorf("Failed to search articles: %v", err)
+		http.Error(w, "Failed to search articles", http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, results)
+}
+
+func parseSearchDate(value string) (time.Time, bool, bool) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, true
+	}
+	return time.Time{}, false, false
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/pagination"
+	"goida/internal/repository"
+)
+
+type AuditHandler struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditHandler(auditRepo repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+func (h *AuditHandler) ListUserEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	page, ok := pageParams(w, r, 50)
+	if !ok {
+		return
+	}
+
+	events, err := h.auditRepo.ListByUser(r.Context(), userID, page)
+	if err != nil {
+		logrus.Errorf("Failed to list audit events: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	total, err := h.auditRepo.CountByUser(r.Context(), userID)
+	if err != nil {
+		logrus.Errorf("Failed to count audit events: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, pagination.NewPage(events, page, total, func(event *models.AuditEvent) (time.Time, int64) {
+		return event.CreatedAt, event.ID
+	}))
+}
@@ -2,120 +2,151 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 
 	"goida/internal/middleware"
 	"goida/internal/models"
-	"goida/internal/repository"
+	"goida/internal/services"
 )
 
 type AuthCredentialsHandler struct {
-	authCredentialsRepo repository.AuthCredentialsRepository
-	validator           *middleware.Validator
+	credentialsService services.CredentialsService
+	validator          *middleware.Validator
 }
 
-func NewAuthCredentialsHandler(authCredentialsRepo repository.AuthCredentialsRepository, validator *middleware.Validator) *AuthCredentialsHandler {
+func NewAuthCredentialsHandler(credentialsService services.CredentialsService, validator *middleware.Validator) *AuthCredentialsHandler {
 	return &AuthCredentialsHandler{
-		authCredentialsRepo: authCredentialsRepo,
-		validator:           validator,
+		credentialsService: credentialsService,
+		validator:          validator,
 	}
 }
 
-func (h *AuthCredentialsHandler) CreateCredentials(w http.ResponseWriter, r *http.Request) {
-	var req models.CreateAuthCredentialsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+func (h *AuthCredentialsHandler) GetOwnCredentials(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.validator.ValidateStruct(&req); err != nil {
-		validationErrors := h.validator.FormatValidationErrors(err)
-		response := map[string]interface{}{
-			"error":   "Validation failed",
-			"details": validationErrors,
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(response)
+	credentials, err := h.credentialsService.Get(r.Context(), claims.UserID)
+	if err != nil {
+		h.writeError(w, err)
 		return
 	}
 
-	// Хешируем пароль
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		logrus.Errorf("Failed to hash password: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentials)
+}
+
+func (h *AuthCredentialsHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangePasswordRequest
+	if !h.decode(w, r, &req) {
 		return
 	}
 
-	credentials := &models.AuthCredentials{
-		UserID:   req.UserID,
-		Login:    req.Login,
-		Password: string(hashedPassword),
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
 	}
 
-	err = h.authCredentialsRepo.Create(credentials)
-	if err != nil {
-		logrus.Errorf("Failed to create auth credentials: %v", err)
-		http.Error(w, "Failed to create credentials", http.StatusInternalServerError)
+	if err := h.credentialsService.ChangePassword(r.Context(), claims, &req, clientIP(r)); err != nil {
+		if writePasswordPolicyError(w, err, "new_password") {
+			return
+		}
+		h.writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Credentials created successfully",
-		"user_id": credentials.UserID,
-		"login":   credentials.Login,
+		"message": "Password changed successfully",
 	})
 }
 
-func (h *AuthCredentialsHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["userId"])
+func (h *AuthCredentialsHandler) ChangeLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangeLoginRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	credentials, err := h.credentialsService.ChangeLogin(r.Context(), claims, &req, clientIP(r))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		h.writeError(w, err)
 		return
 	}
 
-	credentials, err := h.authCredentialsRepo.GetByUserID(userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentials)
+}
+
+func (h *AuthCredentialsHandler) GetUserCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		logrus.Errorf("Failed to get auth credentials: %v", err)
-		http.Error(w, "Credentials not found", http.StatusNotFound)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	response := map[string]interface{}{
-		"user_id":    credentials.UserID,
-		"login":      credentials.Login,
-		"created_at": credentials.CreatedAt,
+	credentials, err := h.credentialsService.Get(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(credentials)
 }
 
-func (h *AuthCredentialsHandler) UpdateCredentials(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["userId"])
+func (h *AuthCredentialsHandler) UpdateUserCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
 	var req models.UpdateAuthCredentialsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !h.decode(w, r, &req) {
 		return
 	}
 
-	if err := h.validator.ValidateStruct(&req); err != nil {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	credentials, err := h.credentialsService.AdminUpdate(r.Context(), claims.UserID, userID, &req, clientIP(r))
+	if err != nil {
+		if writePasswordPolicyError(w, err, "password") {
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentials)
+}
+
+func (h *AuthCredentialsHandler) decode(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	if err := h.validator.ValidateStruct(req); err != nil {
 		validationErrors := h.validator.FormatValidationErrors(err)
 		response := map[string]interface{}{
 			"error":   "Validation failed",
@@ -125,41 +156,29 @@ func (h *AuthCredentialsHandler) UpdateCredentials(w http.ResponseWriter, r *htt
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		json.NewEncoder(w).Encode(response)
-		return
+		return false
 	}
+	return true
+}
 
-	// Получаем существующие учетные данные
-	credentials, err := h.authCredentialsRepo.GetByUserID(userID)
-	if err != nil {
-		logrus.Errorf("Failed to get auth credentials: %v", err)
+func (h *AuthCredentialsHandler) writeError(w http.ResponseWriter, err error) {
+	var limitErr *services.TooManyAttemptsError
+	switch {
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		http.Error(w, "Too many login attempts", http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrWrongPassword):
+		http.Error(w, "Current password is incorrect", http.StatusForbidden)
+	case errors.Is(err, services.ErrLoginTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrUserOutranks):
+		http.Error(w, "Access denied", http.StatusForbidden)
+	case errors.Is(err, services.ErrCredentialsNotFound):
 		http.Error(w, "Credentials not found", http.StatusNotFound)
-		return
-	}
-
-	if req.Login != "" {
-		credentials.Login = req.Login
-	}
-	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			logrus.Errorf("Failed to hash password: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
-		}
-		credentials.Password = string(hashedPassword)
-	}
-
-	err = h.authCredentialsRepo.Update(userID, credentials)
-	if err != nil {
-		logrus.Errorf("Failed to update auth credentials: %v", err)
+	case errors.Is(err, services.ErrNothingToUpdate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logrus.Errorf("Credentials operation failed: %v", err)
 		http.Error(w, "Failed to update credentials", http.StatusInternalServerError)
-		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Credentials updated successfully",
-		"user_id": credentials.UserID,
-		"login":   credentials.Login,
-	})
 }
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 
 	"goida/internal/middleware"
@@ -43,28 +47,207 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.Authenticate(req.Login, req.Password)
+	user, err := h.authService.Authenticate(r.Context(), req.Login, req.Password, clientIP(r))
 	if err != nil {
+		var limitErr *services.TooManyAttemptsError
+		if errors.As(err, &limitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			http.Error(w, "Too many login attempts", http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, services.ErrAccountDisabled) {
+			http.Error(w, "Account is disabled", http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(user)
+	twoFactorEnabled, err := h.authService.TwoFactorEnabled(r.Context(), user)
 	if err != nil {
-		logrus.Errorf("Failed to generate token: %v", err)
+		logrus.Errorf("Failed to check two-factor status: %v", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.AuthResponse{
-		Token: token,
-		User:  *user,
+	if twoFactorEnabled {
+		challenge, err := h.authService.NewTwoFactorChallenge(user)
+		if err != nil {
+			logrus.Errorf("Failed to create two-factor challenge: %v", err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
+		return
+	}
+
+	response, err := h.authService.StartSession(r.Context(), user, clientInfo(r))
+	if err != nil {
+		logrus.Errorf("Failed to start session: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response, err := h.authService.CompleteTwoFactor(r.Context(), req.ChallengeToken, req.Code, clientInfo(r))
+	if err != nil {
+		var limitErr *services.TooManyAttemptsError
+		switch {
+		case errors.As(err, &limitErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			http.Error(w, "Too many login attempts", http.StatusTooManyRequests)
+		case errors.Is(err, services.ErrInvalidChallenge), errors.Is(err, services.ErrTwoFactorNotEnrolled):
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrAccountDisabled):
+			http.Error(w, "Account is disabled", http.StatusForbidden)
+		default:
+			logrus.Errorf("Failed to complete two-factor login: %v", err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response, err := h.authService.Refresh(r.Context(), req.RefreshToken, clientIP(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		logrus.Errorf("Failed to refresh token: %v", err)
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), claims.SessionID); err != nil {
+		logrus.Errorf("Failed to logout: %v", err)
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	page, ok := offsetPageParams(w, r, 50)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), claims, page)
+	if err != nil {
+		logrus.Errorf("Failed to list sessions: %v", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), claims.UserID, mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		logrus.Errorf("Failed to revoke session: %v", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей.
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.authService.RevokeOtherSessions(r.Context(), claims); err != nil {
+		logrus.Errorf("Failed to revoke other sessions: %v", err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	claims, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
@@ -72,14 +255,30 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := models.User{
-		ID:    claims.UserID,
-		Email: claims.Email,
-		Role: &models.Role{
-			Name: claims.Role,
+	permissions, err := h.authService.Permissions(r.Context(), claims.Role)
+	if err != nil {
+		logrus.Errorf("Failed to load permissions: %v", err)
+		http.Error(w, "Failed to load permissions", http.StatusInternalServerError)
+		return
+	}
+
+	profile := models.ProfileResponse{
+		User: models.User{
+			ID:    claims.UserID,
+			Email: claims.Email,
+			Role: &models.Role{
+				Name:        claims.Role,
+				Permissions: permissions,
+			},
 		},
 	}
+	if claims.IsImpersonated() {
+		profile.Impersonation = &models.Impersonation{ActorID: claims.ActorID}
+		if claims.ExpiresAt != nil {
+			profile.Impersonation.ExpiresAt = claims.ExpiresAt.Time
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(profile)
 }
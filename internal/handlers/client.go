@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+)
+
+// clientIP возвращает адрес клиента без порта.
+func clientIP(r *http.Request) string {
+	return middleware.ClientIP(r)
+}
+
+// clientInfo собирает сведения о клиенте для новой сессии.
+func clientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -51,6 +52,10 @@ func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.service.Create(r.Context(), articleID, claims.UserID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(w, "Email verification required", http.StatusForbidden)
+			return
+		}
 		logrus.Errorf("Failed to create comment: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -69,27 +74,23 @@ func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit, offset := 10, 0
-	if v := r.URL.Query().Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			limit = n
-		}
-	}
-	if v := r.URL.Query().Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+	page, ok := pageParams(w, r, 10)
+	if !ok {
+		return
 	}
 
-	items, err := h.service.ListByArticle(r.Context(), articleID, limit, offset)
+	items, err := h.service.ListByArticle(r.Context(), articleID, viewer(r), page)
 	if err != nil {
+		if errors.Is(err, services.ErrArticleNotFound) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
 		logrus.Errorf("Failed to list comments: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(items)
+	writePage(w, r, items)
 }
 
 func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +125,14 @@ func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Access denied", http.StatusForbidden)
 			return
 		}
+		if err.Error() == "comment not found" {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrArticleNotFound) {
+			http.Error(w, "Article not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -145,11 +154,15 @@ func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteOwned(r.Context(), id64, claims.UserID); err != nil {
+	if err := h.service.Delete(r.Context(), id64, claims.UserID, claims.Role); err != nil {
 		if err.Error() == "not found or not owner" {
 			http.Error(w, "Access denied", http.StatusForbidden)
 			return
 		}
+		if err.Error() == "comment not found" {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+// DataExportHandler - выгрузка персональных данных: своих по /api/users/me и
+// любого пользователя по /api/admin/users/{id}.
+type DataExportHandler struct {
+	exportService services.DataExportService
+}
+
+func NewDataExportHandler(exportService services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{exportService: exportService}
+}
+
+func (h *DataExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.export(w, r, claims.UserID, claims.UserID, "/api/users/me")
+}
+
+func (h *DataExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.status(w, r, claims.UserID, "/api/users/me")
+}
+
+func (h *DataExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.download(w, r, claims.UserID)
+}
+
+func (h *DataExportHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	h.export(w, r, actorID, userID, fmt.Sprintf("/api/admin/users/%d", userID))
+}
+
+func (h *DataExportHandler) GetUserExport(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	h.status(w, r, userID, fmt.Sprintf("/api/admin/users/%d", userID))
+}
+
+func (h *DataExportHandler) DownloadUserExport(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	h.download(w, r, userID)
+}
+
+// export отдает архив сразу или, для больших учетных записей и при
+// ?async=true, отвечает 202 с адресом, по которому опрашивать статус.
+func (h *DataExportHandler) export(w http.ResponseWriter, r *http.Request, actorID, userID int, basePath string) {
+	async, _ := strconv.ParseBool(r.URL.Query().Get("async"))
+
+	export, archive, err := h.exportService.Export(r.Context(), actorID, userID, async, clientIP(r))
+	if err != nil {
+		writeDataExportError(w, err)
+		return
+	}
+
+	if archive != nil {
+		writeArchive(w, userID, time.Now(), archive)
+		return
+	}
+
+	setExportURLs(export, basePath)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", export.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+func (h *DataExportHandler) status(w http.ResponseWriter, r *http.Request, userID int, basePath string) {
+	exportID, ok := exportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.exportService.Get(r.Context(), userID, exportID)
+	if err != nil {
+		writeDataExportError(w, err)
+		return
+	}
+
+	setExportURLs(export, basePath)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
+func (h *DataExportHandler) download(w http.ResponseWriter, r *http.Request, userID int) {
+	exportID, ok := exportIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	export, archive, err := h.exportService.Archive(r.Context(), userID, exportID)
+	if err != nil {
+		writeDataExportError(w, err)
+		return
+	}
+
+	writeArchive(w, userID, export.CreatedAt, archive)
+}
+
+func exportIDFromRequest(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	exportID, err := strconv.ParseInt(mux.Vars(r)["exportId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return exportID, true
+}
+
+func setExportURLs(export *models.DataExport, basePath string) {
+	export.StatusURL = fmt.Sprintf("%s/exports/%d", basePath, export.ID)
+	if export.Status == models.DataExportReady {
+		export.DownloadURL = export.StatusURL + "/download"
+	}
+}
+
+func writeArchive(w http.ResponseWriter, userID int, createdAt time.Time, archive []byte) {
+	filename := fmt.Sprintf("goida-export-user-%d-%s.zip", userID, createdAt.UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(archive)
+}
+
+func writeDataExportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrDataExportNotFound):
+		http.Error(w, "Export not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrDataExportNotReady):
+		http.Error(w, "Export is not ready", http.StatusConflict)
+	case errors.Is(err, services.ErrDataExportExpired):
+		http.Error(w, "Export has expired", http.StatusGone)
+	default:
+		logrus.Errorf("Data export failed: %v", err)
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+type EmailVerificationHandler struct {
+	emailVerificationService services.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(emailVerificationService services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+	}
+}
+
+func (h *EmailVerificationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.emailVerificationService.Verify(r.Context(), token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+		logrus.Errorf("Failed to verify email: %v", err)
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"verified": true,
+	})
+}
+
+func (h *EmailVerificationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.emailVerificationService.Resend(r.Context(), claims.UserID); err != nil {
+		var limitErr *services.TooManyAttemptsError
+		switch {
+		case errors.As(err, &limitErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			http.Error(w, "Verification email was sent recently", http.StatusTooManyRequests)
+		case errors.Is(err, services.ErrEmailAlreadyVerified):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			logrus.Errorf("Failed to resend verification email: %v", err)
+			http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Verification email has been sent",
+	})
+}
+
+func (h *EmailVerificationHandler) SetVerified(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateEmailVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.emailVerificationService.SetVerified(r.Context(), claims.UserID, userID, req.Verified, clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrUserOutranks):
+			http.Error(w, "Access denied", http.StatusForbidden)
+		default:
+			logrus.Errorf("Failed to update email verification: %v", err)
+			http.Error(w, "Failed to update email verification", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+type ImpersonationHandler struct {
+	impersonationService services.ImpersonationService
+	validator            *middleware.Validator
+}
+
+func NewImpersonationHandler(impersonationService services.ImpersonationService, validator *middleware.Validator) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+		validator:            validator,
+	}
+}
+
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := h.impersonationService.Start(r.Context(), claims, userID, &req, clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrAccountDisabled), errors.Is(err, services.ErrImpersonateSelf),
+			errors.Is(err, services.ErrImpersonatePrivileged), errors.Is(err, services.ErrImpersonationNested):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": err.Error(),
+			})
+		default:
+			logrus.Errorf("Impersonation failed: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/services"
+)
+
+type LockoutHandler struct {
+	loginLimiter *services.LoginLimiter
+}
+
+func NewLockoutHandler(loginLimiter *services.LoginLimiter) *LockoutHandler {
+	return &LockoutHandler{
+		loginLimiter: loginLimiter,
+	}
+}
+
+func (h *LockoutHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.loginLimiter.ListLockouts(r.Context())
+	if err != nil {
+		logrus.Errorf("Failed to list lockouts: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lockouts)
+}
+
+func (h *LockoutHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if key == "" {
+		http.Error(w, "Invalid lockout key", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.loginLimiter.ClearLockout(r.Context(), key); err != nil {
+		logrus.Errorf("Failed to clear lockout: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
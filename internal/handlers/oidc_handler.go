@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/oidc"
+	"goida/internal/services"
+)
+
+const (
+	oidcStateCookie     = "goida_oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc/"
+)
+
+type OIDCHandler struct {
+	oidcService services.OIDCService
+	authService *services.AuthService
+	validator   *middleware.Validator
+	publicURL   string
+	secure      bool
+}
+
+// NewOIDCHandler создает обработчик входа через внешних провайдеров.
+// publicURL - адрес фронтенда, куда браузер возвращается после входа.
+func NewOIDCHandler(oidcService services.OIDCService, authService *services.AuthService, validator *middleware.Validator, publicURL, apiURL string) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		authService: authService,
+		validator:   validator,
+		publicURL:   strings.TrimSuffix(publicURL, "/"),
+		secure:      strings.HasPrefix(apiURL, "https://"),
+	}
+}
+
+func (h *OIDCHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.oidcService.Providers())
+}
+
+// Login перенаправляет браузер на страницу входа провайдера. Состояние
+// (state, nonce, PKCE verifier) подписывается и сохраняется в cookie.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.oidcService.Begin(r.Context(), mux.Vars(r)["provider"])
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			http.Error(w, "Unknown identity provider", http.StatusNotFound)
+			return
+		}
+		logrus.Errorf("Failed to start OIDC login: %v", err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	h.setStateCookie(w, state, 0)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback принимает код от провайдера и возвращает браузер на фронтенд с
+// одноразовым кодом входа во фрагменте URL (#sso_code=...). Токены в адресной
+// строке не передаются: фронтенд обменивает код через Exchange.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	h.setStateCookie(w, "", -1)
+
+	if idpErr := query.Get("error"); idpErr != "" {
+		logrus.Warnf("Identity provider returned error %q: %s", idpErr, query.Get("error_description"))
+		h.redirectToApp(w, r, "sso_error", "provider_error")
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || query.Get("code") == "" {
+		h.redirectToApp(w, r, "sso_error", "invalid_state")
+		return
+	}
+
+	code, err := h.oidcService.Complete(r.Context(), mux.Vars(r)["provider"], query.Get("code"), query.Get("state"), cookie.Value)
+	if err != nil {
+		h.redirectToApp(w, r, "sso_error", callbackErrorCode(err))
+		return
+	}
+
+	h.redirectToApp(w, r, "sso_code", code)
+}
+
+// Exchange обменивает одноразовый код на сессию. Ответ совпадает с ответом
+// /api/auth/login, включая второй шаг 2FA, если провайдер не подтвердил
+// второй фактор.
+func (h *OIDCHandler) Exchange(w http.ResponseWriter, r *http.Request) {
+	var req models.OIDCExchangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	user, mfa, err := h.oidcService.RedeemLoginCode(r.Context(), req.Code)
+	if err != nil {
+		http.Error(w, "Invalid or expired login code", http.StatusUnauthorized)
+		return
+	}
+	if !user.CanSignIn(time.Now()) {
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+		return
+	}
+
+	if !mfa {
+		twoFactorEnabled, err := h.authService.TwoFactorEnabled(r.Context(), user)
+		if err != nil {
+			logrus.Errorf("Failed to check two-factor status: %v", err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		if twoFactorEnabled {
+			challenge, err := h.authService.NewTwoFactorChallenge(user)
+			if err != nil {
+				logrus.Errorf("Failed to create two-factor challenge: %v", err)
+				http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(challenge)
+			return
+		}
+	}
+
+	response, err := h.authService.StartFederatedSession(r.Context(), user, mfa, clientInfo(r))
+	if err != nil {
+		logrus.Errorf("Failed to start session: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *OIDCHandler) setStateCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *OIDCHandler) redirectToApp(w http.ResponseWriter, r *http.Request, key, value string) {
+	http.Redirect(w, r, h.publicURL+"/#"+url.Values{key: {value}}.Encode(), http.StatusFound)
+}
+
+func callbackErrorCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider):
+		return "unknown_provider"
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		return "invalid_state"
+	case errors.Is(err, services.ErrOIDCEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, services.ErrOIDCAccountConflict):
+		return "account_conflict"
+	case errors.Is(err, services.ErrOIDCProvisioningDisabled):
+		return "account_not_found"
+	case errors.Is(err, services.ErrAccountDisabled):
+		return "account_disabled"
+	case errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrExchangeFailed):
+		logrus.Warnf("OIDC login rejected: %v", err)
+		return "provider_error"
+	default:
+		logrus.Errorf("Failed to complete OIDC login: %v", err)
+		return "server_error"
+	}
+}
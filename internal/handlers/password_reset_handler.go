@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService services.PasswordResetService
+	validator            *middleware.Validator
+}
+
+func NewPasswordResetHandler(passwordResetService services.PasswordResetService, validator *middleware.Validator) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: passwordResetService,
+		validator:            validator,
+	}
+}
+
+func (h *PasswordResetHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Ответ не должен зависеть от того, есть ли такой email: сервис
+	// возвращает только ошибку ограничения частоты.
+	if err := h.passwordResetService.RequestReset(r.Context(), req.Email, clientIP(r)); err != nil {
+		var limitErr *services.TooManyAttemptsError
+		if errors.As(err, &limitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			http.Error(w, "Too many password reset requests", http.StatusTooManyRequests)
+			return
+		}
+		logrus.Errorf("Failed to request password reset: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+func (h *PasswordResetHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(r.Context(), req.Token, req.Password, clientIP(r)); err != nil {
+		if writePasswordPolicyError(w, err, "password") {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		logrus.Errorf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Password has been reset",
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+type PersonalAccessTokenHandler struct {
+	tokenService services.PersonalAccessTokenService
+	validator    *middleware.Validator
+}
+
+func NewPersonalAccessTokenHandler(tokenService services.PersonalAccessTokenService, validator *middleware.Validator) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		tokenService: tokenService,
+		validator:    validator,
+	}
+}
+
+func (h *PersonalAccessTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreatePersonalAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		validationErrors := h.validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := h.tokenService.Create(r.Context(), claims.UserID, &req, clientIP(r))
+	if err != nil {
+		logrus.Errorf("Failed to create personal access token: %v", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *PersonalAccessTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTokens(w, r, claims.UserID)
+}
+
+func (h *PersonalAccessTokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.revoke(w, r, claims.UserID, claims.UserID)
+}
+
+func (h *PersonalAccessTokenHandler) ListUserTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	h.writeTokens(w, r, userID)
+}
+
+func (h *PersonalAccessTokenHandler) RevokeUserToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	h.revoke(w, r, claims.UserID, userID)
+}
+
+func (h *PersonalAccessTokenHandler) writeTokens(w http.ResponseWriter, r *http.Request, userID int) {
+	tokens, err := h.tokenService.List(r.Context(), userID)
+	if err != nil {
+		logrus.Errorf("Failed to list personal access tokens: %v", err)
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []*models.PersonalAccessToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *PersonalAccessTokenHandler) revoke(w http.ResponseWriter, r *http.Request, actorID, userID int) {
+	tokenID, err := strconv.ParseInt(mux.Vars(r)["tokenId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tokenService.Revoke(r.Context(), actorID, userID, tokenID, clientIP(r)); err != nil {
+		if errors.Is(err, services.ErrAccessTokenNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		logrus.Errorf("Failed to revoke personal access token: %v", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goida/internal/middleware"
+	"goida/internal/pagination"
+)
+
+// decodeAndValidate разбирает JSON-тело запроса и проверяет его. При ошибке
+// ответ уже записан и возвращается false.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, validator *middleware.Validator, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	if err := validator.ValidateStruct(req); err != nil {
+		validationErrors := validator.FormatValidationErrors(err)
+		response := map[string]interface{}{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(response)
+		return false
+	}
+	return true
+}
+
+// pageParams читает параметры страницы списка. При некорректном курсоре ответ
+// уже записан и возвращается false.
+func pageParams(w http.ResponseWriter, r *http.Request, defaultLimit int) (pagination.Params, bool) {
+	params, err := pagination.Parse(r, defaultLimit)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return params, false
+	}
+	return params, true
+}
+
+// offsetPageParams - pageParams для списков, которые листаются только
+// смещением.
+func offsetPageParams(w http.ResponseWriter, r *http.Request, defaultLimit int) (pagination.Params, bool) {
+	params, ok := pageParams(w, r, defaultLimit)
+	if ok && params.Cursor != nil {
+		http.Error(w, "Cursor pagination is not supported for this list", http.StatusBadRequest)
+		return params, false
+	}
+	return params, ok
+}
+
+// writePage отдает страницу списка в конверте с заголовком Link.
+func writePage[T any](w http.ResponseWriter, r *http.Request, page pagination.Page[T]) {
+	page.SetLinkHeader(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
@@ -2,27 +2,32 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 
-	"goida/internal/repository"
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
 )
 
 type RoleHandler struct {
-	roleRepo repository.RoleRepository
+	roleService services.RoleService
+	validator   *middleware.Validator
 }
 
-func NewRoleHandler(roleRepo repository.RoleRepository) *RoleHandler {
+func NewRoleHandler(roleService services.RoleService, validator *middleware.Validator) *RoleHandler {
 	return &RoleHandler{
-		roleRepo: roleRepo,
+		roleService: roleService,
+		validator:   validator,
 	}
 }
 
 func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
-	roles, err := h.roleRepo.List()
+	roles, err := h.roleService.List(r.Context())
 	if err != nil {
 		logrus.Errorf("Failed to list roles: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -41,13 +46,135 @@ func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.roleRepo.GetByID(id)
+	role, err := h.roleService.Get(r.Context(), id)
 	if err != nil {
-		logrus.Errorf("Failed to get role: %v", err)
-		http.Error(w, "Role not found", http.StatusNotFound)
+		writeRoleError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(role)
 }
+
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRoleRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	role, err := h.roleService.Create(r.Context(), claims.UserID, &req)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	role, err := h.roleService.Update(r.Context(), claims.UserID, id, &req)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.roleService.Delete(r.Context(), id); err != nil {
+		writeRoleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignUserRole назначает пользователю роль по названию.
+func (h *RoleHandler) AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.roleService.AssignRole(r.Context(), claims.UserID, userID, req.Role, clientIP(r))
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func writeRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		http.Error(w, "Role not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrUserOutranks), errors.Is(err, services.ErrPermissionNotHeld):
+		http.Error(w, "Access denied", http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidRoleName):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Validation failed",
+			"details": map[string]string{"name": err.Error()},
+		})
+	case errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleInUse),
+		errors.Is(err, services.ErrBuiltinRole), errors.Is(err, services.ErrLastAdmin):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		logrus.Errorf("Role operation failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
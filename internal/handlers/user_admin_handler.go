@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/middleware"
+	"goida/internal/models"
+	"goida/internal/services"
+)
+
+// UserAdminHandler - административное редактирование, удаление и
+// блокировка пользователей.
+type UserAdminHandler struct {
+	moderationService services.UserModerationService
+	validator         *middleware.Validator
+}
+
+func NewUserAdminHandler(moderationService services.UserModerationService, validator *middleware.Validator) *UserAdminHandler {
+	return &UserAdminHandler{
+		moderationService: moderationService,
+		validator:         validator,
+	}
+}
+
+func (h *UserAdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.AdminUpdateUserRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.moderationService.Update(r.Context(), actorID, userID, &req, clientIP(r))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.moderationService.Delete(r.Context(), actorID, userID, clientIP(r)); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserAdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.moderationService.Restore(r.Context(), actorID, userID, clientIP(r))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	var req models.SuspendUserRequest
+	if !decodeAndValidate(w, r, h.validator, &req) {
+		return
+	}
+
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.moderationService.Suspend(r.Context(), actorID, userID, &req, clientIP(r))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserAdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := actorAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.moderationService.Unsuspend(r.Context(), actorID, userID, clientIP(r))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// actorAndTarget возвращает id администратора из контекста и id
+// пользователя из URL.
+func actorAndTarget(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	claims, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User context not found", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	return claims.UserID, userID, true
+}
+
+func writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrUserOutranks):
+		http.Error(w, "Access denied", http.StatusForbidden)
+	case errors.Is(err, services.ErrSuspensionExpired):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Validation failed",
+			"details": map[string]string{"until": err.Error()},
+		})
+	case errors.Is(err, services.ErrEmailTaken), errors.Is(err, services.ErrCannotModerateSelf), errors.Is(err, services.ErrLastAdmin):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		logrus.Errorf("User moderation failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
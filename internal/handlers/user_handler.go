@@ -48,6 +48,9 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userService.CreateUserWithCredentials(&req)
 	if err != nil {
+		if writePasswordPolicyError(w, err, "password") {
+			return
+		}
 		logrus.Errorf("Failed to create user: %v", err)
 
 		// Проверяем тип ошибки для возврата соответствующего HTTP статуса
@@ -87,48 +90,27 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if user.IsDeleted || user.IsDeactivated() {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
 
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	claims, ok := middleware.GetUserFromContext(r.Context())
+	page, ok := pageParams(w, r, 10)
 	if !ok {
-		http.Error(w, "User context not found", http.StatusInternalServerError)
 		return
 	}
 
-	if claims.Role != models.RoleAdmin {
-		http.Error(w, "Access denied", http.StatusForbidden)
-		return
-	}
-
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 10
-	offset := 0
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
-	}
-
-	users, err := h.userService.ListUsers(limit, offset)
+	users, err := h.userService.ListUsers(page)
 	if err != nil {
 		logrus.Errorf("Failed to list users: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	writePage(w, r, users)
 }
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"goida/internal/password"
+)
+
+// writePasswordPolicyError отдает нарушения политики паролей в том же
+// формате, что и ошибки Validator. Возвращает false, если err - другая ошибка.
+func writePasswordPolicyError(w http.ResponseWriter, err error, field string) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	response := map[string]interface{}{
+		"error": "Validation failed",
+		"details": map[string]string{
+			field: policyErr.Error(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(response)
+	return true
+}
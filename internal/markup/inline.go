@@ -0,0 +1,480 @@
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxLinkLabel ограничивает поиск ']' для текста ссылки, чтобы строка из
+// множества '[' не разбиралась за квадратичное время.
+const maxLinkLabel = 1000
+
+// maxLinkDestination так же ограничивает разбор адреса и заголовка ссылки:
+// "[a](" без закрывающей скобки иначе просматривается до конца строки.
+const maxLinkDestination = 4000
+
+var (
+	entityPattern   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	urlAutolink     = regexp.MustCompile(`^<((?i:https?|mailto):[^\s<>]*)>`)
+	emailAutolink   = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	allowedSchemes  = map[string]bool{"http": true, "https": true, "mailto": true}
+	externalSchemes = map[string]bool{"http": true, "https": true}
+)
+
+// inlineNode - фрагмент строки: готовый HTML или серия разделителей
+// выделения (*, _, ~~), которые еще могут стать тегами.
+type inlineNode struct {
+	html     string
+	delim    byte
+	count    int
+	orig     int
+	canOpen  bool
+	canClose bool
+	open     string
+	close    string
+}
+
+// inline отрисовывает строчную разметку: выделение, код, ссылки,
+// изображения, автоссылки и жесткие переносы. В inLink вложенные ссылки не
+// создаются.
+func inline(src string, inLink bool) string {
+	var nodes []*inlineNode
+	var buf strings.Builder
+	// unclosed - длины серий '`', для которых дальше по строке нет пары.
+	unclosed := make(map[int]bool)
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, &inlineNode{html: buf.String()})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && isASCIIPunct(src[i+1]):
+			buf.WriteString(html.EscapeString(src[i+1 : i+2]))
+			i += 2
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			buf.WriteString("<br />\n")
+			i += 2
+		case c == '`':
+			n := run(src, i, '`')
+			end := -1
+			if !unclosed[n] {
+				end = findCodeClose(src, i+n, n)
+			}
+			if end < 0 {
+				unclosed[n] = true
+				buf.WriteString(src[i : i+n])
+				i += n
+				continue
+			}
+			buf.WriteString("<code>" + html.EscapeString(codeSpan(src[i+n:end])) + "</code>")
+			i = end + n
+		case c == '!' && i+1 < len(src) && src[i+1] == '[':
+			if out, n, ok := parseLink(src[i+1:], true, inLink); ok {
+				buf.WriteString(out)
+				i += 1 + n
+			} else {
+				buf.WriteByte('!')
+				i++
+			}
+		case c == '[' && !inLink:
+			if out, n, ok := parseLink(src[i:], false, inLink); ok {
+				buf.WriteString(out)
+				i += n
+			} else {
+				buf.WriteByte('[')
+				i++
+			}
+		case c == '<':
+			if out, n, ok := autolink(src[i:], inLink); ok {
+				buf.WriteString(out)
+				i += n
+			} else {
+				buf.WriteString("&lt;")
+				i++
+			}
+		case c == '&':
+			if m := entityPattern.FindString(src[i:]); m != "" {
+				buf.WriteString(html.EscapeString(html.UnescapeString(m)))
+				i += len(m)
+			} else {
+				buf.WriteString("&amp;")
+				i++
+			}
+		case c == '*' || c == '_' || c == '~':
+			n := run(src, i, c)
+			if c == '~' && n != 2 {
+				buf.WriteString(src[i : i+n])
+				i += n
+				continue
+			}
+			flush()
+			nodes = append(nodes, delimiterNode(src, i, n))
+			i += n
+		case c == '\n':
+			text := buf.String()
+			trimmed := strings.TrimRight(text, " ")
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if len(text)-len(trimmed) >= 2 {
+				buf.WriteString("<br />\n")
+			} else {
+				buf.WriteByte('\n')
+			}
+			for i++; i < len(src) && src[i] == ' '; i++ {
+			}
+		default:
+			j := i + 1
+			for j < len(src) && !strings.ContainsRune("\\`![<&*_~\n", rune(src[j])) {
+				j++
+			}
+			buf.WriteString(html.EscapeString(src[i:j]))
+			i = j
+		}
+	}
+	flush()
+
+	processEmphasis(nodes)
+
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.delim == 0 {
+			b.WriteString(n.html)
+			continue
+		}
+		b.WriteString(n.close)
+		b.WriteString(strings.Repeat(string(n.delim), n.count))
+		b.WriteString(n.open)
+	}
+	return b.String()
+}
+
+// delimiterNode определяет, может ли серия разделителей открывать и
+// закрывать выделение (правила flanking из CommonMark).
+func delimiterNode(src string, i, n int) *inlineNode {
+	prev, next := ' ', ' '
+	if i > 0 {
+		prev, _ = utf8.DecodeLastRuneInString(src[:i])
+	}
+	if i+n < len(src) {
+		next, _ = utf8.DecodeRuneInString(src[i+n:])
+	}
+
+	left := !unicode.IsSpace(next) && (!isPunct(next) || unicode.IsSpace(prev) || isPunct(prev))
+	right := !unicode.IsSpace(prev) && (!isPunct(prev) || unicode.IsSpace(next) || isPunct(next))
+
+	node := &inlineNode{delim: src[i], count: n, orig: n, canOpen: left, canClose: right}
+	if src[i] == '_' {
+		node.canOpen = left && (!right || isPunct(prev))
+		node.canClose = right && (!left || isPunct(next))
+	}
+	return node
+}
+
+// processEmphasis сопоставляет закрывающие разделители с ближайшими
+// подходящими открывающими. Разделители между парой становятся текстом,
+// поэтому теги всегда правильно вложены. bottom запоминает, ниже какого
+// узла открывающих для такого закрывающего уже нет (openers_bottom из
+// CommonMark), - это держит разбор линейным.
+func processEmphasis(nodes []*inlineNode) {
+	type bottomKey struct {
+		delim   byte
+		mod     int
+		canOpen bool
+	}
+	bottom := make(map[bottomKey]int)
+
+	for ci, closer := range nodes {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			key := bottomKey{closer.delim, closer.orig % 3, closer.canOpen}
+			lo, ok := bottom[key]
+			if !ok {
+				lo = -1
+			}
+
+			oi := -1
+			for j := ci - 1; j > lo; j-- {
+				o := nodes[j]
+				if o.delim != closer.delim || !o.canOpen || o.count == 0 {
+					continue
+				}
+				if closer.delim == '~' && o.count < 2 {
+					continue
+				}
+				if closer.delim != '~' && (o.canClose || closer.canOpen) &&
+					(o.orig+closer.orig)%3 == 0 && !(o.orig%3 == 0 && closer.orig%3 == 0) {
+					continue
+				}
+				oi = j
+				break
+			}
+			if oi < 0 {
+				bottom[key] = ci - 1
+				break
+			}
+
+			opener := nodes[oi]
+			use, tag := 1, "em"
+			switch {
+			case closer.delim == '~':
+				use, tag = 2, "del"
+			case opener.count >= 2 && closer.count >= 2:
+				use, tag = 2, "strong"
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.open = "<" + tag + ">" + opener.open
+			closer.close += "</" + tag + ">"
+
+			for _, n := range nodes[oi+1 : ci] {
+				n.canOpen, n.canClose = false, false
+			}
+		}
+	}
+}
+
+// findCodeClose ищет серию ровно из n обратных кавычек.
+func findCodeClose(s string, from, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := run(s, j, '`')
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	return -1
+}
+
+func codeSpan(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	return code
+}
+
+// parseLink разбирает [текст](адрес "заголовок") в начале s и возвращает
+// HTML и число прочитанных байт. Небезопасная ссылка отрисовывается как
+// текст.
+func parseLink(s string, image, inLink bool) (string, int, bool) {
+	end := closingBracket(s)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", 0, false
+	}
+	dest, title, n, ok := parseDestination(s[end+1:])
+	if !ok {
+		return "", 0, false
+	}
+	label := inline(s[1:end], true)
+	consumed := end + 1 + n
+
+	href, scheme, safe := safeURL(dest)
+	if image {
+		alt := html.EscapeString(strings.Join(strings.Fields(plainText(label)), " "))
+		if !safe || scheme == "mailto" {
+			return alt, consumed, true
+		}
+		out := `<img src="` + href + `" alt="` + alt + `"`
+		if title != "" {
+			out += ` title="` + html.EscapeString(title) + `"`
+		}
+		return out + " />", consumed, true
+	}
+
+	if !safe || inLink {
+		return label, consumed, true
+	}
+	out := `<a href="` + href + `"`
+	if title != "" {
+		out += ` title="` + html.EscapeString(title) + `"`
+	}
+	if externalSchemes[scheme] || strings.HasPrefix(dest, "//") {
+		out += ` rel="nofollow noopener noreferrer"`
+	}
+	return out + ">" + label + "</a>", consumed, true
+}
+
+// closingBracket возвращает позицию ']', парной к '[' в начале s. Пара для
+// кода тоже ищется только в пределах maxLinkLabel: иначе каждая '[' перед
+// непарной '`' просматривала бы строку до конца.
+func closingBracket(s string) int {
+	if len(s) > maxLinkLabel+1 {
+		s = s[:maxLinkLabel+1]
+	}
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := run(s, j, '`')
+			if end := findCodeClose(s, j+n, n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseDestination разбирает (адрес "заголовок") в начале s.
+func parseDestination(s string) (dest, title string, n int, ok bool) {
+	if len(s) > maxLinkDestination {
+		s = s[:maxLinkDestination]
+	}
+	k := 1
+	skipSpaces := func() {
+		for k < len(s) && (s[k] == ' ' || s[k] == '\n') {
+			k++
+		}
+	}
+	skipSpaces()
+
+	if k < len(s) && s[k] == '<' {
+		end := strings.IndexAny(s[k+1:], ">\n")
+		if end < 0 || s[k+1+end] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[k+1 : k+1+end]
+		k += end + 2
+	} else {
+		start, parens := k, 0
+	loop:
+		for k < len(s) {
+			switch c := s[k]; {
+			case c == '\\' && k+1 < len(s) && isASCIIPunct(s[k+1]):
+				k += 2
+				continue
+			case c <= ' ':
+				break loop
+			case c == '(':
+				parens++
+			case c == ')':
+				if parens == 0 {
+					break loop
+				}
+				parens--
+			}
+			k++
+		}
+		dest = s[start:k]
+	}
+	skipSpaces()
+
+	if k < len(s) && (s[k] == '"' || s[k] == '\'') {
+		quote := s[k]
+		end := -1
+		for j := k + 1; j < len(s); j++ {
+			if s[j] == '\\' {
+				j++
+				continue
+			}
+			if s[j] == quote {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = unescape(s[k+1 : end])
+		k = end + 1
+		skipSpaces()
+	}
+
+	if k >= len(s) || s[k] != ')' {
+		return "", "", 0, false
+	}
+	return unescape(dest), title, k + 1, true
+}
+
+// safeURL проверяет адрес ссылки: допускаются относительные адреса и схемы
+// из allowedSchemes. Возвращает экранированный для атрибута адрес и схему.
+func safeURL(raw string) (string, string, bool) {
+	u := strings.TrimSpace(raw)
+	for _, c := range u {
+		if c < ' ' || c == 0x7f {
+			return "", "", false
+		}
+	}
+
+	scheme := ""
+	if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+		scheme = strings.ToLower(u[:i])
+		if !allowedSchemes[scheme] {
+			return "", "", false
+		}
+	}
+	return html.EscapeString(strings.ReplaceAll(u, " ", "%20")), scheme, true
+}
+
+func autolink(s string, inLink bool) (string, int, bool) {
+	href, text := "", ""
+	var n int
+	if m := urlAutolink.FindStringSubmatch(s); m != nil {
+		href, text, n = m[1], m[1], len(m[0])
+	} else if m := emailAutolink.FindStringSubmatch(s); m != nil {
+		href, text, n = "mailto:"+m[1], m[1], len(m[0])
+	} else {
+		return "", 0, false
+	}
+
+	if inLink {
+		return html.EscapeString(text), n, true
+	}
+	escaped, scheme, ok := safeURL(href)
+	if !ok {
+		return html.EscapeString(text), n, true
+	}
+	out := `<a href="` + escaped + `"`
+	if externalSchemes[scheme] {
+		out += ` rel="nofollow noopener noreferrer"`
+	}
+	return out + ">" + html.EscapeString(text) + "</a>", n, true
+}
+
+// unescape снимает экранирование '\' и HTML-сущности в адресах и
+// заголовках ссылок.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,508 @@
+package markup
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDepth ограничивает вложенность цитат и списков; глубже они
+// отрисовываются как обычный текст.
+const maxDepth = 16
+
+// renderer разбирает блочную структуру Markdown: заголовки, абзацы, цитаты,
+// списки, блоки кода и горизонтальные линии.
+type renderer struct {
+	out *strings.Builder
+	toc []Heading
+	ids map[string]int
+}
+
+func newRenderer() *renderer {
+	return &renderer{out: &strings.Builder{}, ids: make(map[string]int)}
+}
+
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\t", "    ")
+	return strings.Split(strings.TrimRight(source, "\n"), "\n")
+}
+
+// blocks отрисовывает строки как последовательность блоков. В tight-режиме
+// (элементы плотного списка) абзацы не оборачиваются в <p>.
+func (r *renderer) blocks(lines []string, tight bool, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case indent(line) >= 4:
+			i = r.indentedCode(lines, i)
+		default:
+			if f, ok := openFence(line); ok {
+				i = r.fencedCode(lines, i, f)
+				continue
+			}
+			if level, text, ok := atxHeading(line); ok {
+				r.heading(level, text)
+				i++
+				continue
+			}
+			if isThematicBreak(line) {
+				r.out.WriteString("<hr />\n")
+				i++
+				continue
+			}
+			if depth < maxDepth {
+				if isQuote(line) {
+					i = r.blockquote(lines, i, depth)
+					continue
+				}
+				if m, ok := listMarker(line); ok {
+					i = r.list(lines, i, m, depth)
+					continue
+				}
+			}
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+func (r *renderer) paragraph(lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(text) > 0 {
+			if level, ok := setextUnderline(line); ok {
+				r.heading(level, strings.Join(text, "\n"))
+				return i + 1
+			}
+			if interruptsParagraph(line) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+
+	content := inline(strings.TrimRight(strings.Join(text, "\n"), " "), false)
+	if tight {
+		r.out.WriteString(content + "\n")
+	} else {
+		r.out.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+func (r *renderer) heading(level int, text string) {
+	content := inline(strings.TrimSpace(text), false)
+	title := strings.Join(strings.Fields(plainText(content)), " ")
+	id := r.headingID(title)
+	r.toc = append(r.toc, Heading{Level: level, Text: title, ID: id})
+	fmt.Fprintf(r.out, "<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(id), content, level)
+}
+
+// headingID строит уникальный в документе якорь из текста заголовка.
+func (r *renderer) headingID(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(c)
+		case unicode.IsSpace(c) || c == '-' || c == '_':
+			dash = true
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+
+	n := r.ids[id]
+	r.ids[id] = n + 1
+	if n > 0 {
+		id = id + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+func (r *renderer) indentedCode(lines []string, i int) int {
+	var code []string
+	for ; i < len(lines) && (isBlank(lines[i]) || indent(lines[i]) >= 4); i++ {
+		if isBlank(lines[i]) {
+			code = append(code, "")
+		} else {
+			code = append(code, lines[i][4:])
+		}
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+	r.code(code, "")
+	return i
+}
+
+type fence struct {
+	char   byte
+	length int
+	indent int
+	lang   string
+}
+
+func openFence(line string) (fence, bool) {
+	ind := indent(line)
+	if ind > 3 {
+		return fence{}, false
+	}
+	s := line[ind:]
+	if len(s) < 3 || (s[0] != '`' && s[0] != '~') {
+		return fence{}, false
+	}
+	n := run(s, 0, s[0])
+	if n < 3 {
+		return fence{}, false
+	}
+	info := strings.TrimSpace(s[n:])
+	if s[0] == '`' && strings.Contains(info, "`") {
+		return fence{}, false
+	}
+
+	f := fence{char: s[0], length: n, indent: ind}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		f.lang = codeLanguage(fields[0])
+	}
+	return f, true
+}
+
+func (r *renderer) fencedCode(lines []string, i int, f fence) int {
+	var code []string
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if ind := indent(line); ind <= 3 {
+			s := line[ind:]
+			if n := run(s, 0, f.char); n >= f.length && isBlank(s[n:]) {
+				i++
+				break
+			}
+		}
+		strip := indent(line)
+		if strip > f.indent {
+			strip = f.indent
+		}
+		code = append(code, line[strip:])
+	}
+	r.code(code, f.lang)
+	return i
+}
+
+func (r *renderer) code(lines []string, lang string) {
+	if lang != "" {
+		r.out.WriteString(`<pre><code class="language-` + lang + `">`)
+	} else {
+		r.out.WriteString("<pre><code>")
+	}
+	for _, line := range lines {
+		r.out.WriteString(html.EscapeString(line))
+		r.out.WriteByte('\n')
+	}
+	r.out.WriteString("</code></pre>\n")
+}
+
+// codeLanguage оставляет в названии языка только безопасные символы.
+func codeLanguage(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c < utf8.RuneSelf && (isAlnum(byte(c)) || strings.ContainsRune("+#._-", c)) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func isQuote(line string) bool {
+	ind := indent(line)
+	return ind <= 3 && ind < len(line) && line[ind] == '>'
+}
+
+func (r *renderer) blockquote(lines []string, i int, depth int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isQuote(line) {
+			s := line[indent(line)+1:]
+			s = strings.TrimPrefix(s, " ")
+			inner = append(inner, s)
+			continue
+		}
+		// Ленивое продолжение абзаца цитаты без '>'.
+		if !isBlank(line) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !interruptsParagraph(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false, depth+1)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+// marker - маркер элемента списка. width - отступ содержимого элемента от
+// начала строки.
+type marker struct {
+	ordered bool
+	char    byte
+	start   int
+	width   int
+	empty   bool
+}
+
+func listMarker(line string) (marker, bool) {
+	ind := indent(line)
+	if ind > 3 || ind == len(line) {
+		return marker{}, false
+	}
+	s := line[ind:]
+
+	var m marker
+	j := 0
+	switch s[0] {
+	case '-', '*', '+':
+		m.char = s[0]
+		j = 1
+	default:
+		for j < len(s) && j < 9 && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j == 0 || j == len(s) || (s[j] != '.' && s[j] != ')') {
+			return marker{}, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(s[:j])
+		m.char = s[j]
+		j++
+	}
+
+	rest := s[j:]
+	if isBlank(rest) {
+		m.empty = true
+		m.width = ind + j + 1
+		return m, true
+	}
+	if rest[0] != ' ' {
+		return marker{}, false
+	}
+	spaces := indent(rest)
+	if spaces > 4 {
+		spaces = 1
+	}
+	m.width = ind + j + spaces
+	return m, true
+}
+
+func (r *renderer) list(lines []string, i int, first marker, depth int) int {
+	var items [][]string
+	loose := false
+
+	width := first.width
+	items = append(items, []string{itemContent(lines[i], first)})
+	for i++; i < len(lines); {
+		line := lines[i]
+		current := len(items) - 1
+
+		if isBlank(line) {
+			j := i + 1
+			for j < len(lines) && isBlank(lines[j]) {
+				j++
+			}
+			if j == len(lines) {
+				i = j
+				break
+			}
+			if indent(lines[j]) >= width {
+				for ; i < j; i++ {
+					items[current] = append(items[current], "")
+				}
+				continue
+			}
+			if m, ok := listMarker(lines[j]); ok && sameList(first, m) && !isThematicBreak(lines[j]) {
+				loose = true
+				i = j
+				continue
+			}
+			break
+		}
+
+		if indent(line) >= width {
+			items[current] = append(items[current], line[width:])
+			i++
+			continue
+		}
+		if m, ok := listMarker(line); ok && sameList(first, m) && !isThematicBreak(line) {
+			items = append(items, []string{itemContent(line, m)})
+			width = m.width
+			i++
+			continue
+		}
+		item := items[current]
+		if !isBlank(item[len(item)-1]) && !interruptsParagraph(line) {
+			items[current] = append(item, strings.TrimLeft(line, " "))
+			i++
+			continue
+		}
+		break
+	}
+
+	for k, item := range items {
+		for len(item) > 1 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+		}
+		items[k] = item
+		for _, line := range item[1:] {
+			if line == "" {
+				loose = true
+			}
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(r.out, "<ol start=\"%d\">\n", first.start)
+	} else {
+		r.out.WriteString("<" + tag + ">\n")
+	}
+
+	parent := r.out
+	for _, item := range items {
+		r.out = &strings.Builder{}
+		r.blocks(item, !loose, depth+1)
+		content := r.out.String()
+		r.out = parent
+
+		if loose {
+			r.out.WriteString("<li>\n" + content + "</li>\n")
+		} else {
+			r.out.WriteString("<li>" + strings.TrimSuffix(content, "\n") + "</li>\n")
+		}
+	}
+	r.out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func itemContent(line string, m marker) string {
+	if m.empty {
+		return ""
+	}
+	return line[m.width:]
+}
+
+func sameList(a, b marker) bool {
+	return a.ordered == b.ordered && a.char == b.char
+}
+
+// interruptsParagraph сообщает, что строка начинает новый блок, а не
+// продолжает абзац. Нумерованный список прерывает абзац, только если
+// начинается с 1.
+func interruptsParagraph(line string) bool {
+	if _, ok := openFence(line); ok {
+		return true
+	}
+	if _, _, ok := atxHeading(line); ok {
+		return true
+	}
+	if isThematicBreak(line) || isQuote(line) {
+		return true
+	}
+	if m, ok := listMarker(line); ok && !m.empty && (!m.ordered || m.start == 1) {
+		return true
+	}
+	return false
+}
+
+func atxHeading(line string) (int, string, bool) {
+	ind := indent(line)
+	if ind > 3 {
+		return 0, "", false
+	}
+	s := line[ind:]
+	level := run(s, 0, '#')
+	if level == 0 || level > 6 || (level < len(s) && s[level] != ' ') {
+		return 0, "", false
+	}
+
+	text := strings.TrimSpace(s[level:])
+	if closing := strings.TrimRight(text, "#"); closing == "" {
+		text = ""
+	} else if strings.HasSuffix(closing, " ") {
+		text = strings.TrimRight(closing, " ")
+	}
+	return level, text, true
+}
+
+func setextUnderline(line string) (int, bool) {
+	ind := indent(line)
+	if ind > 3 {
+		return 0, false
+	}
+	s := strings.TrimRight(line[ind:], " ")
+	if s == "" {
+		return 0, false
+	}
+	if n := run(s, 0, '='); n == len(s) {
+		return 1, true
+	}
+	if n := run(s, 0, '-'); n == len(s) {
+		return 2, true
+	}
+	return 0, false
+}
+
+func isThematicBreak(line string) bool {
+	ind := indent(line)
+	if ind > 3 {
+		return false
+	}
+	var char byte
+	count := 0
+	for i := ind; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == ' ':
+		case (c == '-' || c == '*' || c == '_') && (char == 0 || char == c):
+			char = c
+			count++
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+func isBlank(line string) bool {
+	return strings.TrimLeft(line, " ") == ""
+}
+
+func indent(line string) int {
+	return run(line, 0, ' ')
+}
+
+// run - длина серии символов c, начиная с позиции i.
+func run(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
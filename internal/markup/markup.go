@@ -0,0 +1,115 @@
+// Package markup отрисовывает текст статьи в безопасный HTML.
+//
+// HTML строится только из разрешенных тегов: p, br, h1-h6, strong, em, del,
+// code, pre, blockquote, ul, ol, li, hr, a (href, title, rel) и img (src,
+// alt, title). HTML в исходном тексте не пропускается, а экранируется, ссылки
+// допускаются только относительные и со схемами http, https и mailto.
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Version - версия правил отрисовки. Ее увеличивают при изменении правил,
+// чтобы сохраненный HTML статей был перестроен.
+const Version = 1
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// excerptMax - длина анонса в символах.
+const excerptMax = 200
+
+// Heading - заголовок документа для оглавления; ID совпадает с атрибутом id
+// заголовка в HTML.
+type Heading struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+type Document struct {
+	HTML    string
+	Excerpt string
+	TOC     []Heading
+}
+
+// Render отрисовывает source в формате format; неизвестный формат
+// считается простым текстом.
+func Render(format, source string) Document {
+	source = normalizeNewlines(source)
+
+	var doc Document
+	if format == FormatMarkdown {
+		r := newRenderer()
+		r.blocks(splitLines(source), false, 0)
+		doc.HTML = r.out.String()
+		doc.TOC = r.toc
+	} else {
+		doc.HTML = renderPlain(source)
+	}
+	doc.Excerpt = excerpt(doc.HTML)
+	return doc
+}
+
+// renderPlain - абзацы разделяются пустой строкой, переносы внутри абзаца
+// сохраняются.
+func renderPlain(source string) string {
+	var b strings.Builder
+	for _, paragraph := range paragraphSeparator.Split(source, -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br />\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+var (
+	paragraphSeparator = regexp.MustCompile(`\n[ \t]*\n`)
+	tagPattern         = regexp.MustCompile(`<[^>]*>`)
+	blockBoundary      = regexp.MustCompile(`</?(?:p|li|ul|ol|blockquote|pre|h[1-6])(?: [^>]*)?>|<br />|<hr />`)
+	// excerptSkipped - код и заголовки в анонс не попадают.
+	excerptSkipped = regexp.MustCompile(`(?s)<pre>.*?</pre>|<h[1-6][^>]*>.*?</h[1-6]>`)
+)
+
+// plainText извлекает текст из HTML, построенного этим пакетом: значения
+// атрибутов экранированы, поэтому '>' внутри тега не встречается.
+func plainText(s string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+}
+
+// textContent - текст HTML с границами блоков, замененными пробелами.
+func textContent(s string) string {
+	return strings.Join(strings.Fields(plainText(blockBoundary.ReplaceAllString(s, " "))), " ")
+}
+
+// excerpt - начало текста без разметки, обрезанное по границе слова.
+func excerpt(rendered string) string {
+	text := textContent(excerptSkipped.ReplaceAllString(rendered, " "))
+	if text == "" {
+		text = textContent(rendered)
+	}
+	if utf8.RuneCountInString(text) <= excerptMax {
+		return text
+	}
+
+	cut := string([]rune(text)[:excerptMax])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
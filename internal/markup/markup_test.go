@@ -0,0 +1,112 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderRejectsUnsafeLinks(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"javascript", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed case", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"hex entity", "[x](jav&#x61;script:alert(1))", "<p>x</p>\n"},
+		{"decimal entity", "[x](&#106;avascript:alert(1))", "<p>x</p>\n"},
+		{"named entity", "[x](javascript&colon;alert(1))", "<p>x</p>\n"},
+		{"backslash escape", "[x](java\\script:alert(1))", "<p>x</p>\n"},
+		{"angle brackets", "[x](<javascript:alert(1)>)", "<p>x</p>\n"},
+		{"leading space", "[x](<  javascript:alert(1)>)", "<p>x</p>\n"},
+		{"control character", "[x](<java\x01script:alert(1)>)", "<p>x</p>\n"},
+		{"data image", "![y](data:image/png;base64,AAAA)", "<p>y</p>\n"},
+		{"vbscript image", "![y](vbscript:msgbox)", "<p>y</p>\n"},
+		{"mailto image", "![y](mailto:a@example.com)", "<p>y</p>\n"},
+		{"autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{"attribute injection", `[x](http://e.com" onclick="alert(1))`, "<p>[x](http://e.com&#34; onclick=&#34;alert(1))</p>\n"},
+		{"title injection", `[x](/a "<b>")`, `<p><a href="/a" title="&lt;b&gt;">x</a></p>` + "\n"},
+		{"relative", "[x](/a?b=1&c=2)", `<p><a href="/a?b=1&amp;c=2">x</a></p>` + "\n"},
+		{"external", "[x](https://example.com)", `<p><a href="https://example.com" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"protocol relative", "[x](//example.com)", `<p><a href="//example.com" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"mailto", "[x](mailto:a@example.com)", `<p><a href="mailto:a@example.com">x</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(FormatMarkdown, tt.src).HTML; got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesRawHTML(t *testing.T) {
+	tests := []struct {
+		format, src, want string
+	}{
+		{FormatMarkdown, "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{FormatMarkdown, "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{FormatMarkdown, "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{FormatMarkdown, "```html\n<script>\n```", `<pre><code class="language-html">&lt;script&gt;` + "\n</code></pre>\n"},
+		{FormatMarkdown, "[<i>](/x)", `<p><a href="/x">&lt;i&gt;</a></p>` + "\n"},
+		{FormatMarkdown, "&#60;script&#62; &bogus;", "<p>&lt;script&gt; &amp;bogus;</p>\n"},
+		{FormatPlain, "<b>a</b>\n\nc", "<p>&lt;b&gt;a&lt;/b&gt;</p>\n<p>c</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.format, tt.src).HTML; got != tt.want {
+			t.Errorf("Render(%s, %q) = %q, want %q", tt.format, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLimitsNesting(t *testing.T) {
+	tests := []struct {
+		src, tag string
+	}{
+		{strings.Repeat("> ", 3*maxDepth) + "deep", "<blockquote>"},
+		{strings.Repeat("- ", 3*maxDepth) + "deep", "<ul>"},
+	}
+	for _, tt := range tests {
+		if got := strings.Count(Render(FormatMarkdown, tt.src).HTML, tt.tag); got != maxDepth {
+			t.Errorf("%s nesting = %d, want %d", tt.tag, got, maxDepth)
+		}
+	}
+}
+
+func TestClosingBracketStaysInLabelWindow(t *testing.T) {
+	if got := closingBracket("[`a]`]"); got != 5 {
+		t.Errorf("code span in label: got %d, want 5", got)
+	}
+	// Пара для '`' за пределами окна не ищется, а ']' за ним не учитывается.
+	if got := closingBracket("[`" + strings.Repeat("a", 2*maxLinkLabel) + "`]"); got != -1 {
+		t.Errorf("label longer than maxLinkLabel: got %d, want -1", got)
+	}
+}
+
+// Строки близки к предельной длине статьи: квадратичный разбор занимает на
+// них больше 10 секунд, ограниченный - доли секунды.
+func TestRenderPathologicalInputs(t *testing.T) {
+	inputs := map[string]string{
+		"brackets":            strings.Repeat("[", 60000),
+		"brackets and ticks":  strings.Repeat("[`", 30000),
+		"ticks after labels":  strings.Repeat("[", 1000) + "``" + strings.Repeat("a`", 90000),
+		"open destinations":   strings.Repeat("[a](", 45000),
+		"open images":         strings.Repeat("![a](", 36000),
+		"open titles":         strings.Repeat(`[a](b "`, 10000),
+		"delimiters":          strings.Repeat("*a", 30000),
+		"unclosed autolinks":  strings.Repeat("<a", 30000),
+		"nested blockquotes":  strings.Repeat("> ", 30000),
+		"unclosed code fence": strings.Repeat("```\n", 15000),
+	}
+	for name, src := range inputs {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			doc := Render(FormatMarkdown, src)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Render took %s", elapsed)
+			}
+			if strings.Contains(doc.HTML, "<a ") || strings.Contains(doc.HTML, "<img ") {
+				t.Errorf("unexpected link in %.80q", doc.HTML)
+			}
+		})
+	}
+}
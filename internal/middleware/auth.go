@@ -2,9 +2,11 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"goida/internal/models"
 	"goida/internal/services"
 )
 
@@ -13,12 +15,18 @@ type contextKey string
 const UserContextKey contextKey = "user"
 
 type AuthMiddleware struct {
-	authService *services.AuthService
+	authService   *services.AuthService
+	tokenService  services.PersonalAccessTokenService
+	authorizer    services.Authorizer
+	impersonation services.ImpersonationService
 }
 
-func NewAuthMiddleware(authService *services.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(authService *services.AuthService, tokenService services.PersonalAccessTokenService, authorizer services.Authorizer, impersonation services.ImpersonationService) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:   authService,
+		tokenService:  tokenService,
+		authorizer:    authorizer,
+		impersonation: impersonation,
 	}
 }
 
@@ -38,17 +46,50 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
+		if strings.HasPrefix(token, models.PersonalAccessTokenPrefix) {
+			claims, err := m.tokenService.Authenticate(r.Context(), token, ClientIP(r))
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		claims, err := m.authService.ValidateToken(token)
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
+		if err := m.authService.ValidateSession(r.Context(), claims, ClientIP(r)); err != nil {
+			if errors.Is(err, services.ErrAccountDisabled) {
+				http.Error(w, "Account is disabled", http.StatusForbidden)
+				return
+			}
+			http.Error(w, "Session revoked", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.IsImpersonated() {
+			m.impersonation.RecordRequest(r.Context(), claims, r.Method, r.URL.Path, ClientIP(r))
+			// Удалять что-либо от имени пользователя нельзя.
+			if r.Method == http.MethodDelete {
+				http.Error(w, "Not allowed while impersonating", http.StatusForbidden)
+				return
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), UserContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireAdmin пропускает в административный раздел роли, у которых есть
+// хотя бы одно административное право, с учетом политики 2FA. Доступ к
+// конкретным маршрутам проверяет RequirePermission.
 func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	return m.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := GetUserFromContext(r.Context())
@@ -57,15 +98,126 @@ func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 			return
 		}
 
-		if claims.Role != "admin" {
+		if claims.IsPersonalToken() {
+			http.Error(w, "Personal access tokens cannot be used for administration", http.StatusForbidden)
+			return
+		}
+		if claims.IsImpersonated() {
+			http.Error(w, "Not allowed while impersonating", http.StatusForbidden)
+			return
+		}
+
+		privileged, err := m.authorizer.IsPrivileged(r.Context(), claims.Role)
+		if err != nil {
+			http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+		if !privileged {
 			http.Error(w, "Admin access required", http.StatusForbidden)
 			return
 		}
 
+		required, err := m.authService.TwoFactorRequiredForRole(r.Context(), claims.Role)
+		if err != nil {
+			http.Error(w, "Failed to check two-factor policy", http.StatusInternalServerError)
+			return
+		}
+		if required && !claims.MFA {
+			http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	}))
 }
 
+// RequireSession пропускает только запросы с сессией, открытой по паролю:
+// управлять учетной записью, сессиями и самими токенами персональным
+// токеном нельзя. При имперсонации разрешено только чтение. Используется
+// после RequireAuth.
+func (m *AuthMiddleware) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "User not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		if claims.IsPersonalToken() {
+			http.Error(w, "Personal access tokens cannot be used for this endpoint", http.StatusForbidden)
+			return
+		}
+		if claims.IsImpersonated() && r.Method != http.MethodGet {
+			http.Error(w, "Not allowed while impersonating", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DenyImpersonation закрывает маршрут для токенов имперсонации. Используется
+// после RequireAuth.
+func (m *AuthMiddleware) DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "User not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		if claims.IsImpersonated() {
+			http.Error(w, "Not allowed while impersonating", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope проверяет, что персональный токен выдан с областью scope.
+// Запросы с сессией проходят всегда. Используется после RequireAuth.
+func (m *AuthMiddleware) RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "User not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		if !claims.HasScope(scope) {
+			http.Error(w, "Token scope "+scope+" required", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// RequirePermission проверяет, что роль пользователя имеет право permission.
+// Используется после RequireAuth.
+func (m *AuthMiddleware) RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "User not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		allowed, err := m.authorizer.Can(r.Context(), claims.Role, permission)
+		if err != nil {
+			http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Permission "+permission+" required", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -74,7 +226,10 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token := parts[1]
 				claims, err := m.authService.ValidateToken(token)
-				if err == nil {
+				if err == nil && m.authService.ValidateSession(r.Context(), claims, ClientIP(r)) == nil {
+					if claims.IsImpersonated() {
+						m.impersonation.RecordRequest(r.Context(), claims, r.Method, r.URL.Path, ClientIP(r))
+					}
 					ctx := context.WithValue(r.Context(), UserContextKey, claims)
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP возвращает адрес клиента без порта.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -2,24 +2,142 @@ package models
 
 import "time"
 
+// Состояния статьи. Читателям видны только опубликованные статьи и
+// запланированные, время публикации которых уже наступило.
+const (
+	ArticleDraft     = "draft"
+	ArticleScheduled = "scheduled"
+	ArticlePublished = "published"
+	ArticleArchived  = "archived"
+)
+
 type Article struct {
-	ID          int       `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Content     string    `json:"content" db:"content"`
-	AuthorID    int       `json:"author_id" db:"author_id"`
-	AuthorName  string    `json:"author_name" db:"author_name"`
-	RatingAvg   float64   `json:"rating_avg" db:"-"`
-	RatingCount int       `json:"rating_count" db:"-"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID            int              `json:"id" db:"id"`
+	Title         string           `json:"title" db:"title"`
+	Slug          string           `json:"slug" db:"slug"`
+	Content       string           `json:"content" db:"content"`
+	ContentFormat string           `json:"content_format" db:"content_format"`
+	ContentHTML   string           `json:"content_html" db:"content_html"`
+	Excerpt       string           `json:"excerpt" db:"excerpt"`
+	TOC           []TOCEntry       `json:"toc" db:"toc"`
+	RenderVersion int              `json:"-" db:"render_version"`
+	AuthorID      int              `json:"author_id" db:"author_id"`
+	AuthorName    string           `json:"author_name" db:"author_name"`
+	AuthorDeleted bool             `json:"author_deleted,omitempty" db:"-"`
+	Status        string           `json:"status" db:"status"`
+	PublishAt     *time.Time       `json:"publish_at,omitempty" db:"publish_at"`
+	CategoryID    *int             `json:"-" db:"category_id"`
+	Category      *ArticleCategory `json:"category,omitempty" db:"-"`
+	Tags          []string         `json:"tags" db:"-"`
+	RatingAvg     float64          `json:"rating_avg" db:"-"`
+	RatingCount   int              `json:"rating_count" db:"-"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// Форматы текста статьи.
+const (
+	ContentPlain    = "plain"
+	ContentMarkdown = "markdown"
+)
+
+// TOCEntry - заголовок статьи в оглавлении; ID - якорь заголовка в
+// content_html.
+type TOCEntry struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
 }
 
+// IsPublished учитывает запланированные статьи, которые планировщик еще не
+// успел перевести в published.
+func (a *Article) IsPublished(now time.Time) bool {
+	switch a.Status {
+	case ArticlePublished:
+		return true
+	case ArticleScheduled:
+		return a.PublishAt != nil && !now.Before(*a.PublishAt)
+	default:
+		return false
+	}
+}
+
+// CreateArticleRequest - без status статья публикуется сразу. Для
+// scheduled обязателен publish_at в будущем. Теги нормализуются, category -
+// slug существующей категории. ContentFormat по умолчанию plain.
 type CreateArticleRequest struct {
-	Title   string `json:"title" validate:"required,min=3"`
-	Content string `json:"content" validate:"required,min=10"`
+	Title         string     `json:"title" validate:"required,min=3"`
+	Content       string     `json:"content" validate:"required,min=10,max=200000"`
+	ContentFormat string     `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	Status        string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt     *time.Time `json:"publish_at"`
+	Tags          []string   `json:"tags" validate:"max=10,dive,min=1,max=50"`
+	Category      string     `json:"category" validate:"max=64"`
 }
 
+// UpdateArticleRequest - Tags и Category меняются, только если переданы;
+// пустой список или "" их снимают.
 type UpdateArticleRequest struct {
-	Title   string `json:"title" validate:"omitempty,min=3"`
-	Content string `json:"content" validate:"omitempty,min=10"`
+	Title         string     `json:"title" validate:"omitempty,min=3"`
+	Content       string     `json:"content" validate:"omitempty,min=10,max=200000"`
+	ContentFormat string     `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	Status        string     `json:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt     *time.Time `json:"publish_at"`
+	Tags          *[]string  `json:"tags" validate:"omitempty,max=10,dive,min=1,max=50"`
+	Category      *string    `json:"category" validate:"omitempty,max=64"`
+}
+
+// ArticleSearchRequest - параметры GET /api/articles/search. Lang: ru, en
+// или пусто - обе морфологии. To - граница не включительно.
+type ArticleSearchRequest struct {
+	Q        string `validate:"required,max=200"`
+	Lang     string `validate:"omitempty,oneof=ru en"`
+	AuthorID int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// ArticleSearchResult - Snippet - фрагменты текста в HTML: текст
+// экранирован, совпадения обернуты в <mark>.
+type ArticleSearchResult struct {
+	Article
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// ArticleRevision - неизменяемая версия статьи. В списке версий Content не
+// заполняется.
+type ArticleRevision struct {
+	ID            int64     `json:"id" db:"id"`
+	ArticleID     int       `json:"article_id" db:"article_id"`
+	Revision      int       `json:"revision" db:"revision"`
+	Title         string    `json:"title" db:"title"`
+	Content       string    `json:"content,omitempty" db:"content"`
+	ContentFormat string    `json:"content_format" db:"content_format"`
+	EditorID      *int      `json:"editor_id,omitempty" db:"editor_id"`
+	EditorName    string    `json:"editor_name,omitempty" db:"-"`
+	RestoredFrom  *int      `json:"restored_from,omitempty" db:"restored_from"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// DiffLine - строка построчного сравнения версий; op: equal, insert или
+// delete.
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+type ArticleDiff struct {
+	ArticleID    int        `json:"article_id"`
+	From         int        `json:"from"`
+	To           int        `json:"to"`
+	TitleFrom    string     `json:"title_from"`
+	TitleTo      string     `json:"title_to"`
+	TitleChanged bool       `json:"title_changed"`
+	Changed      bool       `json:"changed"`
+	Lines        []DiffLine `json:"lines"`
 }
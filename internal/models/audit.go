@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	AuditPasswordChanged          = "credentials.password_changed"
+	AuditLoginChanged             = "credentials.login_changed"
+	AuditCredentialsReset         = "credentials.admin_reset"
+	AuditPasswordResetEmail       = "credentials.password_reset"
+	AuditTokenCreated             = "tokens.created"
+	AuditTokenRevoked             = "tokens.revoked"
+	AuditRoleChanged              = "users.role_changed"
+	AuditEmailVerificationChanged = "users.email_verification_changed"
+	AuditUserUpdated              = "users.updated"
+	AuditUserDeleted              = "users.deleted"
+	AuditUserRestored             = "users.restored"
+	AuditUserSuspended            = "users.suspended"
+	AuditUserUnsuspended          = "users.unsuspended"
+	AuditUserDeactivated          = "users.deactivated"
+	AuditUserReactivated          = "users.reactivated"
+	AuditDeletionScheduled        = "users.deletion_scheduled"
+	AuditUserPurged               = "users.purged"
+	AuditDataExported             = "users.data_exported"
+	AuditImpersonationStart       = "impersonation.started"
+	AuditImpersonatedCall         = "impersonation.request"
+)
+
+type AuditEvent struct {
+	ID        int64                  `json:"id" db:"id"`
+	UserID    *int                   `json:"user_id,omitempty" db:"user_id"`
+	ActorID   *int                   `json:"actor_id,omitempty" db:"actor_id"`
+	Action    string                 `json:"action" db:"action"`
+	Details   map[string]interface{} `json:"details,omitempty" db:"details"`
+	IP        string                 `json:"ip,omitempty" db:"ip"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
@@ -1,11 +1,40 @@
 package models
 
+import "time"
+
 type AuthRequest struct {
 	Login    string `json:"login" validate:"required,min=3"`
 	Password string `json:"password" validate:"required"`
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         User      `json:"user"`
+}
+
+// ImpersonateRequest - причина обязательна и попадает в журнал.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ImpersonationResponse - токен имперсонации не продлевается: refresh-токен
+// не выдается.
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// Impersonation - отметка в профиле о том, что запрос выполнен сотрудником
+// от имени пользователя.
+type Impersonation struct {
+	ActorID   int       `json:"actor_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ProfileResponse struct {
+	User
+	Impersonation *Impersonation `json:"impersonation,omitempty"`
 }
@@ -6,18 +6,22 @@ type AuthCredentials struct {
 	ID        int       `json:"id" db:"id"`
 	UserID    int       `json:"user_id" db:"user_id"`
 	Login     string    `json:"login" db:"login"`
-	Password  string    `json:"password" db:"password"`
+	Password  string    `json:"-" db:"password"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type CreateAuthCredentialsRequest struct {
-	UserID   int    `json:"user_id" validate:"required"`
-	Login    string `json:"login" validate:"required,min=3"`
-	Password string `json:"password" validate:"required"`
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ChangeLoginRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Login           string `json:"login" validate:"required,min=3"`
 }
 
 type UpdateAuthCredentialsRequest struct {
 	Login    string `json:"login" validate:"omitempty,min=3"`
-	Password string `json:"password" validate:"omitempty"`
+	Password string `json:"password"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type Category struct {
+	ID           int       `json:"id" db:"id"`
+	Slug         string    `json:"slug" db:"slug"`
+	Name         string    `json:"name" db:"name"`
+	Description  string    `json:"description" db:"description"`
+	ArticleCount int       `json:"article_count" db:"-"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ArticleCategory - категория в составе статьи.
+type ArticleCategory struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// CreateCategoryRequest - без slug он строится из name.
+type CreateCategoryRequest struct {
+	Slug        string `json:"slug" validate:"omitempty,max=64"`
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+type UpdateCategoryRequest struct {
+	Slug        *string `json:"slug" validate:"omitempty,min=1,max=64"`
+	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=255"`
+}
+
+// TagUsage - тег и число опубликованных статей с ним.
+type TagUsage struct {
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+// ArticleFilter - фильтр списка статей: статьи со всеми тегами Tags и в
+// категории Category (slug). Пустые значения не фильтруют.
+type ArticleFilter struct {
+	Tags     []string
+	Category string
+}
@@ -3,13 +3,14 @@ package models
 import "time"
 
 type Comment struct {
-	ID        int64     `json:"id" db:"id"`
-	ArticleID int       `json:"article_id" db:"article_id"`
-	UserID    int       `json:"user_id" db:"user_id"`
-	Text      string    `json:"text" db:"text"`
-	Rating    int       `json:"rating" db:"rating"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          int64     `json:"id" db:"id"`
+	ArticleID   int       `json:"article_id" db:"article_id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	UserDeleted bool      `json:"user_deleted,omitempty" db:"-"`
+	Text        string    `json:"text" db:"text"`
+	Rating      int       `json:"rating" db:"rating"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateCommentRequest struct {
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Статусы выгрузки персональных данных.
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+type DataExport struct {
+	ID          int64      `json:"id" db:"id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	RequestedBy *int       `json:"requested_by,omitempty" db:"requested_by"`
+	Status      string     `json:"status" db:"status"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	Size        int64      `json:"size,omitempty" db:"size"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	// StatusURL и DownloadURL заполняет обработчик: адреса зависят от того,
+	// запросил выгрузку сам пользователь или администратор.
+	StatusURL   string `json:"status_url,omitempty" db:"-"`
+	DownloadURL string `json:"download_url,omitempty" db:"-"`
+}
+
+// IsExpired сообщает, что срок хранения выгрузки истек в момент now.
+func (e *DataExport) IsExpired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
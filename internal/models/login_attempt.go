@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type LoginAttempt struct {
+	Key           string    `json:"key" db:"key"`
+	Failures      int       `json:"failures" db:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until" db:"locked_until"`
+}
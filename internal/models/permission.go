@@ -0,0 +1,27 @@
+package models
+
+// Права доступа. Роли получают права через таблицу role_permissions, поэтому
+// новые роли (модератор, редактор) заводятся без изменения кода.
+const (
+	PermArticleUpdateAny = "article.update.any"
+	PermArticleDeleteAny = "article.delete.any"
+	PermCommentDeleteAny = "comment.delete.any"
+	PermUserManage       = "user.manage"
+	PermRoleManage       = "role.manage"
+	PermAuditRead        = "audit.read"
+	PermSecurityManage   = "security.manage"
+	PermUserImpersonate  = "user.impersonate"
+	PermCategoryManage   = "category.manage"
+)
+
+// IsAdministrativePermission сообщает, что право открывает доступ к
+// /api/admin. На роли с такими правами распространяется политика
+// обязательной двухфакторной аутентификации.
+func IsAdministrativePermission(permission string) bool {
+	switch permission {
+	case PermUserManage, PermRoleManage, PermAuditRead, PermSecurityManage, PermUserImpersonate, PermCategoryManage:
+		return true
+	default:
+		return false
+	}
+}
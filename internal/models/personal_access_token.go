@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// PersonalAccessTokenPrefix отличает персональные токены от JWT в заголовке
+// Authorization и упрощает поиск утекших токенов сканерами секретов.
+const PersonalAccessTokenPrefix = "goida_pat_"
+
+// Области доступа персональных токенов. Сессии, открытые по паролю, имеют
+// все области; управление учетной записью токенам недоступно.
+const (
+	ScopeArticlesWrite = "articles:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeUsersRead     = "users:read"
+)
+
+type PersonalAccessToken struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Hint       string     `json:"hint" db:"token_hint"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=articles:write comments:write users:read"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// CreatePersonalAccessTokenResponse - открытое значение токена возвращается
+// только один раз, при создании.
+type CreatePersonalAccessTokenResponse struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
@@ -6,6 +6,7 @@ type Role struct {
 	ID          int       `json:"id" db:"id"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
+	Permissions []string  `json:"permissions,omitempty" db:"-"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -14,3 +15,27 @@ const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
 )
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"dive,oneof=article.update.any article.delete.any comment.delete.any user.manage role.manage audit.read security.manage user.impersonate category.manage"`
+}
+
+// UpdateRoleRequest - изменяются только переданные поля. Permissions
+// заменяет список прав целиком.
+type UpdateRoleRequest struct {
+	Name        *string   `json:"name" validate:"omitempty,min=2,max=50"`
+	Description *string   `json:"description" validate:"omitempty,max=255"`
+	Permissions *[]string `json:"permissions" validate:"omitempty,dive,oneof=article.update.any article.delete.any comment.delete.any user.manage role.manage audit.read security.manage user.impersonate category.manage"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// IsBuiltinRole - встроенные роли нельзя переименовать или удалить: user
+// назначается при регистрации, admin создается начальными данными.
+func IsBuiltinRole(name string) bool {
+	return name == RoleUser || name == RoleAdmin
+}
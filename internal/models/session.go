@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	MFA        bool       `json:"mfa" db:"mfa"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IP         string     `json:"ip" db:"ip"`
+	Current    bool       `json:"current" db:"-"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// ClientInfo - сведения о клиенте, сохраняемые в сессии при входе.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type RefreshToken struct {
+	ID        int64      `json:"id" db:"id"`
+	SessionID string     `json:"session_id" db:"session_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -3,19 +3,76 @@ package models
 import "time"
 
 type User struct {
-	ID        int       `json:"id" db:"id"`
-	Email     string    `json:"email" db:"email"`
-	Name      string    `json:"name" db:"name"`
-	RoleID    int       `json:"role_id" db:"role_id"`
-	IsDeleted bool      `json:"is_deleted" db:"is_deleted"`
-	Role      *Role     `json:"role,omitempty" db:"role"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID                  int        `json:"id" db:"id"`
+	Email               string     `json:"email" db:"email"`
+	Name                string     `json:"name" db:"name"`
+	RoleID              int        `json:"role_id" db:"role_id"`
+	IsDeleted           bool       `json:"is_deleted" db:"is_deleted"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty" db:"suspended_at"`
+	SuspendedUntil      *time.Time `json:"suspended_until,omitempty" db:"suspended_until"`
+	SuspensionReason    string     `json:"suspension_reason,omitempty" db:"suspension_reason"`
+	DeactivatedAt       *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" db:"deletion_scheduled_at"`
+	EmailVerified       bool       `json:"email_verified" db:"-"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+	Role                *Role      `json:"role,omitempty" db:"role"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DeletedUserName подставляется вместо имени автора удаленного пользователя.
+const DeletedUserName = "Deleted user"
+
+// IsSuspended сообщает, что блокировка действует в момент now.
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil))
+}
+
+// CanSignIn - удаленный или заблокированный пользователь не может войти, а
+// его токены не принимаются.
+func (u *User) CanSignIn(now time.Time) bool {
+	return !u.IsDeleted && !u.IsSuspended(now)
+}
+
+// IsDeactivated - пользователь сам деактивировал учетную запись. Войти он
+// может: вход снова ее активирует.
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
 }
 
 type CreateUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Name     string `json:"name" validate:"required,min=2"`
 	Login    string `json:"login" validate:"required,min=3"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UpdateEmailVerificationRequest struct {
+	Verified bool `json:"verified"`
+}
+
+// AdminUpdateUserRequest - изменяются только переданные поля.
+type AdminUpdateUserRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+// UpdateProfileRequest - изменяются только переданные поля. Новый email нужно
+// подтвердить заново.
+type UpdateProfileRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+// ConfirmPasswordRequest подтверждает деактивацию и удаление учетной записи.
+// Пароль не нужен, если он не задан (вход только через OIDC).
+type ConfirmPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// SuspendUserRequest - без until блокировка бессрочная.
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" validate:"max=500"`
+	Until  *time.Time `json:"until"`
 }
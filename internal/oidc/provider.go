@@ -0,0 +1,329 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrExchangeFailed = errors.New("authorization code exchange failed")
+)
+
+type Config struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+}
+
+// Identity - проверенные сведения о пользователе из ID-токена провайдера.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	// MFA - провайдер сообщил (claim amr), что при входе использовался
+	// второй фактор.
+	MFA bool
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Provider реализует authorization code flow с PKCE для одного провайдера.
+// Метаданные провайдера загружаются при первом входе, поэтому недоступный
+// провайдер не мешает запуску приложения.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *discovery
+	keys     *remoteKeySet
+}
+
+// NewProvider создает провайдера. client может быть nil - тогда используется
+// клиент с таймаутом 10 секунд.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) ID() string {
+	return p.cfg.ID
+}
+
+func (p *Provider) Name() string {
+	if p.cfg.Name != "" {
+		return p.cfg.Name
+	}
+	return p.cfg.ID
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера. verifier - секрет
+// PKCE, который понадобится при обмене кода.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange обменивает код на токены и проверяет ID-токен: подпись, issuer,
+// audience, срок действия и nonce.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	metadata, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: status %s, error %q", ErrExchangeFailed, resp.Status, tokens.Error)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, claims, keys.keyfunc(ctx),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	identity := p.identityFromClaims(claims)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	// Часть провайдеров не кладет email и группы в ID-токен, а отдает их
+	// только через userinfo.
+	if (identity.Email == "" || identity.Groups == nil) && metadata.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		p.mergeUserinfo(ctx, metadata.UserinfoEndpoint, tokens.AccessToken, identity)
+	}
+	return identity, nil
+}
+
+func (p *Provider) identityFromClaims(claims map[string]interface{}) *Identity {
+	identity := &Identity{
+		Subject:       claimString(claims, "sub"),
+		Email:         strings.ToLower(claimString(claims, "email")),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Name:          claimString(claims, "name"),
+		Groups:        claimStrings(claims, p.cfg.GroupsClaim),
+	}
+	if identity.Name == "" {
+		identity.Name = claimString(claims, "preferred_username")
+	}
+
+	for _, method := range claimStrings(claims, "amr") {
+		switch method {
+		case "mfa", "otp", "hwk", "swk", "fido", "sms":
+			identity.MFA = true
+		}
+	}
+	return identity
+}
+
+// mergeUserinfo дополняет identity данными userinfo. Ответ относится к тому
+// же пользователю, только если sub совпадает.
+func (p *Provider) mergeUserinfo(ctx context.Context, endpoint, accessToken string, identity *Identity) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	claims := map[string]interface{}{}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&claims) != nil {
+		return
+	}
+	if claimString(claims, "sub") != identity.Subject {
+		return
+	}
+
+	info := p.identityFromClaims(claims)
+	if identity.Email == "" {
+		identity.Email = info.Email
+		identity.EmailVerified = info.EmailVerified
+	}
+	if identity.Groups == nil {
+		identity.Groups = info.Groups
+	}
+	if identity.Name == "" {
+		identity.Name = info.Name
+	}
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, *remoteKeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, p.keys, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	metadata := &discovery{}
+	if err := getJSON(ctx, p.client, issuer+"/.well-known/openid-configuration", metadata); err != nil {
+		return nil, nil, fmt.Errorf("failed to discover provider %s: %w", p.cfg.ID, err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, nil, fmt.Errorf("provider %s reports issuer %q, expected %q", p.cfg.ID, metadata.Issuer, p.cfg.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, nil, fmt.Errorf("provider %s metadata is incomplete", p.cfg.ID)
+	}
+
+	p.metadata = metadata
+	p.keys = newRemoteKeySet(metadata.JWKSURI, p.client)
+	return p.metadata, p.keys, nil
+}
+
+// NewVerifier создает секрет PKCE (RFC 7636).
+func NewVerifier() (string, error) {
+	return RandomString(32)
+}
+
+// CodeChallenge вычисляет code_challenge методом S256.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString возвращает size случайных байт в base64url.
+func RandomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimBool учитывает провайдеров, которые передают email_verified строкой.
+func claimBool(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+// claimStrings читает claim-массив строк; строка считается массивом из одного
+// элемента. Для отсутствующего claim возвращается nil.
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case []string:
+		return value
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
+}
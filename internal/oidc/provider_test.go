@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"goida/internal/oidc/oidctest"
+)
+
+const testRedirectURL = "http://localhost:8080/api/auth/oidc/corp/callback"
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+	server := oidctest.NewServer("goida", "secret")
+	t.Cleanup(server.Close)
+
+	provider := NewProvider(Config{
+		ID:           "corp",
+		Issuer:       server.URL,
+		ClientID:     "goida",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+	}, server.Client())
+	return provider, server
+}
+
+// login проходит вход у провайдера и возвращает код вместе с параметрами,
+// которые приложение сохранило бы в состоянии.
+func login(t *testing.T, provider *Provider, server *oidctest.Server, user oidctest.User) (code, verifier, nonce string) {
+	t.Helper()
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce = "nonce-1"
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, state, err := server.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+	return code, verifier, nonce
+}
+
+func TestAuthCodeURLUsesDiscoveredEndpoint(t *testing.T) {
+	provider, server := newTestProvider(t)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != server.URL+"/authorize" {
+		t.Errorf("endpoint = %q, want %q", got, server.URL+"/authorize")
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "goida",
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer("goida", "")
+	defer server.Close()
+
+	provider := NewProvider(Config{ID: "corp", Issuer: server.URL + "/other", ClientID: "goida"}, server.Client())
+	if _, err := provider.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("expected discovery error for foreign issuer")
+	}
+}
+
+func TestExchangeReturnsIdentity(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, verifier, nonce := login(t, provider, server, oidctest.User{
+		Subject:       "u-1",
+		Email:         "ivan@example.com",
+		EmailVerified: true,
+		Name:          "Ivan",
+		Groups:        []string{"staff", "goida-admins"},
+		AMR:           []string{"pwd", "otp"},
+	})
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != "u-1" || identity.Email != "ivan@example.com" || !identity.EmailVerified || identity.Name != "Ivan" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[1] != "goida-admins" {
+		t.Errorf("groups = %v", identity.Groups)
+	}
+	if !identity.MFA {
+		t.Error("amr with otp must be reported as MFA")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, _, nonce := login(t, provider, server, oidctest.User{Subject: "u-1"})
+
+	_, err := provider.Exchange(context.Background(), code, "wrong-verifier", nonce)
+	if !errors.Is(err, ErrExchangeFailed) {
+		t.Fatalf("err = %v, want ErrExchangeFailed", err)
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, verifier, nonce := login(t, provider, server, oidctest.User{Subject: "u-1"})
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("first Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, verifier, nonce); !errors.Is(err, ErrExchangeFailed) {
+		t.Fatalf("second Exchange err = %v, want ErrExchangeFailed", err)
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, verifier, nonce := login(t, provider, server, oidctest.User{Subject: "u-1", Nonce: "replayed"})
+
+	_, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestExchangeRejectsForeignAudience(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, verifier, nonce := login(t, provider, server, oidctest.User{Subject: "u-1", Audience: "other-client"})
+
+	_, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestExchangeRefetchesRotatedKeys(t *testing.T) {
+	provider, server := newTestProvider(t)
+
+	code, verifier, nonce := login(t, provider, server, oidctest.User{Subject: "u-1"})
+	if _, err := provider.Exchange(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	// Ключи только что прочитаны, поэтому новый kid примется лишь после
+	// минимального интервала перечитывания.
+	provider.keys.fetchedAt = provider.keys.fetchedAt.Add(-minJWKSRefresh)
+	server.RotateKey()
+
+	code, verifier, nonce = login(t, provider, server, oidctest.User{Subject: "u-1"})
+	if _, err := provider.Exchange(context.Background(), code, verifier, nonce); err != nil {
+		t.Fatalf("Exchange after rotation: %v", err)
+	}
+}
+
+func TestExchangeMergesUserinfo(t *testing.T) {
+	provider, server := newTestProvider(t)
+	code, verifier, nonce := login(t, provider, server, oidctest.User{
+		Subject:       "u-1",
+		Email:         "ivan@example.com",
+		EmailVerified: true,
+		Name:          "Ivan",
+		Groups:        []string{"staff"},
+		UserinfoOnly:  true,
+	})
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Email != "ivan@example.com" || !identity.EmailVerified || identity.Name != "Ivan" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if len(identity.Groups) != 1 || identity.Groups[0] != "staff" {
+		t.Errorf("groups = %v", identity.Groups)
+	}
+}
@@ -0,0 +1,235 @@
+// Package pagination - общие параметры страниц списков, курсоры keyset-
+// пагинации, конверт ответа и заголовок Link (RFC 8288).
+//
+// Списки, отсортированные по убыванию (время, id), можно листать смещением
+// (?offset=) или курсором (?cursor=) из next_cursor/prev_cursor предыдущего
+// ответа; курсор не зависит от вставок и удалений и не требует пропуска строк
+// в базе. Остальные списки (теги, результаты поиска) листаются только
+// смещением.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLimit - наибольший размер страницы; больший limit уменьшается до него.
+const MaxLimit = 100
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor - позиция в списке: ключ крайней записи выданной страницы и
+// направление. Backward - записи новее ключа (предыдущая страница).
+type Cursor struct {
+	Time     time.Time
+	ID       int64
+	Backward bool
+}
+
+type cursorPayload struct {
+	T time.Time `json:"t"`
+	I int64     `json:"i"`
+	B bool      `json:"b,omitempty"`
+}
+
+// String - непрозрачное представление курсора для клиента.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(cursorPayload{T: c.Time, I: c.ID, B: c.Backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.T.IsZero() || payload.I <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Time: payload.T, ID: payload.I, Backward: payload.B}, nil
+}
+
+// Params - запрошенная страница. При заданном Cursor смещение не
+// используется.
+type Params struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// Parse читает limit, offset и cursor из запроса. Некорректные limit и
+// offset заменяются значениями по умолчанию, некорректный курсор - ошибка.
+func Parse(r *http.Request, defaultLimit int) (Params, error) {
+	var params Params
+	params.Limit, params.Offset = Offset(r, defaultLimit)
+
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := ParseCursor(v)
+		if err != nil {
+			return Params{}, err
+		}
+		params.Cursor = cursor
+		params.Offset = 0
+	}
+	return params, nil
+}
+
+// Offset читает limit и offset для списков без курсоров.
+func Offset(r *http.Request, defaultLimit int) (limit, offset int) {
+	query := r.URL.Query()
+	limit = defaultLimit
+	if v := query.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if v := query.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+	return limit, offset
+}
+
+// Keyset возвращает условие и порядок выборки для ключа (timeExpr, idExpr);
+// n - номер первого параметра запроса для args. Выборка должна читать
+// Fetch() строк и пропускать Skip().
+func (p Params) Keyset(timeExpr, idExpr string, n int) (cond, order string, args []interface{}) {
+	key := "(" + timeExpr + ", " + idExpr + ")"
+	switch {
+	case p.Cursor == nil:
+		return "TRUE", timeExpr + " DESC, " + idExpr + " DESC", nil
+	case p.Cursor.Backward:
+		cond = fmt.Sprintf("%s > ($%d, $%d)", key, n, n+1)
+		order = timeExpr + " ASC, " + idExpr + " ASC"
+	default:
+		cond = fmt.Sprintf("%s < ($%d, $%d)", key, n, n+1)
+		order = timeExpr + " DESC, " + idExpr + " DESC"
+	}
+	return cond, order, []interface{}{p.Cursor.Time, p.Cursor.ID}
+}
+
+// Fetch - сколько строк читать: на одну больше страницы, чтобы узнать, есть
+// ли следующая.
+func (p Params) Fetch() int {
+	return p.Limit + 1
+}
+
+func (p Params) Skip() int {
+	if p.Cursor != nil {
+		return 0
+	}
+	return p.Offset
+}
+
+// Page - конверт ответа списка. Total - число записей во всем списке.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+
+	// offset задан для списков без курсоров: ссылки Link строятся смещением.
+	offset *int
+}
+
+// NewPage строит страницу из строк, выбранных по Keyset с Fetch() строками;
+// key возвращает ключ сортировки записи.
+func NewPage[T any](rows []T, params Params, total int, key func(T) (time.Time, int64)) Page[T] {
+	backward := params.Cursor != nil && params.Cursor.Backward
+	more := len(rows) > params.Limit
+	if more {
+		rows = rows[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if rows == nil {
+		rows = []T{}
+	}
+
+	page := Page[T]{Items: rows, Total: total, Limit: params.Limit}
+	if len(rows) == 0 {
+		return page
+	}
+
+	hasNext, hasPrev := more, params.Cursor != nil || params.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		t, id := key(rows[len(rows)-1])
+		page.NextCursor = Cursor{Time: t, ID: id}.String()
+	}
+	if hasPrev {
+		t, id := key(rows[0])
+		page.PrevCursor = Cursor{Time: t, ID: id, Backward: true}.String()
+	}
+	return page
+}
+
+// NewOffsetPage строит страницу списка без курсоров - упорядоченного не по
+// времени и id (теги, результаты поиска): rows - не больше Limit строк со
+// смещения Offset.
+func NewOffsetPage[T any](rows []T, params Params, total int) Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	offset := params.Offset
+	return Page[T]{Items: rows, Total: total, Limit: params.Limit, offset: &offset}
+}
+
+// SlicePage - страница списка, целиком прочитанного в память.
+func SlicePage[T any](items []T, params Params) Page[T] {
+	start := min(params.Offset, len(items))
+	end := min(start+params.Limit, len(items))
+	return NewOffsetPage(items[start:end], params, len(items))
+}
+
+// SetLinkHeader выставляет заголовок Link со ссылками first, prev и next;
+// ссылки повторяют запрос с заменой cursor (или offset для списков без
+// курсоров).
+func (p Page[T]) SetLinkHeader(w http.ResponseWriter, r *http.Request) {
+	link := func(name, value, rel string) string {
+		query := r.URL.Query()
+		query.Del("offset")
+		query.Del("cursor")
+		if value != "" {
+			query.Set(name, value)
+		}
+		query.Set("limit", strconv.Itoa(p.Limit))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	links := []string{link("", "", "first")}
+	if p.offset != nil {
+		offset := *p.offset
+		if offset > 0 {
+			links = append(links, link("offset", strconv.Itoa(max(offset-p.Limit, 0)), "prev"))
+		}
+		if offset+len(p.Items) < p.Total {
+			links = append(links, link("offset", strconv.Itoa(offset+len(p.Items)), "next"))
+		}
+	} else {
+		if p.PrevCursor != "" {
+			links = append(links, link("cursor", p.PrevCursor, "prev"))
+		}
+		if p.NextCursor != "" {
+			links = append(links, link("cursor", p.NextCursor, "next"))
+		}
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
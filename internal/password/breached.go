@@ -0,0 +1,88 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// minHashPrefixLength - более короткие hex-строки считаются обычными словами,
+// иначе префикс вроде "123456" отсекал бы случайные пароли.
+const minHashPrefixLength = 16
+
+// BreachedList - локальный список скомпрометированных и распространенных
+// паролей. Строка файла - либо сам пароль, либо SHA-1 хеш пароля или его
+// префикс (hex, не короче 16 символов, допускается суффикс ":count" как в
+// выгрузках Have I Been Pwned). Пустые строки и строки с # пропускаются.
+type BreachedList struct {
+	words    map[string]struct{}
+	prefixes map[int]map[string]struct{}
+}
+
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachedList{
+		words:    make(map[string]struct{}),
+		prefixes: make(map[int]map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.words[strings.ToLower(line)] = struct{}{}
+
+		entry := line
+		if i := strings.IndexByte(entry, ':'); i >= 0 {
+			entry = entry[:i]
+		}
+		if len(entry) >= minHashPrefixLength && len(entry) <= sha1.Size*2 && isHex(entry) {
+			if list.prefixes[len(entry)] == nil {
+				list.prefixes[len(entry)] = make(map[string]struct{})
+			}
+			list.prefixes[len(entry)][strings.ToUpper(entry)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) Contains(password string) bool {
+	if _, ok := l.words[strings.ToLower(password)]; ok {
+		return true
+	}
+
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for length, prefixes := range l.prefixes {
+		if _, ok := prefixes[digest[:length]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *BreachedList) Len() int {
+	return len(l.words)
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
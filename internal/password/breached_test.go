@@ -0,0 +1,71 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadTestList(t *testing.T, lines ...string) *BreachedList {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatalf("LoadBreachedList: %v", err)
+	}
+	return list
+}
+
+func TestBreachedListContains(t *testing.T) {
+	list := loadTestList(t,
+		"# комментарий",
+		"",
+		"Qwerty123",
+		// Полный хеш в нижнем регистре с числом утечек, как в выгрузках HIBP.
+		sha1Hex("correct horse")+":42",
+		// Префикс хеша из 16 символов в верхнем регистре.
+		strings.ToUpper(sha1Hex("battery staple")[:16]),
+		// Короткая hex-строка - обычное слово, а не префикс хеша.
+		"123456",
+	)
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"Qwerty123", true},
+		{"qwerty123", true},
+		{"correct horse", true},
+		{"battery staple", true},
+		{"123456", true},
+		{"# комментарий", false},
+		{"correct horse!", false},
+		{"unrelated", false},
+	}
+	for _, tt := range tests {
+		if got := list.Contains(tt.password); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestBreachedListIgnoresShortHashPrefixes(t *testing.T) {
+	// Префикс короче minHashPrefixLength отсекал бы случайные пароли.
+	password := "unrelated"
+	list := loadTestList(t, sha1Hex(password)[:minHashPrefixLength-1])
+
+	if list.Contains(password) {
+		t.Errorf("short prefix %q matched", sha1Hex(password)[:minHashPrefixLength-1])
+	}
+}
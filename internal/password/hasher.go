@@ -0,0 +1,177 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+type Params struct {
+	Algorithm string
+	// Параметры argon2id: память в KiB, число проходов и потоков.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+	// Стоимость bcrypt.
+	BcryptCost int
+}
+
+// Hasher создает хеши настроенным алгоритмом, но проверяет хеши обоих
+// форматов, так что смена алгоритма или параметров не ломает вход со старыми
+// паролями.
+type Hasher struct {
+	params Params
+}
+
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case AlgorithmArgon2id:
+		if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+			return nil, fmt.Errorf("invalid argon2id parameters")
+		}
+		if params.SaltLength == 0 {
+			params.SaltLength = 16
+		}
+		if params.KeyLength == 0 {
+			params.KeyLength = 32
+		}
+	case AlgorithmBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", params.BcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", params.Algorithm)
+	}
+	return &Hasher{params: params}, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	p := argon2Params{
+		memory:      h.params.Memory,
+		iterations:  h.params.Iterations,
+		parallelism: h.params.Parallelism,
+	}
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, h.params.KeyLength)
+	return p.encode(salt, key), nil
+}
+
+// Verify сравнивает пароль с хешем любого поддерживаемого формата.
+// Несовпадение пароля - это false без ошибки; ошибка означает поврежденный
+// или незнакомый хеш.
+func (h *Hasher) Verify(password, hash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+// NeedsRehash сообщает, что хеш создан другим алгоритмом или с другими
+// параметрами, чем настроены сейчас.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	switch h.params.Algorithm {
+	case AlgorithmBcrypt:
+		if !isBcrypt(hash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.params.BcryptCost
+	default:
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return true
+		}
+		return p.memory != h.params.Memory ||
+			p.iterations != h.params.Iterations ||
+			p.parallelism != h.params.Parallelism ||
+			uint32(len(salt)) != h.params.SaltLength ||
+			uint32(len(key)) != h.params.KeyLength
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// encode записывает хеш в общепринятом PHC-формате:
+// $argon2id$v=19$m=65536,t=3,p=2$<соль>$<ключ>.
+func (p argon2Params) encode(salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version in hash")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters in hash: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2 key")
+	}
+	return p, salt, key, nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,130 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Минимальные параметры, чтобы тесты не тратили время на вычисление хешей.
+var (
+	testArgon2 = Params{Algorithm: AlgorithmArgon2id, Memory: 64, Iterations: 1, Parallelism: 1}
+	testBcrypt = Params{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+)
+
+func newTestHasher(t *testing.T, params Params) *Hasher {
+	t.Helper()
+	hasher, err := NewHasher(params)
+	if err != nil {
+		t.Fatalf("NewHasher(%+v): %v", params, err)
+	}
+	return hasher
+}
+
+func TestHashRoundTrip(t *testing.T) {
+	for _, params := range []Params{testArgon2, testBcrypt} {
+		t.Run(params.Algorithm, func(t *testing.T) {
+			hasher := newTestHasher(t, params)
+			hash, err := hasher.Hash("s3cure-пароль")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+
+			if ok, err := hasher.Verify("s3cure-пароль", hash); !ok || err != nil {
+				t.Errorf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := hasher.Verify("s3cure-парол", hash); ok || err != nil {
+				t.Errorf("Verify(wrong) = %v, %v; want false without error", ok, err)
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Error("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+func TestHashUsesRandomSalt(t *testing.T) {
+	hasher := newTestHasher(t, testArgon2)
+	first, _ := hasher.Hash("password")
+	second, _ := hasher.Hash("password")
+	if first == second {
+		t.Error("two hashes of the same password are equal")
+	}
+	if !strings.HasPrefix(first, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("hash = %q, want PHC format", first)
+	}
+}
+
+// Hasher проверяет хеши обоих форматов независимо от настроенного алгоритма.
+func TestVerifyAcceptsOtherAlgorithm(t *testing.T) {
+	argonHash, _ := newTestHasher(t, testArgon2).Hash("password")
+	bcryptHash, _ := newTestHasher(t, testBcrypt).Hash("password")
+
+	if ok, err := newTestHasher(t, testBcrypt).Verify("password", argonHash); !ok || err != nil {
+		t.Errorf("bcrypt hasher on argon2id hash = %v, %v", ok, err)
+	}
+	if ok, err := newTestHasher(t, testArgon2).Verify("password", bcryptHash); !ok || err != nil {
+		t.Errorf("argon2id hasher on bcrypt hash = %v, %v", ok, err)
+	}
+}
+
+func TestNeedsRehashAfterParameterChange(t *testing.T) {
+	argonHash, _ := newTestHasher(t, testArgon2).Hash("password")
+	bcryptHash, _ := newTestHasher(t, testBcrypt).Hash("password")
+
+	changed := func(change func(*Params)) Params {
+		params := testArgon2
+		change(&params)
+		return params
+	}
+	tests := []struct {
+		name   string
+		params Params
+		hash   string
+	}{
+		{"argon2 memory", changed(func(p *Params) { p.Memory = 128 }), argonHash},
+		{"argon2 iterations", changed(func(p *Params) { p.Iterations = 2 }), argonHash},
+		{"argon2 parallelism", changed(func(p *Params) { p.Parallelism = 2 }), argonHash},
+		{"argon2 key length", changed(func(p *Params) { p.KeyLength = 64 }), argonHash},
+		{"argon2 salt length", changed(func(p *Params) { p.SaltLength = 32 }), argonHash},
+		{"bcrypt cost", Params{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1}, bcryptHash},
+		{"bcrypt to argon2", testArgon2, bcryptHash},
+		{"argon2 to bcrypt", testBcrypt, argonHash},
+		{"unknown format", testArgon2, "plain-text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !newTestHasher(t, tt.params).NeedsRehash(tt.hash) {
+				t.Error("NeedsRehash = false, want true")
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnknownFormat(t *testing.T) {
+	hasher := newTestHasher(t, testArgon2)
+	for _, hash := range []string{"", "password", "$1$abc$def", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		if ok, err := hasher.Verify("password", hash); ok || !errors.Is(err, ErrUnknownHashFormat) {
+			t.Errorf("Verify(%q) = %v, %v; want ErrUnknownHashFormat", hash, ok, err)
+		}
+	}
+
+	// Поврежденный хеш известного формата - ошибка, но не ErrUnknownHashFormat.
+	if ok, err := hasher.Verify("password", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$"); ok || err == nil {
+		t.Errorf("Verify(broken argon2id) = %v, %v; want error", ok, err)
+	}
+}
+
+func TestNewHasherValidatesParams(t *testing.T) {
+	for _, params := range []Params{
+		{Algorithm: AlgorithmArgon2id},
+		{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MaxCost + 1},
+		{Algorithm: "md5"},
+	} {
+		if _, err := NewHasher(params); err == nil {
+			t.Errorf("NewHasher(%+v) succeeded", params)
+		}
+	}
+}
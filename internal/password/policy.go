@@ -0,0 +1,96 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BcryptMaxLength - bcrypt учитывает только первые 72 байта пароля.
+const BcryptMaxLength = 72
+
+type PolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// PolicyError перечисляет все нарушенные правила, чтобы пользователь мог
+// исправить пароль за один раз.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+type Policy struct {
+	cfg      PolicyConfig
+	breached *BreachedList
+}
+
+// NewPolicy создает политику паролей. breached может быть nil, если список
+// скомпрометированных паролей не загружен.
+func NewPolicy(cfg PolicyConfig, breached *BreachedList) *Policy {
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// Check проверяет пароль. identifiers - логин, email и другие данные
+// пользователя, с которыми пароль не должен совпадать.
+func (p *Policy) Check(password string, identifiers ...string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("Must be at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.MaxLength > 0 && len(password) > p.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("Must be no more than %d bytes", p.cfg.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "Must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		violations = append(violations, "Must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "Must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, "Must contain a special character")
+	}
+
+	for _, identifier := range identifiers {
+		if identifier != "" && strings.EqualFold(password, identifier) {
+			violations = append(violations, "Must not be the same as login or email")
+			break
+		}
+	}
+
+	if p.breached != nil && p.breached.Contains(password) {
+		violations = append(violations, "This password is too common or has appeared in a data breach")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
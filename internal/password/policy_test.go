@@ -0,0 +1,66 @@
+package password
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violations(err error) []string {
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations
+	}
+	return nil
+}
+
+// MinLength считается в символах, а MaxLength - в байтах: bcrypt учитывает
+// только первые BcryptMaxLength байт.
+func TestPolicyLengthLimits(t *testing.T) {
+	policy := NewPolicy(PolicyConfig{MinLength: 8, MaxLength: BcryptMaxLength}, nil)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"ascii at minimum", "abcdefgh", nil},
+		{"cyrillic at minimum", "пароль12", nil},
+		{"short in runes", "пароль1", []string{"Must be at least 8 characters"}},
+		{"ascii at maximum", strings.Repeat("a", BcryptMaxLength), nil},
+		{"cyrillic at maximum", strings.Repeat("я", BcryptMaxLength/2), nil},
+		{"cyrillic over maximum", strings.Repeat("я", BcryptMaxLength/2+1), []string{"Must be no more than 72 bytes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(policy.Check(tt.password)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check(%q) = %q, want %q", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyReportsAllViolations(t *testing.T) {
+	policy := NewPolicy(PolicyConfig{
+		MinLength:     12,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}, nil)
+
+	want := []string{
+		"Must be at least 12 characters",
+		"Must contain an uppercase letter",
+		"Must contain a digit",
+		"Must contain a special character",
+		"Must not be the same as login or email",
+	}
+	if got := violations(policy.Check("ivan", "", "IVAN")); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %q, want %q", got, want)
+	}
+	if err := policy.Check("Пароль-2024!x"); err != nil {
+		t.Errorf("Check(valid) = %v", err)
+	}
+}
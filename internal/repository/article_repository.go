@@ -1,80 +1,318 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"goida/internal/models"
+	"goida/internal/pagination"
 )
 
+// ArticleRepository - CreateArticle и UpdateArticle в той же транзакции
+// сохраняют новую версию статьи. GetRevision возвращает nil без ошибки, если
+// версия не найдена.
+//
+// Slug статьи, переданный в CreateArticle и UpdateArticle, - основа: при
+// совпадении с чужим slug к нему добавляется числовой суффикс, итоговый
+// записывается в article.Slug. Прежний slug сохраняется в истории.
 type ArticleRepository interface {
 	CreateArticle(article *models.Article) error
 	GetArticle(id int) (*models.Article, error)
-	UpdateArticle(id int, article *models.Article) error
+	// SaveRendering сохраняет HTML, анонс и оглавление, перестроенные по
+	// новой версии правил; более новую отрисовку не перезаписывает.
+	SaveRendering(ctx context.Context, article *models.Article) error
+	// GetArticleBySlug ищет статью по текущему или прежнему slug; в
+	// найденной статье Slug всегда текущий.
+	GetArticleBySlug(ctx context.Context, slug string) (*models.Article, error)
+	// UpdateArticle заполняет revision номером и временем новой версии;
+	// EditorID и RestoredFrom задает вызывающий.
+	UpdateArticle(id int, article *models.Article, revision *models.ArticleRevision) error
 	DeleteArticle(id int) error
-	ListArticles(limit, offset int) ([]*models.Article, error)
-	GetArticlesByAuthor(authorID int, limit, offset int) ([]*models.Article, error)
-	CountArticlesByAuthor(authorID int) (int, error)
+	// ListArticles и GetArticlesByAuthor читают page.Fetch() статей в
+	// порядке, заданном page.Keyset.
+	ListArticles(page pagination.Params, filter models.ArticleFilter, visibility ArticleVisibility) ([]*models.Article, error)
+	CountArticles(filter models.ArticleFilter, visibility ArticleVisibility) (int, error)
+	GetArticlesByAuthor(authorID int, page pagination.Params, visibility ArticleVisibility) ([]*models.Article, error)
+	CountArticlesByAuthor(authorID int, visibility ArticleVisibility) (int, error)
+	// ListAllByAuthor возвращает все статьи автора без учета
+	// DELETED_USER_CONTENT_POLICY - для выгрузки персональных данных.
+	ListAllByAuthor(authorID int) ([]*models.Article, error)
+	// PublishDue переводит в published запланированные статьи, время
+	// публикации которых наступило.
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
+	// SearchArticles - полнотекстовый поиск, результаты упорядочены по
+	// релевантности.
+	SearchArticles(ctx context.Context, search ArticleSearch, visibility ArticleVisibility) ([]*models.ArticleSearchResult, error)
+	CountSearchArticles(ctx context.Context, search ArticleSearch, visibility ArticleVisibility) (int, error)
+	// ListTags возвращает теги опубликованных статей с числом статей.
+	ListTags(ctx context.Context, limit, offset int) ([]*models.TagUsage, error)
+	CountTags(ctx context.Context) (int, error)
+	// ListRevisions читает page.Fetch() версий в порядке, заданном
+	// page.Keyset: от новых к старым.
+	ListRevisions(ctx context.Context, articleID int, page pagination.Params) ([]*models.ArticleRevision, error)
+	CountRevisions(ctx context.Context, articleID int) (int, error)
+	GetRevision(ctx context.Context, articleID, revision int) (*models.ArticleRevision, error)
+}
+
+// ArticleVisibility - какие неопубликованные статьи попадают в выборку: все
+// (All) или только статьи автора AuthorID. Опубликованные видны всегда.
+type ArticleVisibility struct {
+	AuthorID int
+	All      bool
+}
+
+// ArticleSearch - параметры поиска. Language - конфигурация морфологии
+// ("russian" или "english"); пустая - обе. AuthorID = 0 и nil в From/To -
+// без фильтра; даты сравниваются со временем публикации.
+type ArticleSearch struct {
+	Query    string
+	Language string
+	AuthorID int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
 }
 
+const (
+	searchLanguageRussian = "russian"
+	searchLanguageEnglish = "english"
+	// searchHeadlineOptions - до двух фрагментов текста вокруг совпадений.
+	searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=10, MaxWords=30, FragmentDelimiter=" … "`
+)
+
+// articlePublished - условие видимости статьи читателям; совпадает с
+// models.Article.IsPublished.
+const articlePublished = `(a.status = 'published' OR (a.status = 'scheduled' AND a.publish_at <= NOW()))`
+
 type articleRepository struct {
-	db *sql.DB
+	db                 *sql.DB
+	hideDeletedAuthors bool
 }
 
-func NewArticleRepository(db *sql.DB) ArticleRepository {
-	return &articleRepository{db: db}
+// NewArticleRepository - при hideDeletedAuthors статьи удаленных пользователей
+// не возвращаются, иначе автор подписывается как models.DeletedUserName.
+func NewArticleRepository(db *sql.DB, hideDeletedAuthors bool) ArticleRepository {
+	return &articleRepository{db: db, hideDeletedAuthors: hideDeletedAuthors}
+}
+
+const articleColumns = `a.id, a.title, a.slug, a.content, a.content_format, a.content_html, a.excerpt,
+		a.toc, a.render_version, a.author_id, a.status, a.publish_at,
+		a.created_at, a.updated_at, u.name, COALESCE(u.is_deleted, FALSE), c.id, c.slug, c.name`
+
+// scanArticle читает articleColumns, а затем дополнительные колонки в dest.
+func scanArticle(row rowScanner, dest ...interface{}) (*models.Article, error) {
+	article := &models.Article{}
+	var authorName sql.NullString
+	var publishAt sql.NullTime
+	var categoryID sql.NullInt64
+	var categorySlug, categoryName sql.NullString
+	var contentHTML, excerpt sql.NullString
+	var toc []byte
+	err := row.Scan(append([]interface{}{
+		&article.ID, &article.Title, &article.Slug, &article.Content, &article.ContentFormat,
+		&contentHTML, &excerpt, &toc, &article.RenderVersion, &article.AuthorID,
+		&article.Status, &publishAt, &article.CreatedAt, &article.UpdatedAt,
+		&authorName, &article.AuthorDeleted, &categoryID, &categorySlug, &categoryName,
+	}, dest...)...)
+	if err != nil {
+		return nil, err
+	}
+	article.ContentHTML = contentHTML.String
+	article.Excerpt = excerpt.String
+	if len(toc) > 0 {
+		if err := json.Unmarshal(toc, &article.TOC); err != nil {
+			return nil, fmt.Errorf("failed to decode article toc: %w", err)
+		}
+	}
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		article.CategoryID = &id
+		article.Category = &models.ArticleCategory{ID: id, Slug: categorySlug.String, Name: categoryName.String}
+	}
+	article.PublishAt = nullTimePtr(publishAt)
+	if article.Status == models.ArticleScheduled && article.IsPublished(time.Now()) {
+		article.Status = models.ArticlePublished
+	}
+	if article.AuthorDeleted {
+		article.AuthorName = models.DeletedUserName
+	} else if authorName.Valid {
+		article.AuthorName = authorName.String
+	}
+	return article, nil
 }
 
 func (r *articleRepository) CreateArticle(article *models.Article) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if article.Slug, err = uniqueArticleSlug(tx, article.Slug, 0); err != nil {
+		return err
+	}
+
+	toc, err := json.Marshal(article.TOC)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO articles (title, content, author_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO articles (title, slug, content, content_format, content_html, excerpt, toc, render_version,
+			author_id, status, publish_at, category_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, article.Title, article.Content, article.AuthorID).
+	err = tx.QueryRow(query, article.Title, article.Slug, article.Content, article.ContentFormat, article.ContentHTML,
+		article.Excerpt, toc, article.RenderVersion, article.AuthorID, article.Status, article.PublishAt, article.CategoryID).
 		Scan(&article.ID, &article.CreatedAt, &article.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err := setArticleTags(tx, article.ID, article.Tags); err != nil {
+		return err
+	}
 
-	return err
+	_, err = tx.Exec(`
+		INSERT INTO article_revisions (article_id, revision, title, content, content_format, editor_id, created_at)
+		VALUES ($1, 1, $2, $3, $4, $5, $6)`,
+		article.ID, article.Title, article.Content, article.ContentFormat, article.AuthorID, article.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save article revision: %w", err)
+	}
+
+	return tx.Commit()
 }
 
 func (r *articleRepository) GetArticle(id int) (*models.Article, error) {
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at
-		FROM articles WHERE id = $1`
-
-	article := &models.Article{}
-	err := r.db.QueryRow(query, id).Scan(
-		&article.ID, &article.Title, &article.Content,
-		&article.AuthorID, &article.CreatedAt, &article.UpdatedAt)
+		SELECT ` + articleColumns + `
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE a.id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)`
 
+	article, err := scanArticle(r.db.QueryRow(query, id, r.hideDeletedAuthors))
 	if err != nil {
 		return nil, err
 	}
-
+	if err := r.loadTags(context.Background(), []*models.Article{article}); err != nil {
+		return nil, err
+	}
 	return article, nil
 }
 
-func (r *articleRepository) UpdateArticle(id int, article *models.Article) error {
+func (r *articleRepository) SaveRendering(ctx context.Context, article *models.Article) error {
+	toc, err := json.Marshal(article.TOC)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE articles
+		SET content_html = $2, excerpt = $3, toc = $4, render_version = $5
+		WHERE id = $1 AND render_version < $5 AND content = $6 AND content_format = $7`,
+		article.ID, article.ContentHTML, article.Excerpt, toc, article.RenderVersion, article.Content, article.ContentFormat)
+	if err != nil {
+		return fmt.Errorf("failed to save article rendering: %w", err)
+	}
+	return nil
+}
+
+func (r *articleRepository) GetArticleBySlug(ctx context.Context, slug string) (*models.Article, error) {
 	query := `
-		UPDATE articles 
-		SET title = $1, content = $2
-		WHERE id = $3`
+		SELECT ` + articleColumns + `
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE (a.slug = $1 OR a.id = (SELECT article_id FROM article_slugs WHERE slug = $1))
+			AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)`
+
+	article, err := scanArticle(r.db.QueryRowContext(ctx, query, slug, r.hideDeletedAuthors))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadTags(ctx, []*models.Article{article}); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+func (r *articleRepository) UpdateArticle(id int, article *models.Article, revision *models.ArticleRevision) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Блокировка строки статьи упорядочивает номера версий при
+	// одновременных изменениях.
+	var currentSlug string
+	if err := tx.QueryRow(`SELECT slug FROM articles WHERE id = $1 FOR UPDATE`, id).Scan(&currentSlug); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("article not found")
+		}
+		return err
+	}
+	if err := renameArticleSlug(tx, id, currentSlug, article); err != nil {
+		return err
+	}
 
-	result, err := r.db.Exec(query, article.Title, article.Content, id)
+	// Статьи, созданные в обход API (например, сидами), получают первую
+	// версию с текстом до изменения.
+	_, err = tx.Exec(`
+		INSERT INTO article_revisions (article_id, revision, title, content, content_format, editor_id, created_at)
+		SELECT id, 1, title, content, content_format, author_id, COALESCE(updated_at, created_at, NOW())
+		FROM articles
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM article_revisions WHERE article_id = $1)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to save article revision: %w", err)
+	}
+
+	toc, err := json.Marshal(article.TOC)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	query := `
+		UPDATE articles 
+		SET title = $1, slug = $2, content = $3, content_format = $4, content_html = $5, excerpt = $6,
+			toc = $7, render_version = $8, status = $9, publish_at = $10, category_id = $11
+		WHERE id = $12`
+
+	_, err = tx.Exec(query, article.Title, article.Slug, article.Content, article.ContentFormat, article.ContentHTML,
+		article.Excerpt, toc, article.RenderVersion, article.Status, article.PublishAt, article.CategoryID, id)
 	if err != nil {
 		return err
 	}
+	if err := setArticleTags(tx, id, article.Tags); err != nil {
+		return err
+	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("article not found")
+	err = tx.QueryRow(`
+		INSERT INTO article_revisions (article_id, revision, title, content, content_format, editor_id, restored_from)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6
+		FROM article_revisions
+		WHERE article_id = $1
+		RETURNING id, revision, created_at`,
+		id, article.Title, article.Content, article.ContentFormat, revision.EditorID, revision.RestoredFrom).
+		Scan(&revision.ID, &revision.Revision, &revision.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save article revision: %w", err)
 	}
+	revision.ArticleID = id
+	revision.Title = article.Title
+	revision.Content = article.Content
+	revision.ContentFormat = article.ContentFormat
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *articleRepository) DeleteArticle(id int) error {
@@ -97,72 +335,495 @@ func (r *articleRepository) DeleteArticle(id int) error {
 	return nil
 }
 
-func (r *articleRepository) ListArticles(limit, offset int) ([]*models.Article, error) {
+// articleFeedKey - ключ сортировки лент статей: время публикации, для
+// статей без отложенной публикации - время создания.
+const articleFeedKey = `COALESCE(a.publish_at, a.created_at)`
+
+// articleFilterClause - условия ленты статей: параметры $1-$3 - скрытие
+// удаленных авторов и видимость, $4 - категория, $5 - теги.
+const articleFilterClause = `NOT (COALESCE(u.is_deleted, FALSE) AND $1)
+			AND (` + articlePublished + ` OR $2 OR a.author_id = $3)
+			AND ($4 = '' OR c.slug = $4)
+			AND (cardinality($5::text[]) = 0 OR a.id IN (
+				SELECT at.article_id
+				FROM article_tags at
+				JOIN tags t ON t.id = at.tag_id
+				WHERE t.slug = ANY($5::text[])
+				GROUP BY at.article_id
+				HAVING COUNT(*) = cardinality($5::text[])))`
+
+func (r *articleRepository) ListArticles(page pagination.Params, filter models.ArticleFilter, visibility ArticleVisibility) ([]*models.Article, error) {
+	keyset, order, keyArgs := page.Keyset(articleFeedKey, "a.id", 8)
 	query := `
-		SELECT a.id, a.title, a.content, a.author_id, a.created_at, a.updated_at, u.name as author_name
+		SELECT ` + articleColumns + `
 		FROM articles a
 		LEFT JOIN users u ON a.author_id = u.id
-		ORDER BY a.created_at DESC
-		LIMIT $1 OFFSET $2`
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE ` + articleFilterClause + ` AND ` + keyset + `
+		ORDER BY ` + order + `
+		LIMIT $6 OFFSET $7`
+
+	args := append([]interface{}{r.hideDeletedAuthors, visibility.All, visibility.AuthorID,
+		filter.Category, pq.Array(filter.Tags), page.Fetch(), page.Skip()}, keyArgs...)
+	return r.queryArticles(query, args...)
+}
+
+func (r *articleRepository) CountArticles(filter models.ArticleFilter, visibility ArticleVisibility) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE ` + articleFilterClause
+
+	var count int
+	err := r.db.QueryRow(query, r.hideDeletedAuthors, visibility.All, visibility.AuthorID,
+		filter.Category, pq.Array(filter.Tags)).Scan(&count)
+	return count, err
+}
+
+func (r *articleRepository) GetArticlesByAuthor(authorID int, page pagination.Params, visibility ArticleVisibility) ([]*models.Article, error) {
+	keyset, order, keyArgs := page.Keyset(articleFeedKey, "a.id", 7)
+	query := `
+		SELECT ` + articleColumns + `
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE a.author_id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $4)
+			AND (` + articlePublished + ` OR $5 OR a.author_id = $6)
+			AND ` + keyset + `
+		ORDER BY ` + order + `
+		LIMIT $2 OFFSET $3`
+
+	args := append([]interface{}{authorID, page.Fetch(), page.Skip(), r.hideDeletedAuthors,
+		visibility.All, visibility.AuthorID}, keyArgs...)
+	return r.queryArticles(query, args...)
+}
+
+func (r *articleRepository) CountArticlesByAuthor(authorID int, visibility ArticleVisibility) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		WHERE a.author_id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)
+			AND (` + articlePublished + ` OR $3 OR a.author_id = $4)`
+
+	var count int
+	err := r.db.QueryRow(query, authorID, r.hideDeletedAuthors, visibility.All, visibility.AuthorID).Scan(&count)
+	return count, err
+}
+
+func (r *articleRepository) ListAllByAuthor(authorID int) ([]*models.Article, error) {
+	query := `
+		SELECT ` + articleColumns + `
+		FROM articles a
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE a.author_id = $1
+		ORDER BY a.created_at`
+
+	return r.queryArticles(query, authorID)
+}
+
+func (r *articleRepository) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE articles
+		SET status = 'published'
+		WHERE status = 'scheduled' AND publish_at <= $1`
+
+	result, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// searchFilterClause - условия поиска: $1 - текст запроса, $2-$4 - скрытие
+// удаленных авторов и видимость, $5 - автор, $6 и $7 - период.
+const searchFilterClause = `a.search_vector @@ q.query
+			AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)
+			AND (` + articlePublished + ` OR $3 OR a.author_id = $4)
+			AND ($5 = 0 OR a.author_id = $5)
+			AND ($6::timestamptz IS NULL OR COALESCE(a.publish_at, a.created_at) >= $6)
+			AND ($7::timestamptz IS NULL OR COALESCE(a.publish_at, a.created_at) < $7)`
+
+// searchQuery - tsquery для языка поиска и конфигурация для ts_headline.
+func searchQuery(language string) (tsquery, headline string) {
+	switch language {
+	case searchLanguageRussian:
+		return `websearch_to_tsquery('russian', $1)`, searchLanguageRussian
+	case searchLanguageEnglish:
+		return `websearch_to_tsquery('english', $1)`, searchLanguageEnglish
+	}
+	return `websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1)`, searchLanguageRussian
+}
+
+func (r *articleRepository) searchArgs(search ArticleSearch, visibility ArticleVisibility) []interface{} {
+	return []interface{}{search.Query, r.hideDeletedAuthors, visibility.All, visibility.AuthorID,
+		search.AuthorID, search.From, search.To}
+}
+
+func (r *articleRepository) SearchArticles(ctx context.Context, search ArticleSearch, visibility ArticleVisibility) ([]*models.ArticleSearchResult, error) {
+	tsquery, headline := searchQuery(search.Language)
+
+	// Текст экранируется до ts_headline, чтобы в сниппете HTML был только
+	// <mark>.
+	query := `
+		SELECT ` + articleColumns + `,
+			ts_rank_cd(a.search_vector, q.query) AS rank,
+			ts_headline($8::regconfig,
+				replace(replace(replace(a.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q.query, $9)
+		FROM articles a
+		CROSS JOIN (SELECT ` + tsquery + ` AS query) q
+		LEFT JOIN users u ON a.author_id = u.id
+		LEFT JOIN categories c ON a.category_id = c.id
+		WHERE ` + searchFilterClause + `
+		ORDER BY rank DESC, COALESCE(a.publish_at, a.created_at) DESC
+		LIMIT $10 OFFSET $11`
 
-	rows, err := r.db.Query(query, limit, offset)
+	args := append(r.searchArgs(search, visibility), headline, searchHeadlineOptions, search.Limit, search.Offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var articles []*models.Article
+	var results []*models.ArticleSearchResult
 	for rows.Next() {
-		article := &models.Article{}
-		var authorName sql.NullString
-		err := rows.Scan(
-			&article.ID, &article.Title, &article.Content,
-			&article.AuthorID, &article.CreatedAt, &article.UpdatedAt, &authorName)
+		var rank float64
+		var snippet string
+		article, err := scanArticle(rows, &rank, &snippet)
 		if err != nil {
 			return nil, err
 		}
-		if authorName.Valid {
-			article.AuthorName = authorName.String
+		results = append(results, &models.ArticleSearchResult{Article: *article, Rank: rank, Snippet: snippet})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	articles := make([]*models.Article, len(results))
+	for i, result := range results {
+		articles[i] = &result.Article
+	}
+	if err := r.loadTags(ctx, articles); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *articleRepository) CountSearchArticles(ctx context.Context, search ArticleSearch, visibility ArticleVisibility) (int, error) {
+	tsquery, _ := searchQuery(search.Language)
+	query := `
+		SELECT COUNT(*)
+		FROM articles a
+		CROSS JOIN (SELECT ` + tsquery + ` AS query) q
+		LEFT JOIN users u ON a.author_id = u.id
+		WHERE ` + searchFilterClause
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, r.searchArgs(search, visibility)...).Scan(&count)
+	return count, err
+}
+
+func (r *articleRepository) ListTags(ctx context.Context, limit, offset int) ([]*models.TagUsage, error) {
+	query := `
+		SELECT t.slug, COUNT(*)
+		FROM tags t
+		JOIN article_tags at ON at.tag_id = t.id
+		JOIN articles a ON a.id = at.article_id
+		LEFT JOIN users u ON a.author_id = u.id
+		WHERE ` + articlePublished + ` AND NOT (COALESCE(u.is_deleted, FALSE) AND $3)
+		GROUP BY t.slug
+		ORDER BY COUNT(*) DESC, t.slug
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset, r.hideDeletedAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.TagUsage
+	for rows.Next() {
+		tag := &models.TagUsage{}
+		if err := rows.Scan(&tag.Slug, &tag.Count); err != nil {
+			return nil, err
 		}
-		articles = append(articles, article)
+		tags = append(tags, tag)
 	}
+	return tags, rows.Err()
+}
 
-	return articles, nil
+func (r *articleRepository) CountTags(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT t.slug)
+		FROM tags t
+		JOIN article_tags at ON at.tag_id = t.id
+		JOIN articles a ON a.id = at.article_id
+		LEFT JOIN users u ON a.author_id = u.id
+		WHERE ` + articlePublished + ` AND NOT (COALESCE(u.is_deleted, FALSE) AND $1)`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, r.hideDeletedAuthors).Scan(&count)
+	return count, err
+}
+
+// renameArticleSlug выбирает slug статьи по основе article.Slug. Если
+// текущий slug уже построен от той же основы, он сохраняется; иначе прежний
+// slug уходит в историю.
+func renameArticleSlug(tx *sql.Tx, id int, currentSlug string, article *models.Article) error {
+	if article.Slug == "" || slugHasBase(currentSlug, article.Slug) {
+		article.Slug = currentSlug
+		return nil
+	}
+
+	slug, err := uniqueArticleSlug(tx, article.Slug, id)
+	if err != nil {
+		return err
+	}
+	article.Slug = slug
+
+	_, err = tx.Exec(`
+		INSERT INTO article_slugs (slug, article_id) VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING`, currentSlug, id)
+	if err != nil {
+		return fmt.Errorf("failed to save article slug history: %w", err)
+	}
+	// Возврат к прежнему заголовку возвращает и прежний slug.
+	_, err = tx.Exec(`DELETE FROM article_slugs WHERE slug = $1 AND article_id = $2`, slug, id)
+	if err != nil {
+		return fmt.Errorf("failed to save article slug history: %w", err)
+	}
+	return nil
+}
+
+// uniqueArticleSlug подбирает свободный slug: base, base-2, base-3... Занятыми
+// считаются текущие и прежние slug других статей. Разные основы могут
+// претендовать на один slug ("a-2" - и основа "a-2", и второй вариант "a"),
+// поэтому выбор slug сериализуется одной advisory-блокировкой на все статьи;
+// она держится до конца транзакции.
+func uniqueArticleSlug(tx *sql.Tx, base string, articleID int) (string, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('article_slug'))`); err != nil {
+		return "", fmt.Errorf("failed to lock article slug: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT slug FROM articles
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2
+		UNION
+		SELECT slug FROM article_slugs
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND article_id <> $2`,
+		base, articleID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check article slug: %w", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
 }
 
-func (r *articleRepository) GetArticlesByAuthor(authorID int, limit, offset int) ([]*models.Article, error) {
+// slugHasBase сообщает, что slug равен base или base с числовым суффиксом.
+func slugHasBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok || suffix == "" {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// setArticleTags заменяет теги статьи; новые теги создаются.
+func setArticleTags(tx *sql.Tx, articleID int, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM article_tags WHERE article_id = $1`, articleID); err != nil {
+		return fmt.Errorf("failed to update article tags: %w", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`INSERT INTO tags (slug) SELECT unnest($1::text[]) ON CONFLICT (slug) DO NOTHING`, pq.Array(tags))
+	if err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO article_tags (article_id, tag_id)
+		SELECT $1, id FROM tags WHERE slug = ANY($2::text[])`,
+		articleID, pq.Array(tags))
+	if err != nil {
+		return fmt.Errorf("failed to update article tags: %w", err)
+	}
+	return nil
+}
+
+// loadTags заполняет теги статей одним запросом.
+func (r *articleRepository) loadTags(ctx context.Context, articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(articles))
+	byID := make(map[int]*models.Article, len(articles))
+	for i, article := range articles {
+		ids[i] = int64(article.ID)
+		byID[article.ID] = article
+		article.Tags = []string{}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT at.article_id, t.slug
+		FROM article_tags at
+		JOIN tags t ON t.id = at.tag_id
+		WHERE at.article_id = ANY($1)
+		ORDER BY t.slug`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load article tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var articleID int
+		var slug string
+		if err := rows.Scan(&articleID, &slug); err != nil {
+			return err
+		}
+		if article, ok := byID[articleID]; ok {
+			article.Tags = append(article.Tags, slug)
+		}
+	}
+	return rows.Err()
+}
+
+const revisionColumns = `r.id, r.article_id, r.revision, r.title, r.content_format, r.editor_id, r.restored_from,
+		r.created_at, u.name, COALESCE(u.is_deleted, FALSE)`
+
+func scanRevision(row rowScanner, dest ...interface{}) (*models.ArticleRevision, error) {
+	revision := &models.ArticleRevision{}
+	var editorID sql.NullInt64
+	var restoredFrom sql.NullInt64
+	var editorName sql.NullString
+	var editorDeleted bool
+	err := row.Scan(append([]interface{}{
+		&revision.ID, &revision.ArticleID, &revision.Revision, &revision.Title, &revision.ContentFormat,
+		&editorID, &restoredFrom, &revision.CreatedAt, &editorName, &editorDeleted,
+	}, dest...)...)
+	if err != nil {
+		return nil, err
+	}
+	if editorID.Valid {
+		id := int(editorID.Int64)
+		revision.EditorID = &id
+	}
+	if restoredFrom.Valid {
+		from := int(restoredFrom.Int64)
+		revision.RestoredFrom = &from
+	}
+	if editorDeleted {
+		revision.EditorName = models.DeletedUserName
+	} else if editorName.Valid {
+		revision.EditorName = editorName.String
+	}
+	return revision, nil
+}
+
+func (r *articleRepository) ListRevisions(ctx context.Context, articleID int, page pagination.Params) ([]*models.ArticleRevision, error) {
+	keyset, order, keyArgs := page.Keyset("r.created_at", "r.id", 4)
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at
-		FROM articles 
-		WHERE author_id = $1
-		ORDER BY created_at DESC
+		SELECT ` + revisionColumns + `
+		FROM article_revisions r
+		LEFT JOIN users u ON r.editor_id = u.id
+		WHERE r.article_id = $1 AND ` + keyset + `
+		ORDER BY ` + order + `
 		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(query, authorID, limit, offset)
+	args := append([]interface{}{articleID, page.Fetch(), page.Skip()}, keyArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var articles []*models.Article
+	var revisions []*models.ArticleRevision
 	for rows.Next() {
-		article := &models.Article{}
-		err := rows.Scan(
-			&article.ID, &article.Title, &article.Content,
-			&article.AuthorID, &article.CreatedAt, &article.UpdatedAt)
+		revision, err := scanRevision(rows)
 		if err != nil {
 			return nil, err
 		}
-		articles = append(articles, article)
+		revisions = append(revisions, revision)
 	}
-
-	return articles, nil
+	return revisions, rows.Err()
 }
 
-func (r *articleRepository) CountArticlesByAuthor(authorID int) (int, error) {
-	query := `SELECT COUNT(*) FROM articles WHERE author_id = $1`
-
+func (r *articleRepository) CountRevisions(ctx context.Context, articleID int) (int, error) {
 	var count int
-	err := r.db.QueryRow(query, authorID).Scan(&count)
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM article_revisions WHERE article_id = $1`, articleID).Scan(&count)
 	return count, err
 }
+
+func (r *articleRepository) GetRevision(ctx context.Context, articleID, revision int) (*models.ArticleRevision, error) {
+	query := `
+		SELECT ` + revisionColumns + `, r.content
+		FROM article_revisions r
+		LEFT JOIN users u ON r.editor_id = u.id
+		WHERE r.article_id = $1 AND r.revision = $2`
+
+	var content string
+	result, err := scanRevision(r.db.QueryRowContext(ctx, query, articleID, revision), &content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	result.Content = content
+	return result, nil
+}
+
+func (r *articleRepository) queryArticles(query string, args ...interface{}) ([]*models.Article, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []*models.Article
+	for rows.Next() {
+		article, err := scanArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadTags(context.Background(), articles); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"goida/internal/models"
+	"goida/internal/pagination"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	// ListByUser читает page.Fetch() событий в порядке, заданном page.Keyset.
+	ListByUser(ctx context.Context, userID int, page pagination.Params) ([]*models.AuditEvent, error)
+	CountByUser(ctx context.Context, userID int) (int, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %w", err)
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_log (user_id, actor_id, action, details, ip)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at`
+
+	err = r.db.QueryRowContext(ctx, query, event.UserID, event.ActorID, event.Action, details, event.IP).Scan(
+		&event.ID, &event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID int, page pagination.Params) ([]*models.AuditEvent, error) {
+	keyset, order, keyArgs := page.Keyset("created_at", "id", 4)
+	query := `
+		SELECT id, user_id, actor_id, action, details, COALESCE(ip, ''), created_at
+		FROM audit_log
+		WHERE user_id = $1 AND ` + keyset + `
+		ORDER BY ` + order + `
+		LIMIT $2 OFFSET $3`
+
+	args := append([]interface{}{userID, page.Fetch(), page.Skip()}, keyArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		var subjectID, actorID sql.NullInt64
+		var details []byte
+		if err := rows.Scan(&event.ID, &subjectID, &actorID, &event.Action, &details, &event.IP, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if subjectID.Valid {
+			id := int(subjectID.Int64)
+			event.UserID = &id
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			event.ActorID = &id
+		}
+		if err := json.Unmarshal(details, &event.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode audit details: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *auditRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+)
+
+// cachedSessionRepository избавляет AuthMiddleware от обращения к БД на
+// каждый запрос: сессии кешируются на ttl, а время последнего обращения
+// записывается в БД не чаще touchInterval. Отзыв через этот же экземпляр
+// сразу сбрасывает кеш; отзыв, выполненный другим экземпляром приложения,
+// вступает в силу не позже чем через ttl.
+type cachedSessionRepository struct {
+	SessionRepository
+	ttl           time.Duration
+	touchInterval time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*cachedSession
+	lastSweep time.Time
+}
+
+type cachedSession struct {
+	session   models.Session
+	loadedAt  time.Time
+	persisted time.Time
+}
+
+// pendingTouch - время последнего обращения вытесненной из кеша сессии, еще
+// не записанное в БД.
+type pendingTouch struct {
+	id     string
+	ip     string
+	seenAt time.Time
+}
+
+func NewCachedSessionRepository(inner SessionRepository, ttl, touchInterval time.Duration) SessionRepository {
+	return &cachedSessionRepository{
+		SessionRepository: inner,
+		ttl:               ttl,
+		touchInterval:     touchInterval,
+		entries:           make(map[string]*cachedSession),
+		lastSweep:         time.Now(),
+	}
+}
+
+func (r *cachedSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	if entry, ok := r.entries[id]; ok && now.Sub(entry.loadedAt) < r.ttl {
+		session := entry.session
+		r.mu.Unlock()
+		return &session, nil
+	}
+	r.mu.Unlock()
+
+	session, err := r.SessionRepository.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	pending := r.sweep(now)
+
+	entry := &cachedSession{session: *session, loadedAt: now, persisted: session.LastSeenAt}
+	if old, ok := r.entries[id]; ok && old.session.LastSeenAt.After(entry.session.LastSeenAt) {
+		entry.session.LastSeenAt = old.session.LastSeenAt
+		entry.session.IP = old.session.IP
+		entry.persisted = old.persisted
+	}
+	r.entries[id] = entry
+	r.mu.Unlock()
+
+	r.flush(ctx, pending)
+	return session, nil
+}
+
+// ListUserSessions дополняет данные из БД еще не записанным временем
+// последнего обращения.
+func (r *cachedSessionRepository) ListUserSessions(ctx context.Context, userID int, activeSince time.Time) ([]*models.Session, error) {
+	sessions, err := r.SessionRepository.ListUserSessions(ctx, userID, activeSince)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range sessions {
+		if entry, ok := r.entries[session.ID]; ok && entry.session.LastSeenAt.After(session.LastSeenAt) {
+			session.LastSeenAt = entry.session.LastSeenAt
+			session.IP = entry.session.IP
+		}
+	}
+	return sessions, nil
+}
+
+func (r *cachedSessionRepository) TouchSession(ctx context.Context, id string, ip string, seenAt time.Time) error {
+	r.mu.Lock()
+	entry, ok := r.entries[id]
+	if !ok {
+		r.mu.Unlock()
+		return r.SessionRepository.TouchSession(ctx, id, ip, seenAt)
+	}
+
+	entry.session.LastSeenAt = seenAt
+	if ip != "" {
+		entry.session.IP = ip
+	}
+	if seenAt.Sub(entry.persisted) < r.touchInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	entry.persisted = seenAt
+	ip = entry.session.IP
+	r.mu.Unlock()
+
+	return r.SessionRepository.TouchSession(ctx, id, ip, seenAt)
+}
+
+func (r *cachedSessionRepository) RevokeSession(ctx context.Context, id string) error {
+	r.mu.Lock()
+	delete(r.entries, id)
+	r.mu.Unlock()
+
+	return r.SessionRepository.RevokeSession(ctx, id)
+}
+
+func (r *cachedSessionRepository) RevokeUserSessions(ctx context.Context, userID int) error {
+	r.forgetUser(userID, "")
+	return r.SessionRepository.RevokeUserSessions(ctx, userID)
+}
+
+func (r *cachedSessionRepository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	r.forgetUser(userID, keepID)
+	return r.SessionRepository.RevokeOtherSessions(ctx, userID, keepID)
+}
+
+func (r *cachedSessionRepository) forgetUser(userID int, keepID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, entry := range r.entries {
+		if entry.session.UserID == userID && id != keepID {
+			delete(r.entries, id)
+		}
+	}
+}
+
+// sweep удаляет устаревшие записи, чтобы кеш не рос бесконечно, и
+// возвращает не записанное время обращения удаленных сессий - его нужно
+// записать через flush. Вызывается под блокировкой.
+func (r *cachedSessionRepository) sweep(now time.Time) []pendingTouch {
+	if now.Sub(r.lastSweep) < r.ttl {
+		return nil
+	}
+	r.lastSweep = now
+
+	var pending []pendingTouch
+	for id, entry := range r.entries {
+		if now.Sub(entry.loadedAt) >= r.ttl && now.Sub(entry.session.LastSeenAt) >= r.touchInterval {
+			if entry.session.LastSeenAt.After(entry.persisted) {
+				pending = append(pending, pendingTouch{id: id, ip: entry.session.IP, seenAt: entry.session.LastSeenAt})
+			}
+			delete(r.entries, id)
+		}
+	}
+	return pending
+}
+
+// flush записывает время обращения вытесненных сессий. Запрос, во время
+// которого прошла очистка, не должен падать из-за чужих сессий, поэтому
+// ошибки только логируются.
+func (r *cachedSessionRepository) flush(ctx context.Context, pending []pendingTouch) {
+	ctx = context.WithoutCancel(ctx)
+	for _, touch := range pending {
+		if err := r.SessionRepository.TouchSession(ctx, touch.id, touch.ip, touch.seenAt); err != nil {
+			logrus.Warnf("Failed to save last seen time of evicted session: %v", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goida/internal/models"
+)
+
+// fakeSessionRepository - хранилище сессий в памяти; вызов остальных
+// методов паникует на nil-интерфейсе.
+type fakeSessionRepository struct {
+	SessionRepository
+	sessions map[string]*models.Session
+}
+
+func (r *fakeSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	copied := *r.sessions[id]
+	return &copied, nil
+}
+
+func (r *fakeSessionRepository) TouchSession(ctx context.Context, id string, ip string, seenAt time.Time) error {
+	r.sessions[id].LastSeenAt = seenAt
+	r.sessions[id].IP = ip
+	return nil
+}
+
+func TestCachedSessionsSavePendingTouchOnEviction(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	inner := &fakeSessionRepository{sessions: map[string]*models.Session{
+		"a": {ID: "a", LastSeenAt: start},
+		"b": {ID: "b", LastSeenAt: start},
+	}}
+	repo := NewCachedSessionRepository(inner, time.Minute, time.Minute).(*cachedSessionRepository)
+	ctx := context.Background()
+
+	if _, err := repo.GetSession(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	// Обращение раньше touchInterval остается только в кеше.
+	seenAt := start.Add(30 * time.Second)
+	if err := repo.TouchSession(ctx, "a", "10.0.0.1", seenAt); err != nil {
+		t.Fatal(err)
+	}
+	if !inner.sessions["a"].LastSeenAt.Equal(start) {
+		t.Fatalf("touch written before touchInterval")
+	}
+
+	// Запись устарела; очистка при загрузке другой сессии вытесняет ее.
+	repo.entries["a"].loadedAt = start
+	repo.lastSweep = start
+	if _, err := repo.GetSession(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := repo.entries["a"]; ok {
+		t.Fatal("stale entry was not evicted")
+	}
+	if got := inner.sessions["a"]; !got.LastSeenAt.Equal(seenAt) || got.IP != "10.0.0.1" {
+		t.Errorf("evicted session = %+v, want last seen %s from 10.0.0.1", got, seenAt)
+	}
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"goida/internal/models"
+)
+
+type CategoryRepository interface {
+	GetByID(id int) (*models.Category, error)
+	GetBySlug(slug string) (*models.Category, error)
+	// List возвращает категории с числом опубликованных статей.
+	List() ([]*models.Category, error)
+	Create(category *models.Category) error
+	Update(category *models.Category) error
+	// Delete удаляет категорию; ее статьи остаются без категории.
+	Delete(id int) error
+}
+
+type categoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+const categoryColumns = `id, slug, name, description, created_at, updated_at`
+
+func (r *categoryRepository) GetByID(id int) (*models.Category, error) {
+	return r.getOne(`SELECT `+categoryColumns+` FROM categories WHERE id = $1`, id)
+}
+
+func (r *categoryRepository) GetBySlug(slug string) (*models.Category, error) {
+	return r.getOne(`SELECT `+categoryColumns+` FROM categories WHERE slug = $1`, slug)
+}
+
+func (r *categoryRepository) getOne(query string, arg interface{}) (*models.Category, error) {
+	category := &models.Category{}
+	err := r.db.QueryRow(query, arg).Scan(
+		&category.ID, &category.Slug, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("category not found")
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) List() ([]*models.Category, error) {
+	query := `
+		SELECT c.id, c.slug, c.name, c.description, c.created_at, c.updated_at,
+			COUNT(a.id) FILTER (WHERE ` + articlePublished + `)
+		FROM categories c
+		LEFT JOIN articles a ON a.category_id = c.id
+		GROUP BY c.id
+		ORDER BY c.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category := &models.Category{}
+		err := rows.Scan(
+			&category.ID, &category.Slug, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt,
+			&category.ArticleCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *categoryRepository) Create(category *models.Category) error {
+	query := `
+		INSERT INTO categories (slug, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, category.Slug, category.Name, category.Description).
+		Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *categoryRepository) Update(category *models.Category) error {
+	query := `
+		UPDATE categories
+		SET slug = $1, name = $2, description = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query, category.Slug, category.Name, category.Description, category.ID).Scan(&category.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("category not found")
+		}
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *categoryRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
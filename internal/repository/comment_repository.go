@@ -6,22 +6,33 @@ import (
 	"errors"
 
 	"goida/internal/models"
+	"goida/internal/pagination"
 )
 
 type CommentRepository interface {
 	Create(ctx context.Context, c *models.Comment) error
-	FindByArticle(ctx context.Context, articleID int, limit, offset int) ([]*models.Comment, error)
+	// FindByArticle читает page.Fetch() комментариев в порядке, заданном
+	// page.Keyset.
+	FindByArticle(ctx context.Context, articleID int, page pagination.Params) ([]*models.Comment, error)
+	CountByArticle(ctx context.Context, articleID int) (int, error)
+	GetByID(ctx context.Context, id int64) (*models.Comment, error)
 	UpdateOwned(ctx context.Context, id int64, userID int, text string, rating int) error
 	DeleteOwned(ctx context.Context, id int64, userID int) error
+	Delete(ctx context.Context, id int64) error
 	GetArticleRatingStats(ctx context.Context, articleID int) (float64, int, error)
+	// ListByUser возвращает все комментарии и оценки пользователя.
+	ListByUser(ctx context.Context, userID int) ([]*models.Comment, error)
 }
 
 type commentRepository struct {
-	db *sql.DB
+	db                 *sql.DB
+	hideDeletedAuthors bool
 }
 
-func NewCommentRepository(db *sql.DB) CommentRepository {
-	return &commentRepository{db: db}
+// NewCommentRepository - при hideDeletedAuthors комментарии удаленных
+// пользователей не выдаются и не учитываются в рейтинге.
+func NewCommentRepository(db *sql.DB, hideDeletedAuthors bool) CommentRepository {
+	return &commentRepository{db: db, hideDeletedAuthors: hideDeletedAuthors}
 }
 
 func (r *commentRepository) Create(ctx context.Context, c *models.Comment) error {
@@ -29,9 +40,14 @@ func (r *commentRepository) Create(ctx context.Context, c *models.Comment) error
 	return r.db.QueryRowContext(ctx, query, c.ArticleID, c.UserID, c.Text, c.Rating).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
 }
 
-func (r *commentRepository) FindByArticle(ctx context.Context, articleID int, limit, offset int) ([]*models.Comment, error) {
-	query := `SELECT id, article_id, user_id, text, rating, created_at, updated_at FROM comments WHERE article_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-	rows, err := r.db.QueryContext(ctx, query, articleID, limit, offset)
+func (r *commentRepository) FindByArticle(ctx context.Context, articleID int, page pagination.Params) ([]*models.Comment, error) {
+	keyset, order, keyArgs := page.Keyset("c.created_at", "c.id", 5)
+	query := `SELECT c.id, c.article_id, c.user_id, c.text, c.rating, c.created_at, c.updated_at, COALESCE(u.is_deleted, FALSE)
+		FROM comments c LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.article_id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $4) AND ` + keyset + `
+		ORDER BY ` + order + ` LIMIT $2 OFFSET $3`
+	args := append([]interface{}{articleID, page.Fetch(), page.Skip(), r.hideDeletedAuthors}, keyArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -40,12 +56,33 @@ func (r *commentRepository) FindByArticle(ctx context.Context, articleID int, li
 	var items []*models.Comment
 	for rows.Next() {
 		c := &models.Comment{}
-		if err := rows.Scan(&c.ID, &c.ArticleID, &c.UserID, &c.Text, &c.Rating, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.ArticleID, &c.UserID, &c.Text, &c.Rating, &c.CreatedAt, &c.UpdatedAt, &c.UserDeleted); err != nil {
 			return nil, err
 		}
 		items = append(items, c)
 	}
-	return items, nil
+	return items, rows.Err()
+}
+
+func (r *commentRepository) CountByArticle(ctx context.Context, articleID int) (int, error) {
+	query := `SELECT COUNT(*) FROM comments c LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.article_id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, articleID, r.hideDeletedAuthors).Scan(&count)
+	return count, err
+}
+
+func (r *commentRepository) GetByID(ctx context.Context, id int64) (*models.Comment, error) {
+	query := `SELECT id, article_id, user_id, text, rating, created_at, updated_at FROM comments WHERE id = $1`
+	c := &models.Comment{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.ArticleID, &c.UserID, &c.Text, &c.Rating, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("comment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (r *commentRepository) UpdateOwned(ctx context.Context, id int64, userID int, text string, rating int) error {
@@ -79,12 +116,48 @@ func (r *commentRepository) DeleteOwned(ctx context.Context, id int64, userID in
 	return nil
 }
 
+func (r *commentRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("comment not found")
+	}
+	return nil
+}
+
 func (r *commentRepository) GetArticleRatingStats(ctx context.Context, articleID int) (float64, int, error) {
-	query := `SELECT COALESCE(AVG(rating)::float8, 0), COUNT(*) FROM comments WHERE article_id = $1`
+	query := `SELECT COALESCE(AVG(c.rating)::float8, 0), COUNT(*)
+		FROM comments c LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.article_id = $1 AND NOT (COALESCE(u.is_deleted, FALSE) AND $2)`
 	var avg float64
 	var cnt int
-	if err := r.db.QueryRowContext(ctx, query, articleID).Scan(&avg, &cnt); err != nil {
+	if err := r.db.QueryRowContext(ctx, query, articleID, r.hideDeletedAuthors).Scan(&avg, &cnt); err != nil {
 		return 0, 0, err
 	}
 	return avg, cnt, nil
 }
+
+func (r *commentRepository) ListByUser(ctx context.Context, userID int) ([]*models.Comment, error) {
+	query := `SELECT id, article_id, user_id, text, rating, created_at, updated_at FROM comments WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.Comment
+	for rows.Next() {
+		c := &models.Comment{}
+		if err := rows.Scan(&c.ID, &c.ArticleID, &c.UserID, &c.Text, &c.Rating, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, c)
+	}
+	return items, rows.Err()
+}
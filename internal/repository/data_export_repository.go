@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goida/internal/models"
+)
+
+// DataExportRepository - Get, FindPending и GetArchive возвращают nil без
+// ошибки, если выгрузка не найдена.
+type DataExportRepository interface {
+	Create(ctx context.Context, export *models.DataExport) error
+	Get(ctx context.Context, userID int, id int64) (*models.DataExport, error)
+	// FindPending возвращает формируемую выгрузку пользователя, если она есть.
+	FindPending(ctx context.Context, userID int) (*models.DataExport, error)
+	GetArchive(ctx context.Context, userID int, id int64) ([]byte, error)
+	Complete(ctx context.Context, id int64, archive []byte) error
+	Fail(ctx context.Context, id int64, reason string) error
+	// FailStale помечает ошибочными выгрузки, запрошенные до createdBefore и
+	// так и не сформированные (например, из-за перезапуска сервера).
+	FailStale(ctx context.Context, createdBefore time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	// CountUserRecords оценивает объем данных пользователя: число статей,
+	// комментариев, сессий и записей журнала.
+	CountUserRecords(ctx context.Context, userID int) (int, error)
+}
+
+type dataExportRepository struct {
+	db *sql.DB
+}
+
+func NewDataExportRepository(db *sql.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+const dataExportColumns = `id, user_id, requested_by, status, error, size, created_at, completed_at, expires_at`
+
+func (r *dataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	query := `
+		INSERT INTO data_exports (user_id, requested_by, status, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, export.UserID, export.RequestedBy, export.Status, export.ExpiresAt).
+		Scan(&export.ID, &export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) Get(ctx context.Context, userID int, id int64) (*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+	return r.queryOne(ctx, query, id, userID)
+}
+
+func (r *dataExportRepository) FindPending(ctx context.Context, userID int) (*models.DataExport, error) {
+	query := `
+		SELECT ` + dataExportColumns + `
+		FROM data_exports
+		WHERE user_id = $1 AND status = $2
+		ORDER BY created_at DESC
+		LIMIT 1`
+	return r.queryOne(ctx, query, userID, models.DataExportPending)
+}
+
+func (r *dataExportRepository) GetArchive(ctx context.Context, userID int, id int64) ([]byte, error) {
+	var archive []byte
+	query := `SELECT archive FROM data_exports WHERE id = $1 AND user_id = $2 AND status = $3`
+	err := r.db.QueryRowContext(ctx, query, id, userID, models.DataExportReady).Scan(&archive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export archive: %w", err)
+	}
+	return archive, nil
+}
+
+func (r *dataExportRepository) Complete(ctx context.Context, id int64, archive []byte) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, archive = $2, size = $3, completed_at = NOW()
+		WHERE id = $4`
+
+	if _, err := r.db.ExecContext(ctx, query, models.DataExportReady, archive, len(archive), id); err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) Fail(ctx context.Context, id int64, reason string) error {
+	query := `UPDATE data_exports SET status = $1, error = $2, completed_at = NOW() WHERE id = $3`
+	if _, err := r.db.ExecContext(ctx, query, models.DataExportFailed, reason, id); err != nil {
+		return fmt.Errorf("failed to mark data export as failed: %w", err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) FailStale(ctx context.Context, createdBefore time.Time) (int64, error) {
+	query := `
+		UPDATE data_exports
+		SET status = $1, error = 'export generation timed out', completed_at = NOW()
+		WHERE status = $2 AND created_at < $3`
+
+	result, err := r.db.ExecContext(ctx, query, models.DataExportFailed, models.DataExportPending, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark stale data exports: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *dataExportRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM data_exports WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *dataExportRepository) CountUserRecords(ctx context.Context, userID int) (int, error) {
+	query := `
+		SELECT (SELECT COUNT(*) FROM articles WHERE author_id = $1)
+		     + (SELECT COUNT(*) FROM comments WHERE user_id = $1)
+		     + (SELECT COUNT(*) FROM auth_sessions WHERE user_id = $1)
+		     + (SELECT COUNT(*) FROM audit_log WHERE user_id = $1)`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count user records: %w", err)
+	}
+	return count, nil
+}
+
+func (r *dataExportRepository) queryOne(ctx context.Context, query string, args ...interface{}) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	var requestedBy sql.NullInt64
+	var completedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&export.ID, &export.UserID, &requestedBy, &export.Status, &export.Error, &export.Size,
+		&export.CreatedAt, &completedAt, &export.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if requestedBy.Valid {
+		id := int(requestedBy.Int64)
+		export.RequestedBy = &id
+	}
+	export.CompletedAt = nullTimePtr(completedAt)
+	return export, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goida/internal/models"
+)
+
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error)
+	SetLockedUntil(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	ListLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error)
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{}
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`
+
+	err := r.db.QueryRowContext(ctx, query, key).Scan(
+		&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get login attempt: %w", err)
+	}
+	return attempt, nil
+}
+
+// IncrementFailures атомарно увеличивает счетчик; если последняя неудача была
+// раньше окна window, отсчет начинается заново.
+func (r *loginAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at, locked_until)
+		VALUES ($1, 1, NOW(), NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures`
+
+	var failures int
+	if err := r.db.QueryRowContext(ctx, query, key, window.Seconds()).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to register login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (r *loginAttemptRepository) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $1 WHERE key = $2`
+	if _, err := r.db.ExecContext(ctx, query, until, key); err != nil {
+		return fmt.Errorf("failed to lock login attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) ListLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error) {
+	query := `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE locked_until > $1
+		ORDER BY locked_until DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*models.LoginAttempt
+	for rows.Next() {
+		attempt := &models.LoginAttempt{}
+		if err := rows.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"goida/internal/models"
+)
+
+// memoryLoginAttemptRepository хранит счетчики в памяти процесса. Подходит для
+// одного экземпляра приложения; при нескольких экземплярах используйте
+// хранилище в PostgreSQL.
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	attempts  map[string]*models.LoginAttempt
+	window    time.Duration
+	lastSweep time.Time
+}
+
+// memoryLoginSweepInterval - как часто удаляются устаревшие счетчики.
+const memoryLoginSweepInterval = time.Minute
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]*models.LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+func (r *memoryLoginAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.window = window
+	if now.Sub(r.lastSweep) >= memoryLoginSweepInterval {
+		r.sweep(now)
+		r.lastSweep = now
+	}
+
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(now.Add(-window)) {
+		attempt = &models.LoginAttempt{Key: key, LockedUntil: now}
+		r.attempts[key] = attempt
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	return attempt.Failures, nil
+}
+
+func (r *memoryLoginAttemptRepository) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = until
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) ListLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var attempts []*models.LoginAttempt
+	for _, attempt := range r.attempts {
+		if attempt.LockedUntil.After(now) {
+			copied := *attempt
+			attempts = append(attempts, &copied)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].LockedUntil.After(attempts[j].LockedUntil)
+	})
+	return attempts, nil
+}
+
+// sweep удаляет устаревшие счетчики, чтобы перебор случайных логинов не
+// раздувал память. Вызывается под мьютексом.
+func (r *memoryLoginAttemptRepository) sweep(now time.Time) {
+	for key, attempt := range r.attempts {
+		if attempt.LockedUntil.Before(now) && attempt.LastFailureAt.Before(now.Add(-r.window)) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goida/internal/models"
+)
+
+func TestMemoryLoginAttemptsSweepExpiredCounters(t *testing.T) {
+	repo := NewMemoryLoginAttemptRepository().(*memoryLoginAttemptRepository)
+	now := time.Now()
+	repo.attempts["stale"] = &models.LoginAttempt{Key: "stale", LastFailureAt: now.Add(-2 * time.Hour), LockedUntil: now.Add(-time.Hour)}
+	repo.attempts["locked"] = &models.LoginAttempt{Key: "locked", LastFailureAt: now.Add(-2 * time.Hour), LockedUntil: now.Add(time.Hour)}
+	repo.lastSweep = now.Add(-memoryLoginSweepInterval)
+
+	if _, err := repo.IncrementFailures(context.Background(), "fresh", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.attempts["stale"]; ok {
+		t.Error("stale counter survived the sweep")
+	}
+	if _, ok := repo.attempts["locked"]; !ok {
+		t.Error("locked counter was swept")
+	}
+
+	// До следующего интервала счетчики не перебираются.
+	repo.attempts["stale"] = &models.LoginAttempt{Key: "stale", LastFailureAt: now.Add(-2 * time.Hour)}
+	if _, err := repo.IncrementFailures(context.Background(), "fresh", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.attempts["stale"]; !ok {
+		t.Error("sweep ran before the interval elapsed")
+	}
+}
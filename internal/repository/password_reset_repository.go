@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"goida/internal/models"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	Lookup(ctx context.Context, tokenHash string) (int, error)
+	Consume(ctx context.Context, tokenHash string) (int, error)
+	InvalidateForUser(ctx context.Context, userID int) error
+}
+
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(
+		&token.ID, &token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+	return nil
+}
+
+// Lookup возвращает ID пользователя для действующего токена, не помечая его
+// использованным.
+func (r *passwordResetRepository) Lookup(ctx context.Context, tokenHash string) (int, error) {
+	query := `
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	var userID int
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("password reset token not found")
+		}
+		return 0, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	return userID, nil
+}
+
+// Consume помечает действующий токен использованным и возвращает ID
+// пользователя. Проверка и пометка выполняются одним запросом, поэтому токен
+// нельзя использовать дважды даже параллельно.
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string) (int, error) {
+	query := `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+
+	var userID int
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("password reset token not found")
+		}
+		return 0, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *passwordResetRepository) InvalidateForUser(ctx context.Context, userID int) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+	return nil
+}
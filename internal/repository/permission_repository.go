@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type PermissionRepository interface {
+	// ListRolePermissions возвращает права всех ролей, ключ - название роли.
+	ListRolePermissions(ctx context.Context) (map[string][]string, error)
+	ListByRole(ctx context.Context, roleID int) ([]string, error)
+	// SetRolePermissions заменяет права роли целиком.
+	SetRolePermissions(ctx context.Context, roleID int, permissions []string) error
+	// CountUsersWithPermission считает активных (не удаленных, не
+	// заблокированных и не ожидающих удаления) пользователей, чья роль имеет право permission.
+	// Пользователи с ролью exceptRoleID не учитываются (0 - учитываются все).
+	CountUsersWithPermission(ctx context.Context, permission string, exceptRoleID int) (int, error)
+}
+
+type permissionRepository struct {
+	db *sql.DB
+}
+
+func NewPermissionRepository(db *sql.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) ListRolePermissions(ctx context.Context) (map[string][]string, error) {
+	query := `
+		SELECT r.name, rp.permission
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		ORDER BY r.name, rp.permission`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]string)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role permission: %w", err)
+		}
+		result[role] = append(result[role], permission)
+	}
+	return result, rows.Err()
+}
+
+func (r *permissionRepository) ListByRole(ctx context.Context, roleID int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT permission FROM role_permissions WHERE role_id = $1 ORDER BY permission`, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+func (r *permissionRepository) SetRolePermissions(ctx context.Context, roleID int, permissions []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	for _, permission := range permissions {
+		_, err := tx.ExecContext(ctx, `INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`, roleID, permission)
+		if err != nil {
+			return fmt.Errorf("failed to grant permission %s: %w", permission, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *permissionRepository) CountUsersWithPermission(ctx context.Context, permission string, exceptRoleID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM users u
+		JOIN role_permissions rp ON rp.role_id = u.role_id
+		WHERE rp.permission = $1
+		  AND u.role_id <> $2
+		  AND NOT u.is_deleted
+		  AND u.deletion_scheduled_at IS NULL
+		  AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, permission, exceptRoleID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users with permission: %w", err)
+	}
+	return count, nil
+}
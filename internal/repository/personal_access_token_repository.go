@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"goida/internal/models"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+	ListByUser(ctx context.Context, userID int) ([]*models.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID int, id int64) (bool, error)
+	TouchLastUsed(ctx context.Context, id int64, ip string, usedAt time.Time, olderThan time.Time) error
+}
+
+type personalAccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPersonalAccessTokenRepository(db *sql.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	query := `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, token_hint, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID, token.Name, token.TokenHash, token.Hint, pq.Array(token.Scopes), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create personal access token: %w", err)
+	}
+	return nil
+}
+
+func (r *personalAccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	query := `
+		SELECT id, user_id, name, token_hash, token_hint, scopes, expires_at, last_used_at, COALESCE(last_used_ip, ''), created_at, revoked_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1`
+
+	token, err := scanPersonalAccessToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("personal access token not found")
+		}
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *personalAccessTokenRepository) ListByUser(ctx context.Context, userID int) ([]*models.PersonalAccessToken, error) {
+	query := `
+		SELECT id, user_id, name, token_hash, token_hint, scopes, expires_at, last_used_at, COALESCE(last_used_ip, ''), created_at, revoked_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan personal access token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// Revoke отзывает токен пользователя. Возвращает false, если действующего
+// токена с таким id у пользователя нет.
+func (r *personalAccessTokenRepository) Revoke(ctx context.Context, userID int, id int64) (bool, error) {
+	query := `UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// TouchLastUsed записывает время использования, только если предыдущее
+// старше olderThan, чтобы частые запросы скрипта не нагружали БД записью.
+func (r *personalAccessTokenRepository) TouchLastUsed(ctx context.Context, id int64, ip string, usedAt time.Time, olderThan time.Time) error {
+	query := `
+		UPDATE personal_access_tokens
+		SET last_used_at = $2, last_used_ip = NULLIF($3, '')
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $4)`
+
+	if _, err := r.db.ExecContext(ctx, query, id, usedAt, ip, olderThan); err != nil {
+		return fmt.Errorf("failed to update personal access token last use: %w", err)
+	}
+	return nil
+}
+
+func scanPersonalAccessToken(row rowScanner) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	var lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.Hint, pq.Array(&token.Scopes),
+		&token.ExpiresAt, &lastUsedAt, &token.LastUsedIP, &token.CreatedAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
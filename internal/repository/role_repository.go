@@ -11,6 +11,10 @@ type RoleRepository interface {
 	GetByID(id int) (*models.Role, error)
 	GetByName(name string) (*models.Role, error)
 	List() ([]*models.Role, error)
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(id int) error
+	CountUsers(id int) (int, error)
 }
 
 type roleRepository struct {
@@ -87,3 +91,62 @@ func (r *roleRepository) List() ([]*models.Role, error) {
 
 	return roles, nil
 }
+
+func (r *roleRepository) Create(role *models.Role) error {
+	query := `
+		INSERT INTO roles (name, description, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, role.Name, role.Description).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) Update(role *models.Role) error {
+	query := `
+		UPDATE roles
+		SET name = $1, description = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query, role.Name, role.Description, role.ID).Scan(&role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("role not found")
+		}
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
+
+func (r *roleRepository) CountUsers(id int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role_id = $1`, id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count role users: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"goida/internal/models"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	ListUserSessions(ctx context.Context, userID int, activeSince time.Time) ([]*models.Session, error)
+	// ListSessionHistory возвращает все сессии пользователя, включая
+	// отозванные и истекшие.
+	ListSessionHistory(ctx context.Context, userID int) ([]*models.Session, error)
+	TouchSession(ctx context.Context, id string, ip string, seenAt time.Time) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+	RevokeOtherSessions(ctx context.Context, userID int, keepID string) error
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenRotated(ctx context.Context, id int64) (bool, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO auth_sessions (id, user_id, mfa, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, last_seen_at`
+
+	err := r.db.QueryRowContext(ctx, query, session.ID, session.UserID, session.MFA, session.UserAgent, session.IP).Scan(
+		&session.CreatedAt, &session.LastSeenAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, mfa, user_agent, ip, created_at, last_seen_at, revoked_at
+		FROM auth_sessions
+		WHERE id = $1`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// ListUserSessions возвращает неотозванные сессии, которыми пользовались
+// после activeSince; более старые уже не продлить refresh-токеном.
+func (r *sessionRepository) ListUserSessions(ctx context.Context, userID int, activeSince time.Time) ([]*models.Session, error) {
+	query := `
+		SELECT id, user_id, mfa, user_agent, ip, created_at, last_seen_at, revoked_at
+		FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND last_seen_at > $2
+		ORDER BY last_seen_at DESC`
+
+	return r.querySessions(ctx, query, userID, activeSince)
+}
+
+func (r *sessionRepository) ListSessionHistory(ctx context.Context, userID int) ([]*models.Session, error) {
+	query := `
+		SELECT id, user_id, mfa, user_agent, ip, created_at, last_seen_at, revoked_at
+		FROM auth_sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	return r.querySessions(ctx, query, userID)
+}
+
+func (r *sessionRepository) querySessions(ctx context.Context, query string, args ...interface{}) ([]*models.Session, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchSession(ctx context.Context, id string, ip string, seenAt time.Time) error {
+	query := `UPDATE auth_sessions SET last_seen_at = $2, ip = $3 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, seenAt, ip); err != nil {
+		return fmt.Errorf("failed to update session last seen: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID int) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID, keepID); err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, token.SessionID, token.TokenHash, token.ExpiresAt).Scan(
+		&token.ID, &token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	query := `
+		SELECT id, session_id, token_hash, expires_at, rotated_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	var rotatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.SessionID, &token.TokenHash, &token.ExpiresAt, &rotatedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if rotatedAt.Valid {
+		token.RotatedAt = &rotatedAt.Time
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenRotated возвращает false, если токен уже был обменян ранее
+// (в том числе параллельным запросом).
+func (r *sessionRepository) MarkRefreshTokenRotated(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE refresh_tokens SET rotated_at = NOW() WHERE id = $1 AND rotated_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	session := &models.Session{}
+	var lastSeenAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.MFA, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &lastSeenAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastSeenAt.Valid {
+		session.LastSeenAt = lastSeenAt.Time
+	} else {
+		session.LastSeenAt = session.CreatedAt
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
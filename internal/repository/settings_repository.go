@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type SettingsRepository interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+}
+
+type settingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
+
+func (r *settingsRepository) Get(ctx context.Context, key string) (string, error) {
+	var value string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM security_settings WHERE key = $1`, key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("setting not found")
+		}
+		return "", fmt.Errorf("failed to get setting: %w", err)
+	}
+	return value, nil
+}
+
+func (r *settingsRepository) Set(ctx context.Context, key, value string) error {
+	query := `
+		INSERT INTO security_settings (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query, key, value); err != nil {
+		return fmt.Errorf("failed to set setting: %w", err)
+	}
+	return nil
+}
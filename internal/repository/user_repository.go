@@ -3,8 +3,10 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"goida/internal/models"
+	"goida/internal/pagination"
 )
 
 type UserRepository interface {
@@ -14,7 +16,25 @@ type UserRepository interface {
 	GetUserByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id int) error
-	List(limit, offset int) ([]*models.User, error)
+	SetEmailVerified(id int, verified bool) error
+	// ReserveVerificationEmail отмечает отправку письма подтверждения, если
+	// предыдущее ушло не меньше interval назад, иначе возвращает оставшееся
+	// время ожидания. Проверка и отметка выполняются одним запросом.
+	ReserveVerificationEmail(id int, interval time.Duration) (time.Duration, error)
+	// List читает page.Fetch() пользователей в порядке, заданном page.Keyset.
+	List(page pagination.Params) ([]*models.User, error)
+	Count() (int, error)
+	// SetDeleted выполняет мягкое удаление или восстановление.
+	SetDeleted(id int, deleted bool) error
+	Suspend(id int, until *time.Time, reason string) error
+	Unsuspend(id int) error
+	// Deactivate отключает учетную запись по просьбе пользователя; при
+	// deleteAt она будет удалена окончательно в указанный момент.
+	Deactivate(id int, deleteAt *time.Time) error
+	Reactivate(id int) error
+	// PurgeScheduled окончательно удаляет учетные записи, срок удаления
+	// которых наступил к моменту now, и возвращает их id.
+	PurgeScheduled(now time.Time) ([]int, error)
 }
 
 type userRepository struct {
@@ -42,18 +62,9 @@ func (r *userRepository) Create(user *models.User) error {
 }
 
 func (r *userRepository) GetByID(id int) (*models.User, error) {
-	user := &models.User{Role: &models.Role{}}
-	query := `
-		SELECT u.id, u.email, u.name, u.role_id, u.created_at, u.updated_at,
-		       r.id, r.name, r.description, r.created_at, r.updated_at
-		FROM users u
-		LEFT JOIN roles r ON u.role_id = r.id
-		WHERE u.id = $1`
+	query := `SELECT ` + userColumns + ` FROM users u LEFT JOIN roles r ON u.role_id = r.id WHERE u.id = $1`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.RoleID, &user.CreatedAt, &user.UpdatedAt,
-		&user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.CreatedAt, &user.Role.UpdatedAt,
-	)
+	user, err := scanUser(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -65,18 +76,9 @@ func (r *userRepository) GetByID(id int) (*models.User, error) {
 }
 
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
-	user := &models.User{Role: &models.Role{}}
-	query := `
-		SELECT u.id, u.email, u.name, u.role_id, u.created_at, u.updated_at,
-		       r.id, r.name, r.description, r.created_at, r.updated_at
-		FROM users u
-		LEFT JOIN roles r ON u.role_id = r.id
-		WHERE u.email = $1`
+	query := `SELECT ` + userColumns + ` FROM users u LEFT JOIN roles r ON u.role_id = r.id WHERE u.email = $1`
 
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Email, &user.Name, &user.RoleID, &user.CreatedAt, &user.UpdatedAt,
-		&user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.CreatedAt, &user.Role.UpdatedAt,
-	)
+	user, err := scanUser(r.db.QueryRow(query, email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -134,16 +136,138 @@ func (r *userRepository) Delete(id int) error {
 	return nil
 }
 
-func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
+func (r *userRepository) SetEmailVerified(id int, verified bool) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = CASE WHEN $1 THEN COALESCE(email_verified_at, NOW()) ELSE NULL END,
+		    updated_at = NOW()
+		WHERE id = $2`
+
+	result, err := r.db.Exec(query, verified, id)
+	if err != nil {
+		return fmt.Errorf("failed to update email verification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (r *userRepository) ReserveVerificationEmail(id int, interval time.Duration) (time.Duration, error) {
+	query := `
+		UPDATE users
+		SET verification_sent_at = NOW()
+		WHERE id = $1
+		  AND (verification_sent_at IS NULL OR verification_sent_at <= NOW() - make_interval(secs => $2))
+		RETURNING id`
+
+	err := r.db.QueryRow(query, id, interval.Seconds()).Scan(&id)
+	if err == nil {
+		return 0, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to reserve verification email: %w", err)
+	}
+
+	var seconds float64
+	query = `SELECT EXTRACT(EPOCH FROM verification_sent_at + make_interval(secs => $2) - NOW()) FROM users WHERE id = $1`
+	if err := r.db.QueryRow(query, id, interval.Seconds()).Scan(&seconds); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("user not found")
+		}
+		return 0, fmt.Errorf("failed to get verification email time: %w", err)
+	}
+	// Интервал мог истечь между запросами - повторить можно сразу.
+	if seconds <= 0 {
+		return time.Second, nil
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (r *userRepository) SetDeleted(id int, deleted bool) error {
+	query := `
+		UPDATE users
+		SET is_deleted = $1,
+		    deleted_at = CASE WHEN $1 THEN NOW() ELSE NULL END,
+		    updated_at = NOW()
+		WHERE id = $2`
+
+	return r.execForUser(query, deleted, id)
+}
+
+func (r *userRepository) Suspend(id int, until *time.Time, reason string) error {
+	query := `
+		UPDATE users
+		SET suspended_at = NOW(), suspended_until = $1, suspension_reason = $2, updated_at = NOW()
+		WHERE id = $3`
+
+	return r.execForUser(query, until, reason, id)
+}
+
+func (r *userRepository) Unsuspend(id int) error {
+	query := `
+		UPDATE users
+		SET suspended_at = NULL, suspended_until = NULL, suspension_reason = '', updated_at = NOW()
+		WHERE id = $1`
+
+	return r.execForUser(query, id)
+}
+
+func (r *userRepository) Deactivate(id int, deleteAt *time.Time) error {
 	query := `
-		SELECT u.id, u.email, u.name, u.role_id, u.created_at, u.updated_at,
-		       r.id, r.name, r.description, r.created_at, r.updated_at
+		UPDATE users
+		SET deactivated_at = NOW(), deletion_scheduled_at = $1, updated_at = NOW()
+		WHERE id = $2`
+
+	return r.execForUser(query, deleteAt, id)
+}
+
+func (r *userRepository) Reactivate(id int) error {
+	query := `
+		UPDATE users
+		SET deactivated_at = NULL, deletion_scheduled_at = NULL, updated_at = NOW()
+		WHERE id = $1`
+
+	return r.execForUser(query, id)
+}
+
+func (r *userRepository) PurgeScheduled(now time.Time) ([]int, error) {
+	rows, err := r.db.Query(`DELETE FROM users WHERE deletion_scheduled_at <= $1 RETURNING id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan purged user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *userRepository) List(page pagination.Params) ([]*models.User, error) {
+	keyset, order, keyArgs := page.Keyset("u.created_at", "u.id", 3)
+	query := `SELECT ` + userColumns + `
 		FROM users u
 		LEFT JOIN roles r ON u.role_id = r.id
-		ORDER BY u.created_at DESC
+		WHERE ` + keyset + `
+		ORDER BY ` + order + `
 		LIMIT $1 OFFSET $2`
 
-	rows, err := r.db.Query(query, limit, offset)
+	args := append([]interface{}{page.Fetch(), page.Skip()}, keyArgs...)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -151,11 +275,7 @@ func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
 
 	var users []*models.User
 	for rows.Next() {
-		user := &models.User{Role: &models.Role{}}
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Name, &user.RoleID, &user.CreatedAt, &user.UpdatedAt,
-			&user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.CreatedAt, &user.Role.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -164,3 +284,72 @@ func (r *userRepository) List(limit, offset int) ([]*models.User, error) {
 
 	return users, nil
 }
+
+func (r *userRepository) Count() (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+func (r *userRepository) execForUser(query string, args ...interface{}) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+const userColumns = `u.id, u.email, u.name, u.role_id, u.is_deleted, u.deleted_at,
+		u.suspended_at, u.suspended_until, u.suspension_reason,
+		u.deactivated_at, u.deletion_scheduled_at,
+		u.email_verified_at, u.created_at, u.updated_at,
+		r.id, r.name, r.description, r.created_at, r.updated_at`
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{Role: &models.Role{}}
+	var deletedAt, suspendedAt, suspendedUntil, deactivatedAt, deletionScheduledAt, emailVerifiedAt sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Name, &user.RoleID, &user.IsDeleted, &deletedAt,
+		&suspendedAt, &suspendedUntil, &user.SuspensionReason,
+		&deactivatedAt, &deletionScheduledAt,
+		&emailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role.ID, &user.Role.Name, &user.Role.Description, &user.Role.CreatedAt, &user.Role.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.DeletedAt = nullTimePtr(deletedAt)
+	user.SuspendedAt = nullTimePtr(suspendedAt)
+	user.SuspendedUntil = nullTimePtr(suspendedUntil)
+	user.DeactivatedAt = nullTimePtr(deactivatedAt)
+	user.DeletionScheduledAt = nullTimePtr(deletionScheduledAt)
+	setEmailVerified(user, emailVerifiedAt)
+	return user, nil
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func setEmailVerified(user *models.User, emailVerifiedAt sql.NullTime) {
+	if emailVerifiedAt.Valid {
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+}
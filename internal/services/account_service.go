@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+
+// AccountService - самообслуживание учетной записи: профиль, деактивация и
+// удаление с отсрочкой.
+type AccountService interface {
+	UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest, clientIP string) (*models.User, error)
+	Deactivate(ctx context.Context, userID int, req *models.ConfirmPasswordRequest, clientIP string) error
+	ScheduleDeletion(ctx context.Context, userID int, req *models.ConfirmPasswordRequest, clientIP string) (time.Time, error)
+	// Reactivate снимает деактивацию; вызывается при входе. Запланированное
+	// удаление вход не отменяет - для этого есть CancelDeletion.
+	Reactivate(ctx context.Context, user *models.User, clientIP string) error
+	CancelDeletion(ctx context.Context, userID int, clientIP string) error
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+
+type accountService struct {
+	userRepo          repository.UserRepository
+	sessionRepo       repository.SessionRepository
+	permissionRepo    repository.PermissionRepository
+	auditRepo         repository.AuditRepository
+	authorizer        Authorizer
+	credentials       CredentialsService
+	emailVerification EmailVerificationService
+	deletionGrace     time.Duration
+}
+
+func NewAccountService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	permissionRepo repository.PermissionRepository,
+	auditRepo repository.AuditRepository,
+	authorizer Authorizer,
+	credentials CredentialsService,
+	emailVerification EmailVerificationService,
+	deletionGrace time.Duration,
+) AccountService {
+	return &accountService{
+		userRepo:          userRepo,
+		sessionRepo:       sessionRepo,
+		permissionRepo:    permissionRepo,
+		auditRepo:         auditRepo,
+		authorizer:        authorizer,
+		credentials:       credentials,
+		emailVerification: emailVerification,
+		deletionGrace:     deletionGrace,
+	}
+}
+
+// UpdateProfile меняет имя и email. На новый email отправляется письмо
+// подтверждения, до перехода по ссылке адрес считается неподтвержденным.
+func (s *accountService) UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest, clientIP string) (*models.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details, emailChanged, err := updateUserFields(s.userRepo, user, req.Name, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return user, nil
+	}
+	s.authorizer.ForgetUser(userID)
+	s.audit(ctx, userID, models.AuditUserUpdated, details, clientIP)
+
+	user, err = s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if emailChanged {
+		if err := s.emailVerification.SendVerification(ctx, user); err != nil {
+			logrus.Errorf("Failed to send verification email to user %d: %v", userID, err)
+		}
+	}
+	return user, nil
+}
+
+// Deactivate отключает учетную запись и завершает все сессии. Следующий
+// вход активирует ее снова.
+func (s *accountService) Deactivate(ctx context.Context, userID int, req *models.ConfirmPasswordRequest, clientIP string) error {
+	if err := s.prepareDeactivation(ctx, userID, req, clientIP); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Deactivate(userID, nil); err != nil {
+		return err
+	}
+	s.signOut(ctx, userID)
+	s.audit(ctx, userID, models.AuditUserDeactivated, nil, clientIP)
+	return nil
+}
+
+// ScheduleDeletion деактивирует учетную запись и планирует ее окончательное
+// удаление через deletionGrace. До этого момента пользователь может войти и
+// отменить удаление через CancelDeletion.
+func (s *accountService) ScheduleDeletion(ctx context.Context, userID int, req *models.ConfirmPasswordRequest, clientIP string) (time.Time, error) {
+	if err := s.prepareDeactivation(ctx, userID, req, clientIP); err != nil {
+		return time.Time{}, err
+	}
+
+	deleteAt := time.Now().Add(s.deletionGrace)
+	if err := s.userRepo.Deactivate(userID, &deleteAt); err != nil {
+		return time.Time{}, err
+	}
+	s.signOut(ctx, userID)
+	s.audit(ctx, userID, models.AuditDeletionScheduled, map[string]interface{}{
+		"delete_at": deleteAt.UTC().Format(time.RFC3339),
+	}, clientIP)
+	return deleteAt, nil
+}
+
+func (s *accountService) Reactivate(ctx context.Context, user *models.User, clientIP string) error {
+	if !user.IsDeactivated() || user.DeletionScheduledAt != nil {
+		return nil
+	}
+	return s.reactivate(ctx, user, nil, clientIP)
+}
+
+// CancelDeletion отменяет запланированное удаление и снимает деактивацию.
+func (s *accountService) CancelDeletion(ctx context.Context, userID int, clientIP string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+	return s.reactivate(ctx, user, map[string]interface{}{"deletion_cancelled": true}, clientIP)
+}
+
+func (s *accountService) reactivate(ctx context.Context, user *models.User, details map[string]interface{}, clientIP string) error {
+	if err := s.userRepo.Reactivate(user.ID); err != nil {
+		return err
+	}
+	user.DeactivatedAt = nil
+	user.DeletionScheduledAt = nil
+	s.authorizer.ForgetUser(user.ID)
+	s.audit(ctx, user.ID, models.AuditUserReactivated, details, clientIP)
+	return nil
+}
+
+// PurgeDeleted окончательно удаляет учетные записи с истекшим сроком
+// отсрочки вместе со статьями и комментариями. Журнал сохраняется: user_id
+// в нем обнуляется, id удаленного пользователя остается в details.
+func (s *accountService) PurgeDeleted(ctx context.Context) (int, error) {
+	ids, err := s.userRepo.PurgeScheduled(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		s.authorizer.ForgetUser(id)
+		event := &models.AuditEvent{
+			Action:  models.AuditUserPurged,
+			Details: map[string]interface{}{"user_id": id},
+		}
+		if err := s.auditRepo.Create(ctx, event); err != nil {
+			logrus.Errorf("Failed to record audit event %s for user %d: %v", models.AuditUserPurged, id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// prepareDeactivation проверяет пароль и не дает отключить последнего
+// пользователя с правом управления ролями.
+func (s *accountService) prepareDeactivation(ctx context.Context, userID int, req *models.ConfirmPasswordRequest, clientIP string) error {
+	if err := s.credentials.VerifyPassword(ctx, userID, req.Password, clientIP); err != nil {
+		return err
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	last, err := isLastRoleManager(ctx, s.authorizer, s.permissionRepo, user)
+	if err != nil {
+		return err
+	}
+	if last {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *accountService) signOut(ctx context.Context, userID int) {
+	if err := s.sessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+		logrus.Errorf("Failed to revoke sessions of user %d: %v", userID, err)
+	}
+	s.authorizer.ForgetUser(userID)
+}
+
+func (s *accountService) getUser(userID int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *accountService) audit(ctx context.Context, userID int, action string, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &userID,
+		Action:  action,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", action, userID, err)
+	}
+}
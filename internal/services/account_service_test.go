@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goida/internal/models"
+)
+
+type accountUserRepository struct {
+	*fakeUserRepository
+}
+
+func (r accountUserRepository) Reactivate(id int) error {
+	r.users[id].DeactivatedAt = nil
+	r.users[id].DeletionScheduledAt = nil
+	return nil
+}
+
+func newAccountFixture() (AccountService, accountUserRepository, *fakeAuditRepository) {
+	deactivatedAt := time.Now().Add(-time.Hour)
+	deleteAt := time.Now().Add(24 * time.Hour)
+	roles := &fakeRoleRepository{roles: []*models.Role{{ID: 1, Name: "user"}}}
+	users := accountUserRepository{&fakeUserRepository{roles: roles, users: map[int]*models.User{
+		1: {ID: 1, RoleID: 1, DeactivatedAt: &deactivatedAt},
+		2: {ID: 2, RoleID: 1, DeactivatedAt: &deactivatedAt, DeletionScheduledAt: &deleteAt},
+		3: {ID: 3, RoleID: 1},
+	}}}
+	audit := &fakeAuditRepository{}
+	service := NewAccountService(users, nil, nil, audit, roleAuthorizer{}, nil, nil, 30*24*time.Hour)
+	return service, users, audit
+}
+
+func TestReactivateOnLoginKeepsScheduledDeletion(t *testing.T) {
+	service, users, _ := newAccountFixture()
+	ctx := context.Background()
+
+	for _, id := range []int{1, 2} {
+		user, _ := users.GetByID(id)
+		if err := service.Reactivate(ctx, user, ""); err != nil {
+			t.Fatalf("Reactivate(%d): %v", id, err)
+		}
+	}
+	if users.users[1].IsDeactivated() {
+		t.Error("deactivated account was not reactivated")
+	}
+	if users.users[2].DeletionScheduledAt == nil || !users.users[2].IsDeactivated() {
+		t.Error("login cancelled the scheduled deletion")
+	}
+}
+
+func TestCancelDeletion(t *testing.T) {
+	service, users, audit := newAccountFixture()
+	ctx := context.Background()
+
+	if err := service.CancelDeletion(ctx, 2, "10.0.0.1"); err != nil {
+		t.Fatalf("CancelDeletion: %v", err)
+	}
+	if users.users[2].DeletionScheduledAt != nil || users.users[2].IsDeactivated() {
+		t.Errorf("user = %+v, want active without scheduled deletion", users.users[2])
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != models.AuditUserReactivated || audit.events[0].Details["deletion_cancelled"] != true {
+		t.Errorf("audit events = %+v", audit.events)
+	}
+
+	for _, id := range []int{1, 3} {
+		if err := service.CancelDeletion(ctx, id, ""); !errors.Is(err, ErrDeletionNotScheduled) {
+			t.Errorf("user %d: err = %v, want ErrDeletionNotScheduled", id, err)
+		}
+	}
+}
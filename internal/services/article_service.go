@@ -4,45 +4,105 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/markup"
 	"goida/internal/models"
+	"goida/internal/pagination"
 	"goida/internal/repository"
+	"goida/internal/textdiff"
+)
+
+var (
+	ErrArticleNotFound     = errors.New("article not found")
+	ErrArticleAccessDenied = errors.New("access denied")
+	ErrPublishAtInvalid    = errors.New("publish_at must be set in the future for scheduled articles")
+	ErrRevisionNotFound    = errors.New("article revision not found")
+	ErrDiffTooLarge        = errors.New("revisions are too large to compare")
 )
 
+// ArticleService - viewer в методах чтения - авторизованный читатель или nil
+// для анонимного. Неопубликованные статьи видны только автору и ролям с
+// правом article.update.any.
 type ArticleService interface {
 	CreateArticle(req *models.CreateArticleRequest, authorID int) (*models.Article, error)
-	GetArticle(id int) (*models.Article, error)
+	GetArticle(id int, viewer *Claims) (*models.Article, error)
+	// GetArticleBySlug находит статью и по прежнему slug: тогда Slug
+	// результата отличается от запрошенного.
+	GetArticleBySlug(ctx context.Context, slug string, viewer *Claims) (*models.Article, error)
 	UpdateArticle(id int, req *models.UpdateArticleRequest, userID int, userRole string) (*models.Article, error)
 	DeleteArticle(id int, userID int, userRole string) error
-	ListArticles(limit, offset int) ([]*models.Article, error)
-	GetArticlesByAuthor(authorID int, limit, offset int) ([]*models.Article, error)
+	// ListArticles - filter.Tags требует все перечисленные теги сразу.
+	ListArticles(page pagination.Params, filter models.ArticleFilter, viewer *Claims) (pagination.Page[*models.Article], error)
+	GetArticlesByAuthor(authorID int, page pagination.Params, viewer *Claims) (pagination.Page[*models.Article], error)
+	SearchArticles(ctx context.Context, req *models.ArticleSearchRequest, viewer *Claims) (pagination.Page[*models.ArticleSearchResult], error)
 	CanUserModifyArticle(articleID, userID int, userRole string) (bool, error)
+	// ListTags возвращает теги опубликованных статей, самые частые первыми.
+	ListTags(ctx context.Context, page pagination.Params) (pagination.Page[*models.TagUsage], error)
+	// PublishScheduled публикует запланированные статьи, время которых
+	// наступило; вызывается фоновым планировщиком.
+	PublishScheduled(ctx context.Context) (int64, error)
+
+	// Версии статьи доступны тем же, кто может ее редактировать.
+	ListRevisions(ctx context.Context, articleID, userID int, userRole string, page pagination.Params) (pagination.Page[*models.ArticleRevision], error)
+	GetRevision(ctx context.Context, articleID, revision, userID int, userRole string) (*models.ArticleRevision, error)
+	// DiffRevisions сравнивает версии from и to; to = 0 - последняя версия.
+	DiffRevisions(ctx context.Context, articleID, from, to, userID int, userRole string) (*models.ArticleDiff, error)
+	// RestoreRevision возвращает текст и формат старой версии, сохраняя их как
+	// новую версию.
+	RestoreRevision(ctx context.Context, articleID, revision, userID int, userRole string) (*models.Article, error)
 }
 
 type articleService struct {
-	articleRepo repository.ArticleRepository
-	userRepo    repository.UserRepository
-	commentRepo repository.CommentRepository
+	articleRepo  repository.ArticleRepository
+	userRepo     repository.UserRepository
+	commentRepo  repository.CommentRepository
+	categoryRepo repository.CategoryRepository
+	authorizer   Authorizer
 }
 
-func NewArticleService(articleRepo repository.ArticleRepository, userRepo repository.UserRepository, commentRepo repository.CommentRepository) ArticleService {
+func NewArticleService(articleRepo repository.ArticleRepository, userRepo repository.UserRepository, commentRepo repository.CommentRepository, categoryRepo repository.CategoryRepository, authorizer Authorizer) ArticleService {
 	return &articleService{
-		articleRepo: articleRepo,
-		userRepo:    userRepo,
-		commentRepo: commentRepo,
+		articleRepo:  articleRepo,
+		userRepo:     userRepo,
+		commentRepo:  commentRepo,
+		categoryRepo: categoryRepo,
+		authorizer:   authorizer,
 	}
 }
 
 func (s *articleService) CreateArticle(req *models.CreateArticleRequest, authorID int) (*models.Article, error) {
-	_, err := s.userRepo.GetByID(authorID)
+	author, err := s.userRepo.GetByID(authorID)
 	if err != nil {
 		return nil, errors.New("author not found")
 	}
+	if !author.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
 
 	article := &models.Article{
-		Title:    req.Title,
-		Content:  req.Content,
-		AuthorID: authorID,
+		Title:         req.Title,
+		Slug:          articleSlug(req.Title),
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		AuthorID:      authorID,
+		Tags:          normalizeTags(req.Tags),
+	}
+	if article.ContentFormat == "" {
+		article.ContentFormat = models.ContentPlain
+	}
+	renderArticle(article)
+	if err := s.setCategory(article, req.Category); err != nil {
+		return nil, err
+	}
+	status := req.Status
+	if status == "" {
+		status = models.ArticlePublished
+	}
+	if err := setArticleStatus(article, status, req.PublishAt, time.Now()); err != nil {
+		return nil, err
 	}
 
 	err = s.articleRepo.CreateArticle(article)
@@ -53,17 +113,33 @@ func (s *articleService) CreateArticle(req *models.CreateArticleRequest, authorI
 	return article, nil
 }
 
-func (s *articleService) GetArticle(id int) (*models.Article, error) {
+func (s *articleService) GetArticle(id int, viewer *Claims) (*models.Article, error) {
 	article, err := s.articleRepo.GetArticle(id)
 	if err != nil {
 		return nil, err
 	}
-	if s.commentRepo != nil {
-		if avg, cnt, err := s.commentRepo.GetArticleRatingStats(context.Background(), id); err == nil {
-			article.RatingAvg = avg
-			article.RatingCount = cnt
-		}
+	return s.visibleArticle(article, viewer)
+}
+
+func (s *articleService) GetArticleBySlug(ctx context.Context, slug string, viewer *Claims) (*models.Article, error) {
+	article, err := s.articleRepo.GetArticleBySlug(ctx, slug)
+	if err != nil {
+		return nil, ErrArticleNotFound
 	}
+	return s.visibleArticle(article, viewer)
+}
+
+// visibleArticle скрывает от читателя чужие неопубликованные статьи.
+func (s *articleService) visibleArticle(article *models.Article, viewer *Claims) (*models.Article, error) {
+	s.ensureRendered(article)
+	visible, err := canViewArticle(s.authorizer, article, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrArticleNotFound
+	}
+	s.fillRating(article)
 	return article, nil
 }
 
@@ -73,18 +149,44 @@ func (s *articleService) UpdateArticle(id int, req *models.UpdateArticleRequest,
 		return nil, err
 	}
 
-	if userRole != models.RoleAdmin && article.AuthorID != userID {
-		return nil, errors.New("access denied")
+	allowed, err := s.canModify(article, userID, userRole, models.PermArticleUpdateAny)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrArticleAccessDenied
 	}
 
 	if req.Title != "" {
 		article.Title = req.Title
+		article.Slug = articleSlug(req.Title)
 	}
 	if req.Content != "" {
 		article.Content = req.Content
 	}
+	if req.ContentFormat != "" {
+		article.ContentFormat = req.ContentFormat
+	}
+	renderArticle(article)
+	if req.Tags != nil {
+		article.Tags = normalizeTags(*req.Tags)
+	}
+	if req.Category != nil {
+		if err := s.setCategory(article, *req.Category); err != nil {
+			return nil, err
+		}
+	}
+	if req.Status != "" || req.PublishAt != nil {
+		status := req.Status
+		if status == "" {
+			status = article.Status
+		}
+		if err := setArticleStatus(article, status, req.PublishAt, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 
-	err = s.articleRepo.UpdateArticle(id, article)
+	err = s.articleRepo.UpdateArticle(id, article, &models.ArticleRevision{EditorID: &userID})
 	if err != nil {
 		return nil, err
 	}
@@ -98,32 +200,177 @@ func (s *articleService) DeleteArticle(id int, userID int, userRole string) erro
 		return err
 	}
 
-	if userRole != models.RoleAdmin && article.AuthorID != userID {
-		return errors.New("access denied")
+	allowed, err := s.canModify(article, userID, userRole, models.PermArticleDeleteAny)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrArticleAccessDenied
 	}
 
 	return s.articleRepo.DeleteArticle(id)
 }
 
-func (s *articleService) ListArticles(limit, offset int) ([]*models.Article, error) {
-	articles, err := s.articleRepo.ListArticles(limit, offset)
+func (s *articleService) ListArticles(page pagination.Params, filter models.ArticleFilter, viewer *Claims) (pagination.Page[*models.Article], error) {
+	visibility, err := s.visibility(viewer)
 	if err != nil {
-		return nil, err
+		return pagination.Page[*models.Article]{}, err
+	}
+	filter.Tags = normalizeTags(filter.Tags)
+	if filter.Category != "" {
+		filter.Category = slugify(filter.Category, categorySlugMax)
+	}
+	articles, err := s.articleRepo.ListArticles(page, filter, visibility)
+	if err != nil {
+		return pagination.Page[*models.Article]{}, err
+	}
+	total, err := s.articleRepo.CountArticles(filter, visibility)
+	if err != nil {
+		return pagination.Page[*models.Article]{}, err
+	}
+
+	result := pagination.NewPage(articles, page, total, articleFeedKey)
+	s.ensureRendered(result.Items...)
+	for _, a := range result.Items {
+		s.fillRating(a)
+	}
+	return result, nil
+}
+
+// articleFeedKey - ключ сортировки лент статей, как в репозитории: время
+// публикации или создания и id.
+func articleFeedKey(article *models.Article) (time.Time, int64) {
+	if article.PublishAt != nil {
+		return *article.PublishAt, int64(article.ID)
+	}
+	return article.CreatedAt, int64(article.ID)
+}
+
+// searchLanguages - значения lang и конфигурации морфологии PostgreSQL.
+var searchLanguages = map[string]string{
+	"ru": "russian",
+	"en": "english",
+}
+
+func (s *articleService) SearchArticles(ctx context.Context, req *models.ArticleSearchRequest, viewer *Claims) (pagination.Page[*models.ArticleSearchResult], error) {
+	visibility, err := s.visibility(viewer)
+	if err != nil {
+		return pagination.Page[*models.ArticleSearchResult]{}, err
+	}
+
+	search := repository.ArticleSearch{
+		Query:    req.Q,
+		Language: searchLanguages[req.Lang],
+		AuthorID: req.AuthorID,
+		From:     req.From,
+		To:       req.To,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	}
+	results, err := s.articleRepo.SearchArticles(ctx, search, visibility)
+	if err != nil {
+		return pagination.Page[*models.ArticleSearchResult]{}, err
+	}
+	total, err := s.articleRepo.CountSearchArticles(ctx, search, visibility)
+	if err != nil {
+		return pagination.Page[*models.ArticleSearchResult]{}, err
 	}
+	for _, result := range results {
+		s.ensureRendered(&result.Article)
+		s.fillRating(&result.Article)
+	}
+	return pagination.NewOffsetPage(results, pagination.Params{Limit: req.Limit, Offset: req.Offset}, total), nil
+}
+
+func (s *articleService) fillRating(article *models.Article) {
 	if s.commentRepo == nil {
-		return articles, nil
+		return
+	}
+	if avg, cnt, err := s.commentRepo.GetArticleRatingStats(context.Background(), article.ID); err == nil {
+		article.RatingAvg = avg
+		article.RatingCount = cnt
+	}
+}
+
+func (s *articleService) GetArticlesByAuthor(authorID int, page pagination.Params, viewer *Claims) (pagination.Page[*models.Article], error) {
+	visibility, err := s.visibility(viewer)
+	if err != nil {
+		return pagination.Page[*models.Article]{}, err
 	}
-	for _, a := range articles {
-		if avg, cnt, err := s.commentRepo.GetArticleRatingStats(context.Background(), a.ID); err == nil {
-			a.RatingAvg = avg
-			a.RatingCount = cnt
+	articles, err := s.articleRepo.GetArticlesByAuthor(authorID, page, visibility)
+	if err != nil {
+		return pagination.Page[*models.Article]{}, err
+	}
+	total, err := s.articleRepo.CountArticlesByAuthor(authorID, visibility)
+	if err != nil {
+		return pagination.Page[*models.Article]{}, err
+	}
+
+	result := pagination.NewPage(articles, page, total, articleFeedKey)
+	s.ensureRendered(result.Items...)
+	for _, a := range result.Items {
+		s.fillRating(a)
+	}
+	return result, nil
+}
+
+// renderArticle строит HTML, анонс и оглавление статьи по ее тексту.
+func renderArticle(article *models.Article) {
+	doc := markup.Render(article.ContentFormat, article.Content)
+	article.ContentHTML = doc.HTML
+	article.Excerpt = doc.Excerpt
+	article.TOC = make([]models.TOCEntry, len(doc.TOC))
+	for i, heading := range doc.TOC {
+		article.TOC[i] = models.TOCEntry{Level: heading.Level, Text: heading.Text, ID: heading.ID}
+	}
+	article.RenderVersion = markup.Version
+}
+
+// ensureRendered перестраивает отрисовку статей, сохраненную прежней
+// версией правил (или еще не построенную), и сохраняет ее.
+func (s *articleService) ensureRendered(articles ...*models.Article) {
+	for _, article := range articles {
+		if article.RenderVersion == markup.Version {
+			continue
 		}
+		renderArticle(article)
+		if err := s.articleRepo.SaveRendering(context.Background(), article); err != nil {
+			logrus.Warnf("Failed to save rendering of article %d: %v", article.ID, err)
+		}
+	}
+}
+
+func (s *articleService) ListTags(ctx context.Context, page pagination.Params) (pagination.Page[*models.TagUsage], error) {
+	tags, err := s.articleRepo.ListTags(ctx, page.Limit, page.Offset)
+	if err != nil {
+		return pagination.Page[*models.TagUsage]{}, err
 	}
-	return articles, nil
+	total, err := s.articleRepo.CountTags(ctx)
+	if err != nil {
+		return pagination.Page[*models.TagUsage]{}, err
+	}
+	return pagination.NewOffsetPage(tags, page, total), nil
 }
 
-func (s *articleService) GetArticlesByAuthor(authorID int, limit, offset int) ([]*models.Article, error) {
-	return s.articleRepo.GetArticlesByAuthor(authorID, limit, offset)
+// setCategory привязывает статью к категории по slug; пустой slug снимает
+// категорию.
+func (s *articleService) setCategory(article *models.Article, slug string) error {
+	if slug == "" {
+		article.CategoryID = nil
+		article.Category = nil
+		return nil
+	}
+	category, err := s.categoryRepo.GetBySlug(slugify(slug, categorySlugMax))
+	if err != nil {
+		return ErrUnknownCategory
+	}
+	article.CategoryID = &category.ID
+	article.Category = &models.ArticleCategory{ID: category.ID, Slug: category.Slug, Name: category.Name}
+	return nil
+}
+
+func (s *articleService) PublishScheduled(ctx context.Context) (int64, error) {
+	return s.articleRepo.PublishDue(ctx, time.Now())
 }
 
 func (s *articleService) CanUserModifyArticle(articleID, userID int, userRole string) (bool, error) {
@@ -132,9 +379,192 @@ func (s *articleService) CanUserModifyArticle(articleID, userID int, userRole st
 		return false, err
 	}
 
-	if userRole == models.RoleAdmin {
+	return s.canModify(article, userID, userRole, models.PermArticleUpdateAny)
+}
+
+// canModify - автор управляет своей статьей, чужой - только при наличии
+// права permission.
+func (s *articleService) canModify(article *models.Article, userID int, userRole, permission string) (bool, error) {
+	if article.AuthorID == userID {
 		return true, nil
 	}
+	return s.authorizer.Can(context.Background(), userRole, permission)
+}
+
+func (s *articleService) ListRevisions(ctx context.Context, articleID, userID int, userRole string, page pagination.Params) (pagination.Page[*models.ArticleRevision], error) {
+	if _, err := s.editableArticle(articleID, userID, userRole); err != nil {
+		return pagination.Page[*models.ArticleRevision]{}, err
+	}
+	revisions, err := s.articleRepo.ListRevisions(ctx, articleID, page)
+	if err != nil {
+		return pagination.Page[*models.ArticleRevision]{}, err
+	}
+	total, err := s.articleRepo.CountRevisions(ctx, articleID)
+	if err != nil {
+		return pagination.Page[*models.ArticleRevision]{}, err
+	}
+	return pagination.NewPage(revisions, page, total, func(revision *models.ArticleRevision) (time.Time, int64) {
+		return revision.CreatedAt, revision.ID
+	}), nil
+}
+
+func (s *articleService) GetRevision(ctx context.Context, articleID, revision, userID int, userRole string) (*models.ArticleRevision, error) {
+	if _, err := s.editableArticle(articleID, userID, userRole); err != nil {
+		return nil, err
+	}
+	return s.getRevision(ctx, articleID, revision)
+}
 
-	return article.AuthorID == userID, nil
+func (s *articleService) DiffRevisions(ctx context.Context, articleID, from, to, userID int, userRole string) (*models.ArticleDiff, error) {
+	if _, err := s.editableArticle(articleID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		latest, err := s.articleRepo.ListRevisions(ctx, articleID, pagination.Params{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) == 0 {
+			return nil, ErrRevisionNotFound
+		}
+		to = latest[0].Revision
+	}
+
+	oldRevision, err := s.getRevision(ctx, articleID, from)
+	if err != nil {
+		return nil, err
+	}
+	newRevision, err := s.getRevision(ctx, articleID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	edits, err := textdiff.Lines(oldRevision.Content, newRevision.Content)
+	if errors.Is(err, textdiff.ErrTooLarge) {
+		return nil, ErrDiffTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]models.DiffLine, len(edits))
+	for i, edit := range edits {
+		lines[i] = models.DiffLine{Op: string(edit.Op), Text: edit.Text, OldLine: edit.OldLine, NewLine: edit.NewLine}
+	}
+	titleChanged := oldRevision.Title != newRevision.Title
+
+	return &models.ArticleDiff{
+		ArticleID:    articleID,
+		From:         from,
+		To:           to,
+		TitleFrom:    oldRevision.Title,
+		TitleTo:      newRevision.Title,
+		TitleChanged: titleChanged,
+		Changed:      titleChanged || textdiff.Changed(edits),
+		Lines:        lines,
+	}, nil
+}
+
+func (s *articleService) RestoreRevision(ctx context.Context, articleID, revision, userID int, userRole string) (*models.Article, error) {
+	article, err := s.editableArticle(articleID, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.getRevision(ctx, articleID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	article.Title = old.Title
+	article.Slug = articleSlug(old.Title)
+	article.Content = old.Content
+	article.ContentFormat = old.ContentFormat
+	renderArticle(article)
+	err = s.articleRepo.UpdateArticle(articleID, article, &models.ArticleRevision{
+		EditorID:     &userID,
+		RestoredFrom: &old.Revision,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.articleRepo.GetArticle(articleID)
+}
+
+// editableArticle возвращает статью, если пользователь может ее
+// редактировать.
+func (s *articleService) editableArticle(articleID, userID int, userRole string) (*models.Article, error) {
+	article, err := s.articleRepo.GetArticle(articleID)
+	if err != nil {
+		return nil, ErrArticleNotFound
+	}
+
+	allowed, err := s.canModify(article, userID, userRole, models.PermArticleUpdateAny)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrArticleAccessDenied
+	}
+	return article, nil
+}
+
+func (s *articleService) getRevision(ctx context.Context, articleID, revision int) (*models.ArticleRevision, error) {
+	result, err := s.articleRepo.GetRevision(ctx, articleID, revision)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return result, nil
+}
+
+// visibility определяет, какие неопубликованные статьи видит читатель.
+func (s *articleService) visibility(viewer *Claims) (repository.ArticleVisibility, error) {
+	return articleVisibility(s.authorizer, viewer)
+}
+
+func articleVisibility(authorizer Authorizer, viewer *Claims) (repository.ArticleVisibility, error) {
+	if viewer == nil {
+		return repository.ArticleVisibility{}, nil
+	}
+	all, err := authorizer.Can(context.Background(), viewer.Role, models.PermArticleUpdateAny)
+	if err != nil {
+		return repository.ArticleVisibility{}, err
+	}
+	return repository.ArticleVisibility{AuthorID: viewer.UserID, All: all}, nil
+}
+
+// canViewArticle - опубликованную статью видят все, остальные - только
+// автор и роли с правом article.update.any.
+func canViewArticle(authorizer Authorizer, article *models.Article, viewer *Claims) (bool, error) {
+	if article.IsPublished(time.Now()) {
+		return true, nil
+	}
+	visibility, err := articleVisibility(authorizer, viewer)
+	if err != nil {
+		return false, err
+	}
+	return visibility.All || article.AuthorID == visibility.AuthorID, nil
+}
+
+// setArticleStatus переводит статью в состояние status. publish_at хранит
+// запланированное время для scheduled и фактическое - для published.
+func setArticleStatus(article *models.Article, status string, publishAt *time.Time, now time.Time) error {
+	switch status {
+	case models.ArticleDraft:
+		article.PublishAt = nil
+	case models.ArticleScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return ErrPublishAtInvalid
+		}
+		article.PublishAt = publishAt
+	case models.ArticlePublished:
+		if article.PublishAt == nil || article.PublishAt.After(now) {
+			article.PublishAt = &now
+		}
+	}
+	article.Status = status
+	return nil
 }
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+)
+
+// articleSlugMax - длина slug без числового суффикса, который добавляет
+// репозиторий при совпадении.
+const articleSlugMax = 100
+
+// cyrillicToLatin - транслитерация строчных русских букв; та же таблица
+// используется при заполнении slug в миграции articles/007.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// articleSlug строит slug статьи из заголовка: кириллица
+// транслитерируется, остаются латинские буквы и цифры, остальное
+// схлопывается в дефис. Пустой результат заменяется на "article".
+func articleSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			write(string(r))
+		default:
+			if latin, ok := cyrillicToLatin[r]; ok {
+				write(latin)
+			} else {
+				dash = true
+			}
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > articleSlugMax {
+		slug = strings.TrimRight(slug[:articleSlugMax], "-")
+	}
+	if slug == "" {
+		return "article"
+	}
+	return slug
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestArticleSlug(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"Привет, мир", "privet-mir"},
+		{"Съешь же ещё этих мягких булок", "sesh-zhe-eshchyo-etikh-myagkikh-bulok"},
+		{"Щука и ёж: Цапля, Юла, Яхта, Чай, Шар, Хлеб, Жук, Йод", "shchuka-i-yozh-tsaplya-yula-yakhta-chay-shar-khleb-zhuk-yod"},
+		{"  --Go 1.23 -- релиз--  ", "go-1-23-reliz"},
+		{"Ъ Ь", "article"},
+		{"Café über", "caf-ber"},
+		{"!!!", "article"},
+		{"", "article"},
+	}
+	for _, tt := range tests {
+		if got := articleSlug(tt.title); got != tt.want {
+			t.Errorf("articleSlug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestArticleSlugTruncation(t *testing.T) {
+	if got := articleSlug(strings.Repeat("a", 150)); got != strings.Repeat("a", articleSlugMax) {
+		t.Errorf("long title: len = %d, want %d", len(got), articleSlugMax)
+	}
+	// Обрезка приходится на дефис, он отбрасывается.
+	if got, want := articleSlug(strings.Repeat("a", 99)+" bcd"), strings.Repeat("a", 99); got != want {
+		t.Errorf("cut at dash: got %q, want %q", got, want)
+	}
+	// Многобуквенная транслитерация может быть разрезана посередине, как и в
+	// миграции.
+	if got, want := articleSlug(strings.Repeat("a", 99)+"щ"), strings.Repeat("a", 99)+"s"; got != want {
+		t.Errorf("cut inside transliteration: got %q, want %q", got, want)
+	}
+}
+
+// migrationSlug повторяет выражение из миграции articles/007, беря таблицы
+// replace и translate из самого файла миграции.
+func migrationSlug(t *testing.T, title string) string {
+	t.Helper()
+	sql, err := os.ReadFile("../../migrations/articles/007-add-article-slugs.sql")
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+
+	replaces := regexp.MustCompile(`'(\p{Cyrillic})', '([a-z]*)'\)`).FindAllStringSubmatch(string(sql), -1)
+	translate := regexp.MustCompile(`'(\p{Cyrillic}{2,})', '([a-z]+)'\)`).FindStringSubmatch(string(sql))
+	if translate == nil {
+		t.Fatal("translate table not found in migration")
+	}
+	if len(replaces)+len([]rune(translate[1])) != len(cyrillicToLatin) {
+		t.Fatal("migration tables do not cover the alphabet")
+	}
+
+	s := strings.ToLower(title)
+	for _, r := range replaces {
+		s = strings.ReplaceAll(s, r[1], r[2])
+	}
+	from, to := []rune(translate[1]), []rune(translate[2])
+	s = strings.Map(func(r rune) rune {
+		for i, c := range from {
+			if c == r {
+				return to[i]
+			}
+		}
+		return r
+	}, s)
+	s = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(s, "-"), "-")
+	if len(s) > articleSlugMax {
+		s = s[:articleSlugMax]
+	}
+	if s = strings.TrimRight(s, "-"); s == "" {
+		return "article"
+	}
+	return s
+}
+
+func TestArticleSlugMatchesMigration(t *testing.T) {
+	alphabet := "абвгдеёжзийклмнопрстуфхцчшщъыьэюя"
+	titles := []string{
+		alphabet,
+		strings.ToUpper(alphabet),
+		"Привет, мир",
+		"  --Go 1.23 -- релиз--  ",
+		"Café über",
+		"Ъ Ь",
+		strings.Repeat("a", 99) + "щ",
+		strings.Repeat("я ", 80),
+	}
+	for _, r := range alphabet {
+		titles = append(titles, "x"+string(r)+"x")
+	}
+	for _, title := range titles {
+		if got, want := articleSlug(title), migrationSlug(t, title); got != want {
+			t.Errorf("articleSlug(%q) = %q, migration gives %q", title, got, want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"Go Lang", tagSlugMax, "go-lang"},
+		{"  C++ / C#  ", tagSlugMax, "c++-c#"},
+		{"Новости Науки", categorySlugMax, "новости-науки"},
+		{"Ёлка_2024", categorySlugMax, "ёлка-2024"},
+		{"---", categorySlugMax, ""},
+		{"абв где", 4, "абв"},
+		{"абвгд", 3, "абв"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.value, tt.max); got != tt.want {
+			t.Errorf("slugify(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
@@ -1,88 +1,657 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sirupsen/logrus"
 
+	"goida/internal/config"
+	"goida/internal/jwtkeys"
 	"goida/internal/models"
+	"goida/internal/pagination"
+	"goida/internal/password"
 	"goida/internal/repository"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrInvalidChallenge    = errors.New("invalid two-factor challenge")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// Типы токенов (claim typ), подписанных ключами приложения: токен одного
+// назначения не принимается вместо другого.
+const (
+	accessTokenType        = "access"
+	twoFactorChallengeType = "2fa_challenge"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	// maxChallengeFailures - сколько неверных кодов допускает один
+	// challenge-токен.
+	maxChallengeFailures = 5
+	maxUserAgentLength   = 512
+)
+
 type AuthService struct {
 	userRepo            repository.UserRepository
 	authCredentialsRepo repository.AuthCredentialsRepository
-	jwtSecret           string
+	sessionRepo         repository.SessionRepository
+	loginLimiter        *LoginLimiter
+	twoFactor           TwoFactorService
+	authorizer          Authorizer
+	accounts            AccountService
+	hasher              *password.Hasher
+	keys                *jwtkeys.KeySet
+	challenges          *challengeTracker
+	audience            string
+	accessTokenTTL      time.Duration
+	refreshTokenTTL     time.Duration
 }
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+	Type      string `json:"typ"`
+	MFA       bool   `json:"mfa,omitempty"`
+	// ActorID - сотрудник, действующий от имени UserID по токену
+	// имперсонации. Сессия в SessionID принадлежит ему.
+	ActorID int `json:"actor_id,omitempty"`
+	jwt.RegisteredClaims
+
+	// TokenID и Scopes заполняются, только если запрос авторизован
+	// персональным токеном доступа; в JWT они не попадают.
+	TokenID int64    `json:"-"`
+	Scopes  []string `json:"-"`
+}
+
+// IsPersonalToken сообщает, что запрос авторизован персональным токеном, а
+// не сессией.
+func (c *Claims) IsPersonalToken() bool {
+	return c.TokenID != 0
+}
+
+// IsImpersonated сообщает, что запрос выполняет сотрудник от имени
+// пользователя.
+func (c *Claims) IsImpersonated() bool {
+	return c.ActorID != 0
+}
+
+// HasScope проверяет область доступа. Сессии имеют все области.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.IsPersonalToken() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type challengeClaims struct {
+	Type string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo repository.UserRepository, authCredentialsRepo repository.AuthCredentialsRepository, jwtSecret string) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, authCredentialsRepo repository.AuthCredentialsRepository, sessionRepo repository.SessionRepository, loginLimiter *LoginLimiter, twoFactor TwoFactorService, authorizer Authorizer, accounts AccountService, hasher *password.Hasher, keys *jwtkeys.KeySet, cfg config.AuthConfig) *AuthService {
 	return &AuthService{
 		userRepo:            userRepo,
 		authCredentialsRepo: authCredentialsRepo,
-		jwtSecret:           jwtSecret,
+		sessionRepo:         sessionRepo,
+		loginLimiter:        loginLimiter,
+		twoFactor:           twoFactor,
+		authorizer:          authorizer,
+		accounts:            accounts,
+		hasher:              hasher,
+		keys:                keys,
+		challenges:          newChallengeTracker(),
+		audience:            cfg.TokenAudience,
+		accessTokenTTL:      cfg.AccessTokenTTL,
+		refreshTokenTTL:     cfg.RefreshTokenTTL,
 	}
 }
 
-func (s *AuthService) Authenticate(login, password string) (*models.User, error) {
-	credentials, err := s.authCredentialsRepo.GetByLogin(login)
-	if err != nil {
-		return nil, errors.New("invalid credentials")
+// Authenticate проверяет логин и пароль с учетом ограничения числа попыток.
+// Если попытки для логина или адреса клиента временно заблокированы,
+// возвращается *TooManyAttemptsError, а пароль даже не проверяется.
+func (s *AuthService) Authenticate(ctx context.Context, login, password, clientIP string) (*models.User, error) {
+	if err := s.loginLimiter.Check(ctx, login, clientIP); err != nil {
+		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(credentials.UserID)
+	user, err := s.checkPassword(login, password)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		if limitErr := s.loginLimiter.RegisterFailure(ctx, login, clientIP); limitErr != nil {
+			logrus.Errorf("Failed to register login failure: %v", limitErr)
+		}
+		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(credentials.Password), []byte(password))
+	// При включенной 2FA счетчик сбрасывается только после второго фактора:
+	// иначе, зная пароль, можно обнулять его новым входом и перебирать коды.
+	twoFactorEnabled, err := s.twoFactor.IsEnabled(ctx, user.ID)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
+	}
+	if !twoFactorEnabled {
+		if err := s.loginLimiter.RegisterSuccess(ctx, login); err != nil {
+			logrus.Errorf("Failed to reset login attempts: %v", err)
+		}
 	}
 
+	// О блокировке сообщается только после проверки пароля.
+	if !user.CanSignIn(time.Now()) {
+		return nil, ErrAccountDisabled
+	}
 	return user, nil
 }
 
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
+// StartSession открывает новую сессию и выдает пару access/refresh токенов.
+func (s *AuthService) StartSession(ctx context.Context, user *models.User, client models.ClientInfo) (*models.AuthResponse, error) {
+	return s.startSession(ctx, user, false, client)
+}
+
+// StartFederatedSession открывает сессию после входа через внешнего
+// провайдера. mfa - провайдер подтвердил вход вторым фактором.
+func (s *AuthService) StartFederatedSession(ctx context.Context, user *models.User, mfa bool, client models.ClientInfo) (*models.AuthResponse, error) {
+	return s.startSession(ctx, user, mfa, client)
+}
+
+func (s *AuthService) TwoFactorEnabled(ctx context.Context, user *models.User) (bool, error) {
+	return s.twoFactor.IsEnabled(ctx, user.ID)
+}
+
+// Permissions возвращает права роли, чтобы клиент мог показать доступные
+// действия.
+func (s *AuthService) Permissions(ctx context.Context, role string) ([]string, error) {
+	return s.authorizer.Permissions(ctx, role)
+}
+
+func (s *AuthService) TwoFactorRequiredForRole(ctx context.Context, role string) (bool, error) {
+	return s.twoFactor.RequiredForRole(ctx, role)
+}
+
+// NewTwoFactorChallenge выдает короткоживущий токен, подтверждающий, что
+// пароль уже проверен. Сессия создается только после ввода второго фактора.
+func (s *AuthService) NewTwoFactorChallenge(user *models.User) (*models.TwoFactorChallengeResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(twoFactorChallengeTTL)
+
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge id: %w", err)
+	}
+
+	// Challenge-токен предназначен только самому API: аудитория - издатель.
+	registered := s.keys.Claims(s.keys.Issuer(), strconv.Itoa(user.ID), now, twoFactorChallengeTTL)
+	registered.ID = id
+	token, err := s.keys.Sign(challengeClaims{
+		Type:             twoFactorChallengeType,
+		RegisteredClaims: registered,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign challenge: %w", err)
+	}
+
+	return &models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// CompleteTwoFactor обменивает challenge-токен и код (TOTP или код
+// восстановления) на сессию. Неверные коды учитываются тем же ограничителем,
+// что и неверные пароли, а challenge-токен одноразовый и перестает
+// действовать после maxChallengeFailures неверных кодов.
+func (s *AuthService) CompleteTwoFactor(ctx context.Context, challengeToken, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	claims := &challengeClaims{}
+	err := s.keys.Parse(challengeToken, claims, s.keys.Issuer())
+	if err != nil || claims.Type != twoFactorChallengeType || claims.ID == "" {
+		return nil, ErrInvalidChallenge
+	}
+	expiresAt := claims.ExpiresAt.Time
+	if !s.challenges.open(claims.ID, expiresAt) {
+		return nil, ErrInvalidChallenge
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	credentials, err := s.authCredentialsRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.loginLimiter.Check(ctx, credentials.Login, client.IP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	// Учетную запись могли заблокировать после проверки пароля.
+	if !user.CanSignIn(time.Now()) {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := s.twoFactor.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.challenges.fail(claims.ID, expiresAt)
+			if limitErr := s.loginLimiter.RegisterFailure(ctx, credentials.Login, client.IP); limitErr != nil {
+				logrus.Errorf("Failed to register login failure: %v", limitErr)
+			}
+		}
+		return nil, err
+	}
+	if !s.challenges.close(claims.ID, expiresAt) {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.loginLimiter.RegisterSuccess(ctx, credentials.Login); err != nil {
+		logrus.Errorf("Failed to reset login attempts: %v", err)
+	}
+	return s.startSession(ctx, user, true, client)
+}
+
+// challengeTracker считает неверные коды по идентификатору (jti)
+// challenge-токена и помнит использованные токены до истечения их срока.
+// Состояние хранится в памяти экземпляра; общий для всех экземпляров предел
+// задает счетчик логина в LoginLimiter.
+type challengeTracker struct {
+	mu         sync.Mutex
+	challenges map[string]*challengeState
+}
+
+type challengeState struct {
+	failures  int
+	closed    bool
+	expiresAt time.Time
+}
+
+func newChallengeTracker() *challengeTracker {
+	return &challengeTracker{challenges: make(map[string]*challengeState)}
+}
+
+// open сообщает, можно ли еще проверять код по токену.
+func (t *challengeTracker) open(id string, expiresAt time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.state(id, expiresAt)
+	return !state.closed
+}
+
+// fail учитывает неверный код и закрывает токен после
+// maxChallengeFailures неудач.
+func (t *challengeTracker) fail(id string, expiresAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.state(id, expiresAt)
+	state.failures++
+	if state.failures >= maxChallengeFailures {
+		state.closed = true
+	}
+}
+
+// close закрывает токен после успешного входа; false - токен уже закрыт
+// (например, параллельным запросом).
+func (t *challengeTracker) close(id string, expiresAt time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.state(id, expiresAt)
+	if state.closed {
+		return false
+	}
+	state.closed = true
+	return true
+}
+
+// state возвращает запись токена, удаляя записи истекших токенов.
+// Вызывается под мьютексом.
+func (t *challengeTracker) state(id string, expiresAt time.Time) *challengeState {
+	now := time.Now()
+	for key, state := range t.challenges {
+		if now.After(state.expiresAt) {
+			delete(t.challenges, key)
+		}
+	}
+	state, ok := t.challenges[id]
+	if !ok {
+		state = &challengeState{expiresAt: expiresAt}
+		t.challenges[id] = state
+	}
+	return state
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Повторное
+// предъявление уже обменянного токена считается утечкой: вся сессия отзывается.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken, clientIP string) (*models.AuthResponse, error) {
+	token, err := s.sessionRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, token.SessionID)
+	if err != nil || session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.RotatedAt != nil {
+		return nil, s.revokeReusedSession(ctx, session)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := s.sessionRepo.MarkRefreshTokenRotated(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.revokeReusedSession(ctx, session)
+	}
+
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !user.CanSignIn(time.Now()) {
+		if err := s.sessionRepo.RevokeSession(ctx, session.ID); err != nil {
+			logrus.Errorf("Failed to revoke session of disabled user %d: %v", user.ID, err)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	s.touchSession(ctx, session.ID, clientIP)
+	return s.issueTokens(ctx, user, session)
+}
+
+func (s *AuthService) Logout(ctx context.Context, sessionID string) error {
+	return s.sessionRepo.RevokeSession(ctx, sessionID)
+}
+
+// ValidateSession проверяет, что сессия, указанная в токене, не отозвана, и
+// отмечает обращение. Сессии кешируются репозиторием, поэтому БД не
+// запрашивается на каждый запрос.
+func (s *AuthService) ValidateSession(ctx context.Context, claims *Claims, clientIP string) error {
+	session, err := s.sessionRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		return ErrSessionRevoked
+	}
+	owner := claims.UserID
+	if claims.IsImpersonated() {
+		owner = claims.ActorID
+	}
+	if session.RevokedAt != nil || session.UserID != owner {
+		return ErrSessionRevoked
+	}
+
+	// Токен имперсонации действует, пока у сотрудника есть право на нее.
+	if claims.IsImpersonated() {
+		if err := s.checkImpersonator(ctx, claims.ActorID); err != nil {
+			return err
+		}
+	}
+
+	// Роль в токене могла измениться после его выдачи: права проверяются
+	// по текущей роли.
+	role, err := s.authorizer.UserRole(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			return err
+		}
+		return ErrSessionRevoked
+	}
+	claims.Role = role
+
+	s.touchSession(ctx, session.ID, clientIP)
+	return nil
+}
+
+func (s *AuthService) checkImpersonator(ctx context.Context, actorID int) error {
+	role, err := s.authorizer.UserRole(ctx, actorID)
+	if err != nil {
+		return ErrSessionRevoked
+	}
+	allowed, err := s.authorizer.Can(ctx, role, models.PermUserImpersonate)
+	if err != nil || !allowed {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя; текущая помечается
+// флагом Current. Сессии, не использовавшиеся дольше срока жизни
+// refresh-токена, не показываются: продлить их уже нельзя. Сессий у
+// пользователя немного, поэтому страница вырезается из полного списка.
+func (s *AuthService) ListSessions(ctx context.Context, claims *Claims, page pagination.Params) (pagination.Page[*models.Session], error) {
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, claims.UserID, time.Now().Add(-s.refreshTokenTTL))
+	if err != nil {
+		return pagination.Page[*models.Session]{}, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == claims.SessionID
+	}
+	return pagination.SlicePage(sessions, page), nil
+}
+
+// RevokeSession завершает одну из сессий пользователя. Чужие и уже
+// отозванные сессии неотличимы от несуществующих.
+func (s *AuthService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	return s.sessionRepo.RevokeSession(ctx, sessionID)
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, claims *Claims) error {
+	return s.sessionRepo.RevokeOtherSessions(ctx, claims.UserID, claims.SessionID)
+}
+
+func (s *AuthService) GenerateToken(user *models.User, session *models.Session) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.accessTokenTTL)
+
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role.Name,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		UserID:           user.ID,
+		Email:            user.Email,
+		Role:             user.Role.Name,
+		SessionID:        session.ID,
+		Type:             accessTokenType,
+		MFA:              session.MFA,
+		RegisteredClaims: s.keys.Claims(s.audience, strconv.Itoa(user.ID), now, s.accessTokenTTL),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	signed, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
+	claims := &Claims{}
+	if err := s.keys.Parse(tokenString, claims, s.audience); err != nil {
+		return nil, err
+	}
+	if claims.Type != accessTokenType || claims.SessionID == "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func (s *AuthService) HashPassword(password string) (string, error) {
+	return s.hasher.Hash(password)
+}
+
+func (s *AuthService) startSession(ctx context.Context, user *models.User, mfa bool, client models.ClientInfo) (*models.AuthResponse, error) {
+	if !user.CanSignIn(time.Now()) {
+		return nil, ErrAccountDisabled
+	}
+	if err := s.accounts.Reactivate(ctx, user, client.IP); err != nil {
+		return nil, fmt.Errorf("failed to reactivate account: %w", err)
+	}
+
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	session := &models.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		MFA:       mfa,
+		UserAgent: truncate(client.UserAgent, maxUserAgentLength),
+		IP:        client.IP,
+	}
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, session)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, session *models.Session) (*models.AuthResponse, error) {
+	accessToken, expiresAt, err := s.GenerateToken(user, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	err = s.sessionRepo.CreateRefreshToken(ctx, &models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	permissions, err := s.authorizer.Permissions(ctx, user.Role.Name)
 	if err != nil {
 		return nil, err
 	}
+	user.Role.Permissions = permissions
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	return &models.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		User:         *user,
+	}, nil
+}
+
+func (s *AuthService) checkPassword(login, password string) (*models.User, error) {
+	credentials, err := s.authCredentialsRepo.GetByLogin(login)
+	if err != nil {
+		return nil, ErrInvalidCredentials
 	}
 
-	return nil, errors.New("invalid token")
+	user, err := s.userRepo.GetByID(credentials.UserID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	ok, err := s.hasher.Verify(password, credentials.Password)
+	if err != nil {
+		logrus.Errorf("Failed to verify password for user %d: %v", credentials.UserID, err)
+		return nil, ErrInvalidCredentials
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if s.hasher.NeedsRehash(credentials.Password) {
+		s.rehashPassword(credentials, password)
+	}
+
+	return user, nil
 }
 
-func (s *AuthService) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// rehashPassword пересчитывает устаревший хеш, пока открытый пароль известен.
+// Ошибка не мешает входу: хеш обновится при следующем успешном входе.
+func (s *AuthService) rehashPassword(credentials *models.AuthCredentials, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		logrus.Errorf("Failed to rehash password for user %d: %v", credentials.UserID, err)
+		return
+	}
+
+	credentials.Password = hashedPassword
+	if err := s.authCredentialsRepo.Update(credentials.UserID, credentials); err != nil {
+		logrus.Errorf("Failed to store rehashed password for user %d: %v", credentials.UserID, err)
+		return
+	}
+	logrus.Infof("Password hash upgraded for user %d", credentials.UserID)
+}
+
+// touchSession обновляет время последнего обращения. Ошибка не должна
+// прерывать запрос, поэтому только логируется.
+func (s *AuthService) touchSession(ctx context.Context, sessionID, clientIP string) {
+	if err := s.sessionRepo.TouchSession(ctx, sessionID, clientIP, time.Now()); err != nil {
+		logrus.Errorf("Failed to update session %s last seen: %v", sessionID, err)
+	}
+}
+
+func (s *AuthService) revokeReusedSession(ctx context.Context, session *models.Session) error {
+	logrus.Warnf("Refresh token reuse detected for session %s (user %d), revoking session", session.ID, session.UserID)
+	if err := s.sessionRepo.RevokeSession(ctx, session.ID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	// Обрезаем по границе символа, чтобы не оставить половину UTF-8
+	// последовательности: Postgres не примет такую строку.
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
 }
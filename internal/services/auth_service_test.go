@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"goida/internal/config"
+	"goida/internal/jwtkeys"
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+func newTokenFixture(t *testing.T, audience string) (*AuthService, *jwtkeys.KeySet) {
+	t.Helper()
+	keys, err := jwtkeys.NewEphemeral("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, keys, config.AuthConfig{
+		AccessTokenTTL: 15 * time.Minute,
+		TokenAudience:  audience,
+	})
+	return service, keys
+}
+
+func TestValidateTokenAcceptsAccessToken(t *testing.T) {
+	service, _ := newTokenFixture(t, "goida-api")
+	user := &models.User{ID: 7, Email: "ivan@example.com", Role: &models.Role{Name: "user"}}
+
+	token, _, err := service.GenerateToken(user, &models.Session{ID: "s-1"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := service.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.UserID != 7 || claims.SessionID != "s-1" || claims.Issuer != "http://localhost:8080" || claims.Subject != "7" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestValidateTokenRejectsForeignAudience(t *testing.T) {
+	service, keys := newTokenFixture(t, "goida-api")
+	other := NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, keys, config.AuthConfig{
+		AccessTokenTTL: 15 * time.Minute,
+		TokenAudience:  "billing",
+	})
+	user := &models.User{ID: 7, Role: &models.Role{Name: "user"}}
+
+	token, _, err := other.GenerateToken(user, &models.Session{ID: "s-1"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := service.ValidateToken(token); err == nil {
+		t.Error("token for another audience was accepted")
+	}
+}
+
+// Служебные токены подписаны теми же ключами, но не должны приниматься
+// вместо access-токена, и наоборот.
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	service, _ := newTokenFixture(t, "goida-api")
+	user := &models.User{ID: 7, Role: &models.Role{Name: "user"}}
+
+	challenge, err := service.NewTwoFactorChallenge(user)
+	if err != nil {
+		t.Fatalf("NewTwoFactorChallenge: %v", err)
+	}
+	if _, err := service.ValidateToken(challenge.ChallengeToken); err == nil {
+		t.Error("2FA challenge accepted as access token")
+	}
+
+	access, _, err := service.GenerateToken(user, &models.Session{ID: "s-1"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	_, err = service.CompleteTwoFactor(context.Background(), access, "000000", models.ClientInfo{})
+	if !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("access token as challenge: err = %v, want ErrInvalidChallenge", err)
+	}
+}
+
+type fakeAuthCredentialsRepository struct {
+	repository.AuthCredentialsRepository
+	credentials map[int]*models.AuthCredentials
+}
+
+func (r *fakeAuthCredentialsRepository) GetByUserID(userID int) (*models.AuthCredentials, error) {
+	return r.credentials[userID], nil
+}
+
+// fakeTwoFactor принимает единственный код validCode.
+type fakeTwoFactor struct {
+	TwoFactorService
+	validCode string
+}
+
+func (f fakeTwoFactor) Verify(ctx context.Context, userID int, code string) error {
+	if code != f.validCode {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func newTwoFactorFixture(t *testing.T, user *models.User) (*AuthService, repository.LoginAttemptRepository) {
+	t.Helper()
+	keys, err := jwtkeys.NewEphemeral("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := &fakeRoleRepository{roles: []*models.Role{{ID: 1, Name: "user"}}}
+	users := &fakeUserRepository{roles: roles, users: map[int]*models.User{user.ID: user}}
+	credentials := &fakeAuthCredentialsRepository{credentials: map[int]*models.AuthCredentials{
+		user.ID: {UserID: user.ID, Login: "ivan"},
+	}}
+	attempts := repository.NewMemoryLoginAttemptRepository()
+	limiter := NewLoginLimiter(attempts, config.LoginThrottleConfig{
+		FreeAttempts:     100,
+		MaxLoginFailures: 100,
+		MaxIPFailures:    100,
+		LockoutDuration:  time.Hour,
+		FailureWindow:    time.Hour,
+	})
+	service := NewAuthService(users, credentials, nil, limiter, fakeTwoFactor{validCode: "123456"}, nil, nil, nil, keys, config.AuthConfig{
+		AccessTokenTTL: 15 * time.Minute,
+		TokenAudience:  "goida-api",
+	})
+	return service, attempts
+}
+
+func TestCompleteTwoFactorInvalidatesChallengeAfterFailures(t *testing.T) {
+	user := &models.User{ID: 7, RoleID: 1}
+	service, _ := newTwoFactorFixture(t, user)
+	ctx := context.Background()
+
+	challenge, err := service.NewTwoFactorChallenge(user)
+	if err != nil {
+		t.Fatalf("NewTwoFactorChallenge: %v", err)
+	}
+	for i := 0; i < maxChallengeFailures; i++ {
+		_, err := service.CompleteTwoFactor(ctx, challenge.ChallengeToken, "000000", models.ClientInfo{})
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+	// Даже верный код больше не принимается по этому токену.
+	_, err = service.CompleteTwoFactor(ctx, challenge.ChallengeToken, "123456", models.ClientInfo{})
+	if !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("after %d failures: err = %v, want ErrInvalidChallenge", maxChallengeFailures, err)
+	}
+}
+
+func TestCompleteTwoFactorCountsFailuresUntilVerified(t *testing.T) {
+	user := &models.User{ID: 7, RoleID: 1}
+	service, attempts := newTwoFactorFixture(t, user)
+	ctx := context.Background()
+
+	challenge, err := service.NewTwoFactorChallenge(user)
+	if err != nil {
+		t.Fatalf("NewTwoFactorChallenge: %v", err)
+	}
+	if _, err := service.CompleteTwoFactor(ctx, challenge.ChallengeToken, "000000", models.ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	attempt, err := attempts.Get(ctx, loginKey("ivan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt == nil || attempt.Failures != 1 {
+		t.Errorf("login attempt = %+v, want 1 failure", attempt)
+	}
+}
+
+func TestCompleteTwoFactorRejectsSuspendedUser(t *testing.T) {
+	user := &models.User{ID: 7, RoleID: 1}
+	service, _ := newTwoFactorFixture(t, user)
+
+	challenge, err := service.NewTwoFactorChallenge(user)
+	if err != nil {
+		t.Fatalf("NewTwoFactorChallenge: %v", err)
+	}
+	// Пользователя удалили, пока он вводил код.
+	user.IsDeleted = true
+	_, err = service.CompleteTwoFactor(context.Background(), challenge.ChallengeToken, "123456", models.ClientInfo{})
+	if !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("err = %v, want ErrAccountDisabled", err)
+	}
+}
+
+func TestChallengeTrackerClosesOnFirstUse(t *testing.T) {
+	tracker := newChallengeTracker()
+	expiresAt := time.Now().Add(time.Minute)
+
+	if !tracker.close("a", expiresAt) {
+		t.Fatal("first use rejected")
+	}
+	if tracker.open("a", expiresAt) || tracker.close("a", expiresAt) {
+		t.Error("challenge accepted twice")
+	}
+
+	tracker.fail("b", time.Now().Add(-time.Second))
+	tracker.open("c", expiresAt)
+	if _, ok := tracker.challenges["b"]; ok {
+		t.Error("expired challenge was not swept")
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"Mozilla", 10, "Mozilla"},
+		{"Mozilla", 3, "Moz"},
+		{"Яндекс", 4, "Ян"},
+		{"Яндекс", 5, "Ян"},
+		{"a😀b", 4, "a"},
+		{"😀", 2, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.value, tt.max)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+// permissionCacheTTL - как долго изменения прав и ролей, сделанные в базе в
+// обход API (или другим экземпляром приложения), могут не учитываться.
+const permissionCacheTTL = time.Minute
+
+// Authorizer - единая точка проверки прав. Права ролей и текущие роли
+// пользователей читаются из базы и кешируются в памяти.
+type Authorizer interface {
+	Can(ctx context.Context, role, permission string) (bool, error)
+	Permissions(ctx context.Context, role string) ([]string, error)
+	// IsPrivileged сообщает, что у роли есть хотя бы одно административное
+	// право.
+	IsPrivileged(ctx context.Context, role string) (bool, error)
+	// UserRole возвращает текущую роль пользователя. Роль в access-токене
+	// могла устареть, поэтому права проверяются по ней. Для удаленного или
+	// заблокированного пользователя возвращается ErrAccountDisabled.
+	UserRole(ctx context.Context, userID int) (string, error)
+	// ForgetUser сбрасывает закешированные данные пользователя.
+	ForgetUser(userID int)
+	// Reload сбрасывает весь кеш: права ролей и роли пользователей.
+	Reload()
+}
+
+type userCacheEntry struct {
+	user     *models.User
+	loadedAt time.Time
+}
+
+type authorizer struct {
+	permissionRepo repository.PermissionRepository
+	userRepo       repository.UserRepository
+
+	mu        sync.Mutex
+	roles     map[string][]string
+	loadedAt  time.Time
+	users     map[int]userCacheEntry
+	lastSweep time.Time
+}
+
+func NewAuthorizer(permissionRepo repository.PermissionRepository, userRepo repository.UserRepository) Authorizer {
+	return &authorizer{
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+		users:          make(map[int]userCacheEntry),
+	}
+}
+
+func (a *authorizer) Can(ctx context.Context, role, permission string) (bool, error) {
+	permissions, err := a.Permissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *authorizer) Permissions(ctx context.Context, role string) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.roles == nil || time.Since(a.loadedAt) > permissionCacheTTL {
+		roles, err := a.permissionRepo.ListRolePermissions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.roles = roles
+		a.loadedAt = time.Now()
+	}
+	return a.roles[role], nil
+}
+
+func (a *authorizer) IsPrivileged(ctx context.Context, role string) (bool, error) {
+	permissions, err := a.Permissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if models.IsAdministrativePermission(p) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *authorizer) UserRole(ctx context.Context, userID int) (string, error) {
+	user, err := a.cachedUser(userID)
+	if err != nil {
+		return "", err
+	}
+	// Срок блокировки проверяется при каждом обращении, а не при загрузке.
+	if !user.CanSignIn(time.Now()) {
+		return "", ErrAccountDisabled
+	}
+	if user.Role == nil {
+		return "", nil
+	}
+	return user.Role.Name, nil
+}
+
+func (a *authorizer) cachedUser(userID int) (*models.User, error) {
+	a.mu.Lock()
+	cached, ok := a.users[userID]
+	a.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) <= permissionCacheTTL {
+		return cached.user, nil
+	}
+
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	a.sweep(now)
+	a.users[userID] = userCacheEntry{user: user, loadedAt: now}
+	return user, nil
+}
+
+func (a *authorizer) ForgetUser(userID int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.users, userID)
+}
+
+// sweep удаляет устаревшие записи о пользователях, чтобы кеш не рос бесконечно.
+// Вызывается под блокировкой.
+func (a *authorizer) sweep(now time.Time) {
+	if now.Sub(a.lastSweep) < permissionCacheTTL {
+		return
+	}
+	a.lastSweep = now
+	for id, entry := range a.users {
+		if now.Sub(entry.loadedAt) > permissionCacheTTL {
+			delete(a.users, id)
+		}
+	}
+}
+
+func (a *authorizer) Reload() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.roles = nil
+	a.users = make(map[int]userCacheEntry)
+}
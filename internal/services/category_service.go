@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category with this slug already exists")
+	ErrInvalidSlug      = errors.New("slug must contain letters or digits")
+	ErrUnknownCategory  = errors.New("unknown category")
+)
+
+// CategoryService - категории ведут администраторы; статьи ссылаются на
+// них по slug.
+type CategoryService interface {
+	List(ctx context.Context) ([]*models.Category, error)
+	Create(ctx context.Context, req *models.CreateCategoryRequest) (*models.Category, error)
+	Update(ctx context.Context, id int, req *models.UpdateCategoryRequest) (*models.Category, error)
+	// Delete удаляет категорию; статьи остаются без категории.
+	Delete(ctx context.Context, id int) error
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo}
+}
+
+const (
+	categorySlugMax = 64
+	tagSlugMax      = 50
+)
+
+func (s *categoryService) List(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []*models.Category{}
+	}
+	return categories, nil
+}
+
+func (s *categoryService) Create(ctx context.Context, req *models.CreateCategoryRequest) (*models.Category, error) {
+	source := req.Slug
+	if source == "" {
+		source = req.Name
+	}
+	slug := slugify(source, categorySlugMax)
+	if slug == "" {
+		return nil, ErrInvalidSlug
+	}
+	if _, err := s.categoryRepo.GetBySlug(slug); err == nil {
+		return nil, ErrCategoryExists
+	}
+
+	category := &models.Category{Slug: slug, Name: req.Name, Description: req.Description}
+	if err := s.categoryRepo.Create(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) Update(ctx context.Context, id int, req *models.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.categoryRepo.GetByID(id)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	if req.Slug != nil {
+		slug := slugify(*req.Slug, categorySlugMax)
+		if slug == "" {
+			return nil, ErrInvalidSlug
+		}
+		if slug != category.Slug {
+			if _, err := s.categoryRepo.GetBySlug(slug); err == nil {
+				return nil, ErrCategoryExists
+			}
+			category.Slug = slug
+		}
+	}
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+
+	if err := s.categoryRepo.Update(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) Delete(ctx context.Context, id int) error {
+	if err := s.categoryRepo.Delete(id); err != nil {
+		if err.Error() == "category not found" {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// slugify приводит строку к виду slug: нижний регистр, буквы и цифры
+// сохраняются, остальные символы схлопываются в один дефис. "+" и "#"
+// оставлены ради тегов вроде c++ и c#.
+func slugify(value string, max int) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+
+	runes := []rune(b.String())
+	if len(runes) > max {
+		runes = runes[:max]
+	}
+	return strings.TrimRight(string(runes), "-")
+}
+
+// normalizeTags приводит теги к slug и убирает повторы, сохраняя порядок.
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		slug := slugify(tag, tagSlugMax)
+		if slug != "" && !containsString(result, slug) {
+			result = append(result, slug)
+		}
+	}
+	return result
+}
@@ -3,26 +3,32 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
 	"goida/internal/models"
+	"goida/internal/pagination"
 	"goida/internal/repository"
 )
 
 type CommentService interface {
 	Create(ctx context.Context, articleID int, userID int, req *models.CreateCommentRequest) (*models.Comment, error)
-	ListByArticle(ctx context.Context, articleID int, limit, offset int) ([]*models.Comment, error)
+	// ListByArticle возвращает ErrArticleNotFound, если статья не видна
+	// читателю viewer (nil - анонимный запрос).
+	ListByArticle(ctx context.Context, articleID int, viewer *Claims, page pagination.Params) (pagination.Page[*models.Comment], error)
 	UpdateOwned(ctx context.Context, id int64, userID int, req *models.UpdateCommentRequest) error
-	DeleteOwned(ctx context.Context, id int64, userID int) error
+	Delete(ctx context.Context, id int64, userID int, userRole string) error
 	GetArticleRatingStats(ctx context.Context, articleID int) (float64, int, error)
 }
 
 type commentService struct {
-	comments repository.CommentRepository
-	articles repository.ArticleRepository
+	comments   repository.CommentRepository
+	articles   repository.ArticleRepository
+	users      repository.UserRepository
+	authorizer Authorizer
 }
 
-func NewCommentService(comments repository.CommentRepository, articles repository.ArticleRepository) CommentService {
-	return &commentService{comments: comments, articles: articles}
+func NewCommentService(comments repository.CommentRepository, articles repository.ArticleRepository, users repository.UserRepository, authorizer Authorizer) CommentService {
+	return &commentService{comments: comments, articles: articles, users: users, authorizer: authorizer}
 }
 
 func (s *commentService) Create(ctx context.Context, articleID int, userID int, req *models.CreateCommentRequest) (*models.Comment, error) {
@@ -30,7 +36,17 @@ func (s *commentService) Create(ctx context.Context, articleID int, userID int,
 		return nil, errors.New("validation failed")
 	}
 
-	if _, err := s.articles.GetArticle(articleID); err != nil {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Комментировать можно только опубликованные статьи.
+	article, err := s.articles.GetArticle(articleID)
+	if err != nil || !article.IsPublished(time.Now()) {
 		return nil, errors.New("article not found")
 	}
 
@@ -41,18 +57,64 @@ func (s *commentService) Create(ctx context.Context, articleID int, userID int,
 	return comment, nil
 }
 
-func (s *commentService) ListByArticle(ctx context.Context, articleID int, limit, offset int) ([]*models.Comment, error) {
-	return s.comments.FindByArticle(ctx, articleID, limit, offset)
+func (s *commentService) ListByArticle(ctx context.Context, articleID int, viewer *Claims, page pagination.Params) (pagination.Page[*models.Comment], error) {
+	// Комментарии и оценки видны тем же читателям, что и сама статья.
+	article, err := s.articles.GetArticle(articleID)
+	if err != nil {
+		return pagination.Page[*models.Comment]{}, ErrArticleNotFound
+	}
+	visible, err := canViewArticle(s.authorizer, article, viewer)
+	if err != nil {
+		return pagination.Page[*models.Comment]{}, err
+	}
+	if !visible {
+		return pagination.Page[*models.Comment]{}, ErrArticleNotFound
+	}
+
+	items, err := s.comments.FindByArticle(ctx, articleID, page)
+	if err != nil {
+		return pagination.Page[*models.Comment]{}, err
+	}
+	total, err := s.comments.CountByArticle(ctx, articleID)
+	if err != nil {
+		return pagination.Page[*models.Comment]{}, err
+	}
+	return pagination.NewPage(items, page, total, func(c *models.Comment) (time.Time, int64) {
+		return c.CreatedAt, c.ID
+	}), nil
 }
 
 func (s *commentService) UpdateOwned(ctx context.Context, id int64, userID int, req *models.UpdateCommentRequest) error {
 	if req.Rating != 0 && (req.Rating < 1 || req.Rating > 5) {
 		return errors.New("validation failed")
 	}
+
+	comment, err := s.comments.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		return errors.New("not found or not owner")
+	}
+	// Как и при создании: у снятой с публикации статьи комментарии и оценки
+	// не меняются.
+	article, err := s.articles.GetArticle(comment.ArticleID)
+	if err != nil || !article.IsPublished(time.Now()) {
+		return ErrArticleNotFound
+	}
+
 	return s.comments.UpdateOwned(ctx, id, userID, req.Text, req.Rating)
 }
 
-func (s *commentService) DeleteOwned(ctx context.Context, id int64, userID int) error {
+// Delete удаляет свой комментарий, а при праве comment.delete.any - любой.
+func (s *commentService) Delete(ctx context.Context, id int64, userID int, userRole string) error {
+	canDeleteAny, err := s.authorizer.Can(ctx, userRole, models.PermCommentDeleteAny)
+	if err != nil {
+		return err
+	}
+	if canDeleteAny {
+		return s.comments.Delete(ctx, id)
+	}
 	return s.comments.DeleteOwned(ctx, id, userID)
 }
 
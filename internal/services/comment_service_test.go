@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"goida/internal/models"
+	"goida/internal/pagination"
+	"goida/internal/repository"
+)
+
+type fakeArticleRepository struct {
+	repository.ArticleRepository
+	articles map[int]*models.Article
+}
+
+func (r *fakeArticleRepository) GetArticle(id int) (*models.Article, error) {
+	article, ok := r.articles[id]
+	if !ok {
+		return nil, fmt.Errorf("article not found")
+	}
+	return article, nil
+}
+
+type fakeCommentRepository struct {
+	repository.CommentRepository
+	comments []*models.Comment
+	updated  int
+}
+
+func (r *fakeCommentRepository) GetByID(ctx context.Context, id int64) (*models.Comment, error) {
+	for _, c := range r.comments {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.New("comment not found")
+}
+
+func (r *fakeCommentRepository) FindByArticle(ctx context.Context, articleID int, page pagination.Params) ([]*models.Comment, error) {
+	var items []*models.Comment
+	for _, c := range r.comments {
+		if c.ArticleID == articleID {
+			items = append(items, c)
+		}
+	}
+	return items, nil
+}
+
+func (r *fakeCommentRepository) CountByArticle(ctx context.Context, articleID int) (int, error) {
+	items, _ := r.FindByArticle(ctx, articleID, pagination.Params{})
+	return len(items), nil
+}
+
+func (r *fakeCommentRepository) UpdateOwned(ctx context.Context, id int64, userID int, text string, rating int) error {
+	r.updated++
+	return nil
+}
+
+// moderatorAuthorizer дает право article.update.any только роли moderator.
+type moderatorAuthorizer struct {
+	Authorizer
+}
+
+func (moderatorAuthorizer) Can(ctx context.Context, role, permission string) (bool, error) {
+	return role == "moderator" && permission == models.PermArticleUpdateAny, nil
+}
+
+func newCommentFixture() (CommentService, *fakeCommentRepository) {
+	articles := &fakeArticleRepository{articles: map[int]*models.Article{
+		1: {ID: 1, AuthorID: 10, Status: models.ArticlePublished},
+		2: {ID: 2, AuthorID: 10, Status: models.ArticleDraft},
+		3: {ID: 3, AuthorID: 10, Status: models.ArticleArchived},
+	}}
+	comments := &fakeCommentRepository{comments: []*models.Comment{
+		{ID: 100, ArticleID: 1, UserID: 20, Rating: 5},
+		{ID: 200, ArticleID: 2, UserID: 20, Rating: 4},
+		{ID: 300, ArticleID: 3, UserID: 20, Rating: 3},
+	}}
+	return NewCommentService(comments, articles, nil, moderatorAuthorizer{}), comments
+}
+
+func TestListByArticleHidesCommentsOfInvisibleArticles(t *testing.T) {
+	service, _ := newCommentFixture()
+	ctx := context.Background()
+	page := pagination.Params{Limit: 10}
+
+	tests := []struct {
+		name      string
+		articleID int
+		viewer    *Claims
+		visible   bool
+	}{
+		{"published, anonymous", 1, nil, true},
+		{"draft, anonymous", 2, nil, false},
+		{"archived, anonymous", 3, nil, false},
+		{"draft, other user", 2, &Claims{UserID: 20, Role: "user"}, false},
+		{"draft, author", 2, &Claims{UserID: 10, Role: "user"}, true},
+		{"archived, moderator", 3, &Claims{UserID: 30, Role: "moderator"}, true},
+		{"missing article", 4, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := service.ListByArticle(ctx, tt.articleID, tt.viewer, page)
+			if !tt.visible {
+				if !errors.Is(err, ErrArticleNotFound) {
+					t.Errorf("err = %v, want ErrArticleNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListByArticle: %v", err)
+			}
+			if len(items.Items) != 1 {
+				t.Errorf("items = %d, want 1", len(items.Items))
+			}
+		})
+	}
+}
+
+func TestUpdateOwnedRequiresPublishedArticle(t *testing.T) {
+	service, comments := newCommentFixture()
+	ctx := context.Background()
+	req := &models.UpdateCommentRequest{Rating: 1}
+
+	if err := service.UpdateOwned(ctx, 100, 20, req); err != nil {
+		t.Fatalf("published article: %v", err)
+	}
+	for _, id := range []int64{200, 300} {
+		if err := service.UpdateOwned(ctx, id, 20, req); !errors.Is(err, ErrArticleNotFound) {
+			t.Errorf("comment %d: err = %v, want ErrArticleNotFound", id, err)
+		}
+	}
+	if err := service.UpdateOwned(ctx, 100, 21, req); err == nil || err.Error() != "not found or not owner" {
+		t.Errorf("foreign comment: err = %v", err)
+	}
+	if comments.updated != 1 {
+		t.Errorf("updates = %d, want 1", comments.updated)
+	}
+}
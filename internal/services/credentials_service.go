@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/password"
+	"goida/internal/repository"
+)
+
+var (
+	ErrWrongPassword       = errors.New("current password is incorrect")
+	ErrLoginTaken          = errors.New("login is already taken")
+	ErrCredentialsNotFound = errors.New("credentials not found")
+	ErrNothingToUpdate     = errors.New("login or password must be provided")
+)
+
+type CredentialsService interface {
+	Get(ctx context.Context, userID int) (*models.AuthCredentials, error)
+	ChangePassword(ctx context.Context, claims *Claims, req *models.ChangePasswordRequest, clientIP string) error
+	ChangeLogin(ctx context.Context, claims *Claims, req *models.ChangeLoginRequest, clientIP string) (*models.AuthCredentials, error)
+	AdminUpdate(ctx context.Context, actorID, userID int, req *models.UpdateAuthCredentialsRequest, clientIP string) (*models.AuthCredentials, error)
+	// VerifyPassword подтверждает опасное действие паролем. Пользователю без
+	// пароля (вход только через OIDC) подтверждение не требуется.
+	VerifyPassword(ctx context.Context, userID int, password, clientIP string) error
+}
+
+type credentialsService struct {
+	userRepo            repository.UserRepository
+	authCredentialsRepo repository.AuthCredentialsRepository
+	sessionRepo         repository.SessionRepository
+	auditRepo           repository.AuditRepository
+	loginLimiter        *LoginLimiter
+	hasher              *password.Hasher
+	passwordPolicy      *password.Policy
+	authorizer          Authorizer
+}
+
+func NewCredentialsService(
+	userRepo repository.UserRepository,
+	authCredentialsRepo repository.AuthCredentialsRepository,
+	sessionRepo repository.SessionRepository,
+	auditRepo repository.AuditRepository,
+	loginLimiter *LoginLimiter,
+	hasher *password.Hasher,
+	passwordPolicy *password.Policy,
+	authorizer Authorizer,
+) CredentialsService {
+	return &credentialsService{
+		userRepo:            userRepo,
+		authCredentialsRepo: authCredentialsRepo,
+		sessionRepo:         sessionRepo,
+		auditRepo:           auditRepo,
+		loginLimiter:        loginLimiter,
+		hasher:              hasher,
+		passwordPolicy:      passwordPolicy,
+		authorizer:          authorizer,
+	}
+}
+
+func (s *credentialsService) Get(ctx context.Context, userID int) (*models.AuthCredentials, error) {
+	credentials, err := s.authCredentialsRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, ErrCredentialsNotFound
+	}
+	return credentials, nil
+}
+
+// ChangePassword меняет пароль и отзывает все сессии пользователя, кроме
+// текущей: остальные устройства придется авторизовать заново.
+func (s *credentialsService) ChangePassword(ctx context.Context, claims *Claims, req *models.ChangePasswordRequest, clientIP string) error {
+	credentials, err := s.verifyCurrentPassword(ctx, claims.UserID, req.CurrentPassword, clientIP)
+	if err != nil {
+		return err
+	}
+
+	if err := s.passwordPolicy.Check(req.NewPassword, credentials.Login, claims.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	credentials.Password = hashedPassword
+
+	if err := s.authCredentialsRepo.Update(claims.UserID, credentials); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeOtherSessions(ctx, claims.UserID, claims.SessionID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, claims.UserID, claims.UserID, models.AuditPasswordChanged, nil, clientIP)
+	return nil
+}
+
+func (s *credentialsService) ChangeLogin(ctx context.Context, claims *Claims, req *models.ChangeLoginRequest, clientIP string) (*models.AuthCredentials, error) {
+	credentials, err := s.verifyCurrentPassword(ctx, claims.UserID, req.CurrentPassword, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	oldLogin := credentials.Login
+	if req.Login == oldLogin {
+		return credentials, nil
+	}
+	if err := s.ensureLoginAvailable(req.Login, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	credentials.Login = req.Login
+	if err := s.authCredentialsRepo.Update(claims.UserID, credentials); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, claims.UserID, claims.UserID, models.AuditLoginChanged, map[string]interface{}{
+		"old_login": oldLogin,
+		"new_login": credentials.Login,
+	}, clientIP)
+	return credentials, nil
+}
+
+// AdminUpdate позволяет администратору сменить логин и/или пароль
+// пользователя без знания текущего пароля; пользователя с правами, которых
+// нет у администратора, - нельзя. При смене пароля все сессии пользователя
+// отзываются.
+func (s *credentialsService) AdminUpdate(ctx context.Context, actorID, userID int, req *models.UpdateAuthCredentialsRequest, clientIP string) (*models.AuthCredentials, error) {
+	if req.Login == "" && req.Password == "" {
+		return nil, ErrNothingToUpdate
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrCredentialsNotFound
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{}
+	if req.Login != "" && req.Login != credentials.Login {
+		if err := s.ensureLoginAvailable(req.Login, userID); err != nil {
+			return nil, err
+		}
+		details["old_login"] = credentials.Login
+		details["new_login"] = req.Login
+		credentials.Login = req.Login
+	}
+	if req.Password != "" {
+		if err := s.passwordPolicy.Check(req.Password, credentials.Login, user.Email); err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := s.hasher.Hash(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		credentials.Password = hashedPassword
+		details["password_changed"] = true
+	}
+
+	if err := s.authCredentialsRepo.Update(userID, credentials); err != nil {
+		return nil, err
+	}
+
+	if req.Password != "" {
+		if err := s.sessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+			return nil, err
+		}
+		if err := s.loginLimiter.RegisterSuccess(ctx, credentials.Login); err != nil {
+			logrus.Errorf("Failed to reset login attempts: %v", err)
+		}
+	}
+
+	s.audit(ctx, userID, actorID, models.AuditCredentialsReset, details, clientIP)
+	return credentials, nil
+}
+
+func (s *credentialsService) VerifyPassword(ctx context.Context, userID int, password, clientIP string) error {
+	_, err := s.verifyCurrentPassword(ctx, userID, password, clientIP)
+	if errors.Is(err, ErrCredentialsNotFound) {
+		return nil
+	}
+	return err
+}
+
+// verifyCurrentPassword проверяет текущий пароль с учетом ограничителя
+// попыток входа, чтобы украденный access-токен не позволял подбирать пароль.
+func (s *credentialsService) verifyCurrentPassword(ctx context.Context, userID int, currentPassword, clientIP string) (*models.AuthCredentials, error) {
+	credentials, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loginLimiter.Check(ctx, credentials.Login, clientIP); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.hasher.Verify(currentPassword, credentials.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.loginLimiter.RegisterFailure(ctx, credentials.Login, clientIP); err != nil {
+			logrus.Errorf("Failed to register failed password check: %v", err)
+		}
+		return nil, ErrWrongPassword
+	}
+	return credentials, nil
+}
+
+func (s *credentialsService) ensureLoginAvailable(login string, userID int) error {
+	existing, err := s.authCredentialsRepo.GetByLogin(login)
+	if err == nil && existing != nil && existing.UserID != userID {
+		return ErrLoginTaken
+	}
+	return nil
+}
+
+// audit не прерывает операцию: изменение уже сохранено, поэтому сбой записи
+// в журнал только логируется.
+func (s *credentialsService) audit(ctx context.Context, userID, actorID int, action string, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", action, userID, err)
+	}
+}
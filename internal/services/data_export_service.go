@@ -0,0 +1,330 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/config"
+	"goida/internal/models"
+	"goida/internal/pagination"
+	"goida/internal/repository"
+)
+
+const (
+	// dataExportTimeout - выгрузка, не сформированная за это время (например,
+	// из-за перезапуска сервера), считается ошибочной.
+	dataExportTimeout = 30 * time.Minute
+	dataExportWorkers = 2
+	auditExportPage   = 500
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrDataExportNotReady = errors.New("data export is not ready")
+	ErrDataExportExpired  = errors.New("data export has expired")
+)
+
+// DataExportService выгружает все персональные данные пользователя в
+// ZIP-архив с JSON-файлами. Хеши паролей, токенов и секреты 2FA в выгрузку
+// не попадают.
+type DataExportService interface {
+	// Export формирует архив сразу, если данных немного. Иначе (или при
+	// async) выгрузка ставится в очередь: archive равен nil, а статус
+	// выгрузки нужно опрашивать через Get.
+	Export(ctx context.Context, actorID, userID int, async bool, clientIP string) (*models.DataExport, []byte, error)
+	Get(ctx context.Context, userID int, exportID int64) (*models.DataExport, error)
+	Archive(ctx context.Context, userID int, exportID int64) (*models.DataExport, []byte, error)
+	// Cleanup удаляет истекшие выгрузки и помечает зависшие ошибочными.
+	Cleanup(ctx context.Context) (int64, error)
+}
+
+type dataExportService struct {
+	exportRepo          repository.DataExportRepository
+	userRepo            repository.UserRepository
+	authCredentialsRepo repository.AuthCredentialsRepository
+	identityRepo        repository.UserIdentityRepository
+	tokenRepo           repository.PersonalAccessTokenRepository
+	articleRepo         repository.ArticleRepository
+	commentRepo         repository.CommentRepository
+	sessionRepo         repository.SessionRepository
+	auditRepo           repository.AuditRepository
+	twoFactor           TwoFactorService
+	syncLimit           int
+	ttl                 time.Duration
+	workers             chan struct{}
+}
+
+func NewDataExportService(
+	exportRepo repository.DataExportRepository,
+	userRepo repository.UserRepository,
+	authCredentialsRepo repository.AuthCredentialsRepository,
+	identityRepo repository.UserIdentityRepository,
+	tokenRepo repository.PersonalAccessTokenRepository,
+	articleRepo repository.ArticleRepository,
+	commentRepo repository.CommentRepository,
+	sessionRepo repository.SessionRepository,
+	auditRepo repository.AuditRepository,
+	twoFactor TwoFactorService,
+	cfg config.ExportConfig,
+) DataExportService {
+	return &dataExportService{
+		exportRepo:          exportRepo,
+		userRepo:            userRepo,
+		authCredentialsRepo: authCredentialsRepo,
+		identityRepo:        identityRepo,
+		tokenRepo:           tokenRepo,
+		articleRepo:         articleRepo,
+		commentRepo:         commentRepo,
+		sessionRepo:         sessionRepo,
+		auditRepo:           auditRepo,
+		twoFactor:           twoFactor,
+		syncLimit:           cfg.SyncLimit,
+		ttl:                 cfg.TTL,
+		workers:             make(chan struct{}, dataExportWorkers),
+	}
+}
+
+// exportCredentials - метаданные способов входа без паролей и секретов.
+type exportCredentials struct {
+	Password             *models.AuthCredentials       `json:"password_login,omitempty"`
+	TwoFactor            *models.TwoFactorStatus       `json:"two_factor"`
+	Identities           []*models.UserIdentity        `json:"identities"`
+	PersonalAccessTokens []*models.PersonalAccessToken `json:"personal_access_tokens"`
+}
+
+func (s *dataExportService) Export(ctx context.Context, actorID, userID int, async bool, clientIP string) (*models.DataExport, []byte, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+
+	if !async {
+		records, err := s.exportRepo.CountUserRecords(ctx, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		async = records > s.syncLimit
+	}
+
+	if !async {
+		archive, err := s.buildArchive(ctx, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.audit(ctx, userID, actorID, map[string]interface{}{"async": false}, clientIP)
+		return nil, archive, nil
+	}
+
+	pending, err := s.exportRepo.FindPending(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pending != nil {
+		return pending, nil, nil
+	}
+
+	export := &models.DataExport{
+		UserID:      userID,
+		RequestedBy: &actorID,
+		Status:      models.DataExportPending,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, nil, err
+	}
+	s.audit(ctx, userID, actorID, map[string]interface{}{"async": true, "export_id": export.ID}, clientIP)
+
+	go s.generate(export.ID, userID)
+	return export, nil, nil
+}
+
+func (s *dataExportService) Get(ctx context.Context, userID int, exportID int64) (*models.DataExport, error) {
+	export, err := s.exportRepo.Get(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrDataExportNotFound
+	}
+	return export, nil
+}
+
+func (s *dataExportService) Archive(ctx context.Context, userID int, exportID int64) (*models.DataExport, []byte, error) {
+	export, err := s.Get(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.IsExpired(time.Now()) {
+		return nil, nil, ErrDataExportExpired
+	}
+	if export.Status != models.DataExportReady {
+		return nil, nil, ErrDataExportNotReady
+	}
+
+	archive, err := s.exportRepo.GetArchive(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if archive == nil {
+		return nil, nil, ErrDataExportNotFound
+	}
+	return export, archive, nil
+}
+
+func (s *dataExportService) Cleanup(ctx context.Context) (int64, error) {
+	if _, err := s.exportRepo.FailStale(ctx, time.Now().Add(-dataExportTimeout)); err != nil {
+		return 0, err
+	}
+	return s.exportRepo.DeleteExpired(ctx, time.Now())
+}
+
+// generate формирует архив в фоне; одновременно работает не больше
+// dataExportWorkers выгрузок.
+func (s *dataExportService) generate(exportID int64, userID int) {
+	s.workers <- struct{}{}
+	defer func() { <-s.workers }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	archive, err := s.buildArchive(ctx, userID)
+	if err == nil {
+		err = s.exportRepo.Complete(ctx, exportID, archive)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to generate data export %d for user %d: %v", exportID, userID, err)
+		if err := s.exportRepo.Fail(context.Background(), exportID, "failed to generate export"); err != nil {
+			logrus.Errorf("Failed to mark data export %d as failed: %v", exportID, err)
+		}
+	}
+}
+
+func (s *dataExportService) buildArchive(ctx context.Context, userID int) ([]byte, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.loadCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	articles, err := s.articleRepo.ListAllByAuthor(userID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessionRepo.ListSessionHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.loadAudit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"credentials.json", credentials},
+		{"articles.json", emptyIfNil(articles)},
+		{"comments.json", emptyIfNil(comments)},
+		{"sessions.json", emptyIfNil(sessions)},
+		{"audit.json", emptyIfNil(events)},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	now := time.Now()
+	for _, file := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *dataExportService) loadCredentials(ctx context.Context, userID int) (*exportCredentials, error) {
+	result := &exportCredentials{}
+	if credentials, err := s.authCredentialsRepo.GetByUserID(userID); err == nil {
+		result.Password = credentials
+	}
+
+	twoFactor, err := s.twoFactor.Status(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.TwoFactor = twoFactor
+
+	identities, err := s.identityRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Identities = emptyIfNil(identities)
+
+	tokens, err := s.tokenRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.PersonalAccessTokens = emptyIfNil(tokens)
+	return result, nil
+}
+
+func (s *dataExportService) loadAudit(ctx context.Context, userID int) ([]*models.AuditEvent, error) {
+	var events []*models.AuditEvent
+	page := pagination.Params{Limit: auditExportPage}
+	for {
+		rows, err := s.auditRepo.ListByUser(ctx, userID, page)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) <= page.Limit {
+			return append(events, rows...), nil
+		}
+		rows = rows[:page.Limit]
+		events = append(events, rows...)
+
+		last := rows[len(rows)-1]
+		page.Cursor = &pagination.Cursor{Time: last.CreatedAt, ID: last.ID}
+	}
+}
+
+func (s *dataExportService) audit(ctx context.Context, userID, actorID int, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  models.AuditDataExported,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", models.AuditDataExported, userID, err)
+	}
+}
+
+// emptyIfNil сериализует пустой список как [], а не null.
+func emptyIfNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/jwtkeys"
+	"goida/internal/mail"
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+const emailVerificationType = "email_verify"
+
+var (
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+)
+
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user *models.User) error
+	Resend(ctx context.Context, userID int) error
+	Verify(ctx context.Context, token string) error
+	// SetVerified - ручная отметка администратором actorID; чужую роль с
+	// большими правами он изменить не может (ErrUserOutranks).
+	SetVerified(ctx context.Context, actorID, userID int, verified bool, clientIP string) (*models.User, error)
+}
+
+type verificationClaims struct {
+	Type  string `json:"typ"`
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+type emailVerificationService struct {
+	userRepo       repository.UserRepository
+	auditRepo      repository.AuditRepository
+	authorizer     Authorizer
+	keys           *jwtkeys.KeySet
+	mailer         mail.Mailer
+	apiURL         string
+	tokenTTL       time.Duration
+	resendInterval time.Duration
+}
+
+func NewEmailVerificationService(userRepo repository.UserRepository, auditRepo repository.AuditRepository, authorizer Authorizer, keys *jwtkeys.KeySet, mailer mail.Mailer, apiURL string, tokenTTL, resendInterval time.Duration) EmailVerificationService {
+	return &emailVerificationService{
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		authorizer:     authorizer,
+		keys:           keys,
+		mailer:         mailer,
+		apiURL:         apiURL,
+		tokenTTL:       tokenTTL,
+		resendInterval: resendInterval,
+	}
+}
+
+// SendVerification отправляет подписанную ссылку подтверждения. В токен
+// записывается email, поэтому после смены адреса старые ссылки перестают
+// действовать.
+func (s *emailVerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	// Отметка об отправке нужна только для ограничения повторных писем.
+	if _, err := s.userRepo.ReserveVerificationEmail(user.ID, 0); err != nil {
+		return err
+	}
+	return s.send(ctx, user)
+}
+
+func (s *emailVerificationService) send(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	token, err := s.keys.Sign(verificationClaims{
+		Type:             emailVerificationType,
+		Email:            user.Email,
+		RegisteredClaims: s.keys.Claims(s.keys.Issuer(), strconv.Itoa(user.ID), now, s.tokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign verification token: %w", err)
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email в GoIda",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nЧтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s/api/auth/verify?token=%s\n\nСсылка действует %s.\n",
+			user.Name, s.apiURL, url.QueryEscape(token), s.tokenTTL,
+		),
+	})
+}
+
+func (s *emailVerificationService) Resend(ctx context.Context, userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	// Время последней отправки хранится в базе и резервируется одним
+	// запросом: параллельные запросы и другие экземпляры не отправят
+	// лишнего письма, а перезапуск не сбросит ограничение.
+	wait, err := s.userRepo.ReserveVerificationEmail(userID, s.resendInterval)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	return s.send(ctx, user)
+}
+
+func (s *emailVerificationService) Verify(ctx context.Context, token string) error {
+	claims := &verificationClaims{}
+	if err := s.keys.Parse(token, claims, s.keys.Issuer()); err != nil || claims.Type != emailVerificationType {
+		return ErrInvalidVerificationToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user.Email != claims.Email {
+		return ErrInvalidVerificationToken
+	}
+
+	if !user.EmailVerified {
+		if err := s.userRepo.SetEmailVerified(userID, true); err != nil {
+			return err
+		}
+		logrus.Infof("Email verified for user %d", userID)
+	}
+	return nil
+}
+
+func (s *emailVerificationService) SetVerified(ctx context.Context, actorID, userID int, verified bool, clientIP string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetEmailVerified(userID, verified); err != nil {
+		return nil, err
+	}
+
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  models.AuditEmailVerificationChanged,
+		Details: map[string]interface{}{"verified": verified},
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", event.Action, userID, err)
+	}
+
+	return s.userRepo.GetByID(userID)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goida/internal/mail"
+	"goida/internal/models"
+)
+
+// verificationUserRepository хранит время отправки писем, как колонка
+// users.verification_sent_at.
+type verificationUserRepository struct {
+	*fakeUserRepository
+	sentAt map[int]time.Time
+}
+
+func (r *verificationUserRepository) ReserveVerificationEmail(id int, interval time.Duration) (time.Duration, error) {
+	if wait := interval - time.Since(r.sentAt[id]); wait > 0 {
+		return wait, nil
+	}
+	r.sentAt[id] = time.Now()
+	return 0, nil
+}
+
+type fakeMailer struct {
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message mail.Message) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+type verificationFixture struct {
+	service EmailVerificationService
+	users   *verificationUserRepository
+	audit   *fakeAuditRepository
+	mailer  *fakeMailer
+}
+
+func newVerificationFixture(t *testing.T) *verificationFixture {
+	t.Helper()
+	role := newRoleFixture()
+	users := &verificationUserRepository{fakeUserRepository: role.users, sentAt: map[int]time.Time{}}
+	f := &verificationFixture{users: users, audit: role.audit, mailer: &fakeMailer{}}
+	_, keys := newTokenFixture(t, "goida-api")
+	f.service = NewEmailVerificationService(users, f.audit, role.authorizer, keys, f.mailer, "http://localhost:8080", time.Hour, time.Minute)
+	return f
+}
+
+func TestResendIsLimitedPerUser(t *testing.T) {
+	f := newVerificationFixture(t)
+	ctx := context.Background()
+
+	if err := f.service.Resend(ctx, 2); err != nil {
+		t.Fatalf("first resend: %v", err)
+	}
+	var limitErr *TooManyAttemptsError
+	if err := f.service.Resend(ctx, 2); !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+		t.Errorf("second resend: err = %v, want TooManyAttemptsError", err)
+	}
+	if len(f.mailer.sent) != 1 {
+		t.Errorf("sent = %d, want 1", len(f.mailer.sent))
+	}
+}
+
+func TestSetVerifiedChecksRankAndRecordsAudit(t *testing.T) {
+	f := newVerificationFixture(t)
+	ctx := context.Background()
+
+	user, err := f.service.SetVerified(ctx, 1, 2, true, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("SetVerified: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("email is not verified")
+	}
+	if len(f.audit.events) != 1 || f.audit.events[0].Action != models.AuditEmailVerificationChanged || f.audit.events[0].Details["verified"] != true {
+		t.Errorf("audit events = %+v", f.audit.events)
+	}
+
+	if _, err := f.service.SetVerified(ctx, 1, 3, false, ""); !errors.Is(err, ErrUserOutranks) {
+		t.Errorf("admin target: err = %v, want ErrUserOutranks", err)
+	}
+	if _, err := f.service.SetVerified(ctx, 1, 42, true, ""); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("missing user: err = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/jwtkeys"
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var (
+	ErrImpersonateSelf       = errors.New("cannot impersonate yourself")
+	ErrImpersonatePrivileged = errors.New("users with administrative permissions cannot be impersonated")
+	ErrImpersonationNested   = errors.New("impersonation cannot be started while impersonating")
+)
+
+// ImpersonationService выдает сотрудникам поддержки короткоживущие токены,
+// действующие от имени пользователя. Токен привязан к сессии сотрудника, а
+// каждый запрос по нему записывается в журнал с обоими идентификаторами.
+type ImpersonationService interface {
+	Start(ctx context.Context, actor *Claims, userID int, req *models.ImpersonateRequest, clientIP string) (*models.ImpersonationResponse, error)
+	RecordRequest(ctx context.Context, claims *Claims, method, path, clientIP string)
+}
+
+type impersonationService struct {
+	userRepo   repository.UserRepository
+	auditRepo  repository.AuditRepository
+	authorizer Authorizer
+	keys       *jwtkeys.KeySet
+	audience   string
+	ttl        time.Duration
+}
+
+// audience - аудитория access-токенов (JWT_AUDIENCE): токен имперсонации
+// принимается там же, где обычный access-токен.
+func NewImpersonationService(userRepo repository.UserRepository, auditRepo repository.AuditRepository, authorizer Authorizer, keys *jwtkeys.KeySet, audience string, ttl time.Duration) ImpersonationService {
+	return &impersonationService{
+		userRepo:   userRepo,
+		auditRepo:  auditRepo,
+		authorizer: authorizer,
+		keys:       keys,
+		audience:   audience,
+		ttl:        ttl,
+	}
+}
+
+// Start проверяет цель и выдает токен. Имперсонировать можно только
+// активного пользователя без административных прав: иначе токен открывал бы
+// доступ к администрированию в обход 2FA сотрудника.
+func (s *impersonationService) Start(ctx context.Context, actor *Claims, userID int, req *models.ImpersonateRequest, clientIP string) (*models.ImpersonationResponse, error) {
+	if actor.IsImpersonated() {
+		return nil, ErrImpersonationNested
+	}
+	if actor.UserID == userID {
+		return nil, ErrImpersonateSelf
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.CanSignIn(time.Now()) || user.IsDeactivated() {
+		return nil, ErrAccountDisabled
+	}
+
+	privileged, err := s.authorizer.IsPrivileged(ctx, user.Role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return nil, ErrImpersonatePrivileged
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	token, err := s.keys.Sign(Claims{
+		UserID:           user.ID,
+		Email:            user.Email,
+		Role:             user.Role.Name,
+		SessionID:        actor.SessionID,
+		Type:             accessTokenType,
+		ActorID:          actor.UserID,
+		RegisteredClaims: s.keys.Claims(s.audience, strconv.Itoa(user.ID), now, s.ttl),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign impersonation token: %w", err)
+	}
+
+	permissions, err := s.authorizer.Permissions(ctx, user.Role.Name)
+	if err != nil {
+		return nil, err
+	}
+	user.Role.Permissions = permissions
+
+	s.audit(ctx, user.ID, actor.UserID, models.AuditImpersonationStart, map[string]interface{}{
+		"reason":     req.Reason,
+		"session_id": actor.SessionID,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}, clientIP)
+
+	return &models.ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      *user,
+	}, nil
+}
+
+func (s *impersonationService) RecordRequest(ctx context.Context, claims *Claims, method, path, clientIP string) {
+	s.audit(ctx, claims.UserID, claims.ActorID, models.AuditImpersonatedCall, map[string]interface{}{
+		"method": method,
+		"path":   path,
+	}, clientIP)
+}
+
+func (s *impersonationService) audit(ctx context.Context, userID, actorID int, action string, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", action, userID, err)
+	}
+}
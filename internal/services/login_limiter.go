@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"goida/internal/config"
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter)
+}
+
+// LoginLimiter считает неудачные попытки входа отдельно по логину и по адресу
+// клиента: после нескольких бесплатных попыток каждая следующая откладывается
+// экспоненциально, а по достижении порога ключ блокируется на LockoutDuration.
+type LoginLimiter struct {
+	store repository.LoginAttemptRepository
+	cfg   config.LoginThrottleConfig
+}
+
+func NewLoginLimiter(store repository.LoginAttemptRepository, cfg config.LoginThrottleConfig) *LoginLimiter {
+	return &LoginLimiter{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+func (l *LoginLimiter) Check(ctx context.Context, login, clientIP string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, key := range l.keys(login, clientIP) {
+		attempt, err := l.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.LockedUntil.After(now) {
+			if wait := attempt.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (l *LoginLimiter) RegisterFailure(ctx context.Context, login, clientIP string) error {
+	now := time.Now()
+
+	for _, key := range l.keys(login, clientIP) {
+		failures, err := l.store.IncrementFailures(ctx, key, l.cfg.FailureWindow)
+		if err != nil {
+			return err
+		}
+
+		if delay := l.delay(key, failures); delay > 0 {
+			if err := l.store.SetLockedUntil(ctx, key, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RegisterSuccess сбрасывает только счетчик логина: сброс счетчика адреса
+// позволил бы перебирать чужие пароли, периодически входя в свой аккаунт.
+func (l *LoginLimiter) RegisterSuccess(ctx context.Context, login string) error {
+	return l.store.Reset(ctx, loginKey(login))
+}
+
+func (l *LoginLimiter) ListLockouts(ctx context.Context) ([]*models.LoginAttempt, error) {
+	return l.store.ListLocked(ctx, time.Now())
+}
+
+func (l *LoginLimiter) ClearLockout(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
+
+func (l *LoginLimiter) delay(key string, failures int) time.Duration {
+	maxFailures := l.cfg.MaxLoginFailures
+	if strings.HasPrefix(key, "ip:") {
+		maxFailures = l.cfg.MaxIPFailures
+	}
+
+	if failures >= maxFailures {
+		return l.cfg.LockoutDuration
+	}
+	if failures <= l.cfg.FreeAttempts {
+		return 0
+	}
+
+	delay := l.cfg.BaseDelay
+	for i := l.cfg.FreeAttempts + 1; i < failures && delay < l.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.cfg.MaxDelay {
+		delay = l.cfg.MaxDelay
+	}
+	return delay
+}
+
+func (l *LoginLimiter) keys(login, clientIP string) []string {
+	keys := []string{loginKey(login)}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(login)
+}
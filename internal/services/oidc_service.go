@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/config"
+	"goida/internal/jwtkeys"
+	"goida/internal/models"
+	"goida/internal/oidc"
+	"goida/internal/repository"
+)
+
+const (
+	oidcStateType    = "oidc_state"
+	oidcLoginType    = "oidc_login"
+	oidcLoginCodeTTL = time.Minute
+)
+
+var (
+	ErrUnknownProvider          = errors.New("unknown identity provider")
+	ErrInvalidOIDCState         = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified     = errors.New("identity provider did not confirm the email")
+	ErrOIDCAccountConflict      = errors.New("an account with this email exists but its email is not verified")
+	ErrOIDCProvisioningDisabled = errors.New("no account is linked to this identity")
+	ErrInvalidLoginCode         = errors.New("invalid or expired login code")
+)
+
+type OIDCService interface {
+	Providers() []*models.OIDCProviderInfo
+	// Begin возвращает адрес страницы входа провайдера и подписанное
+	// состояние, которое нужно вернуть в Complete (хранится в cookie).
+	Begin(ctx context.Context, providerID string) (authURL, state string, err error)
+	// Complete завершает вход: проверяет состояние и ID-токен, находит,
+	// привязывает или создает пользователя и выдает одноразовый код входа.
+	Complete(ctx context.Context, providerID, code, stateParam, state string) (string, error)
+	// RedeemLoginCode обменивает одноразовый код на пользователя. mfa -
+	// провайдер подтвердил вход вторым фактором.
+	RedeemLoginCode(ctx context.Context, code string) (user *models.User, mfa bool, err error)
+}
+
+type oidcStateClaims struct {
+	Type     string `json:"typ"`
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+type oidcLoginClaims struct {
+	Type string `json:"typ"`
+	MFA  bool   `json:"mfa,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type oidcProvider struct {
+	*oidc.Provider
+	cfg config.OIDCProviderConfig
+}
+
+type oidcService struct {
+	providers      map[string]*oidcProvider
+	order          []string
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	identityRepo   repository.UserIdentityRepository
+	permissionRepo repository.PermissionRepository
+	auditRepo      repository.AuditRepository
+	authorizer     Authorizer
+	keys           *jwtkeys.KeySet
+	apiURL         string
+	stateTTL       time.Duration
+
+	mu       sync.Mutex
+	redeemed map[string]time.Time
+}
+
+// NewOIDCService создает провайдеров из конфигурации. httpClient может быть
+// nil; в тестах через него подключается mock-сервер провайдера.
+func NewOIDCService(cfg config.OIDCConfig, httpClient *http.Client, userRepo repository.UserRepository, roleRepo repository.RoleRepository, identityRepo repository.UserIdentityRepository, permissionRepo repository.PermissionRepository, auditRepo repository.AuditRepository, authorizer Authorizer, keys *jwtkeys.KeySet, apiURL string) OIDCService {
+	s := &oidcService{
+		providers:      make(map[string]*oidcProvider),
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		identityRepo:   identityRepo,
+		permissionRepo: permissionRepo,
+		auditRepo:      auditRepo,
+		authorizer:     authorizer,
+		keys:           keys,
+		apiURL:         strings.TrimSuffix(apiURL, "/"),
+		stateTTL:       cfg.StateTTL,
+		redeemed:       make(map[string]time.Time),
+	}
+
+	for _, providerCfg := range cfg.Providers {
+		provider := oidc.NewProvider(oidc.Config{
+			ID:           providerCfg.ID,
+			Name:         providerCfg.Name,
+			Issuer:       providerCfg.Issuer,
+			ClientID:     providerCfg.ClientID,
+			ClientSecret: providerCfg.ClientSecret,
+			RedirectURL:  s.apiURL + "/api/auth/oidc/" + providerCfg.ID + "/callback",
+			Scopes:       providerCfg.Scopes,
+			GroupsClaim:  providerCfg.GroupsClaim,
+		}, httpClient)
+
+		s.providers[providerCfg.ID] = &oidcProvider{Provider: provider, cfg: providerCfg}
+		s.order = append(s.order, providerCfg.ID)
+	}
+	return s
+}
+
+func (s *oidcService) Providers() []*models.OIDCProviderInfo {
+	providers := make([]*models.OIDCProviderInfo, 0, len(s.order))
+	for _, id := range s.order {
+		providers = append(providers, &models.OIDCProviderInfo{
+			ID:       id,
+			Name:     s.providers[id].Name(),
+			LoginURL: s.apiURL + "/api/auth/oidc/" + id + "/login",
+		})
+	}
+	return providers
+}
+
+func (s *oidcService) Begin(ctx context.Context, providerID string) (string, string, error) {
+	provider, ok := s.providers[providerID]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	stateParam, err := oidc.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, stateParam, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	state, err := s.keys.Sign(oidcStateClaims{
+		Type:             oidcStateType,
+		Provider:         providerID,
+		State:            stateParam,
+		Nonce:            nonce,
+		Verifier:         verifier,
+		RegisteredClaims: s.keys.Claims(s.keys.Issuer(), "", now, s.stateTTL),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign login state: %w", err)
+	}
+	return authURL, state, nil
+}
+
+func (s *oidcService) Complete(ctx context.Context, providerID, code, stateParam, state string) (string, error) {
+	provider, ok := s.providers[providerID]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	claims := &oidcStateClaims{}
+	err := s.keys.Parse(state, claims, s.keys.Issuer())
+	if err != nil || claims.Type != oidcStateType || claims.Provider != providerID || claims.State != stateParam {
+		return "", ErrInvalidOIDCState
+	}
+
+	identity, err := provider.Exchange(ctx, code, claims.Verifier, claims.Nonce)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return "", err
+	}
+	if !user.CanSignIn(time.Now()) {
+		return "", ErrAccountDisabled
+	}
+
+	if err := s.syncRole(ctx, provider, user, identity.Groups); err != nil {
+		logrus.Errorf("Failed to apply role mapping for user %d: %v", user.ID, err)
+	}
+
+	return s.newLoginCode(user, identity.MFA)
+}
+
+func (s *oidcService) RedeemLoginCode(ctx context.Context, code string) (*models.User, bool, error) {
+	claims := &oidcLoginClaims{}
+	err := s.keys.Parse(code, claims, s.keys.Issuer())
+	if err != nil || claims.Type != oidcLoginType || claims.ID == "" {
+		return nil, false, ErrInvalidLoginCode
+	}
+
+	if !s.markRedeemed(claims.ID, claims.ExpiresAt.Time) {
+		return nil, false, ErrInvalidLoginCode
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, false, ErrInvalidLoginCode
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, false, ErrInvalidLoginCode
+	}
+	return user, claims.MFA, nil
+}
+
+// resolveUser находит пользователя по привязке (provider, sub). Без привязки
+// пользователь ищется по email: привязка к существующей учетной записи
+// возможна, только если и провайдер, и GoIda подтвердили этот email. Иначе
+// создается новый пользователь, если это разрешено для провайдера.
+func (s *oidcService) resolveUser(ctx context.Context, provider *oidcProvider, identity *oidc.Identity) (*models.User, error) {
+	if linked, err := s.identityRepo.GetByProviderSubject(ctx, provider.ID(), identity.Subject); err == nil {
+		if err := s.identityRepo.TouchLogin(ctx, linked.ID, identity.Email); err != nil {
+			logrus.Errorf("Failed to update identity %d: %v", linked.ID, err)
+		}
+		return s.userRepo.GetByID(linked.UserID)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	if err == nil {
+		if !user.EmailVerified {
+			return nil, ErrOIDCAccountConflict
+		}
+	} else {
+		if !provider.cfg.AutoProvision {
+			return nil, ErrOIDCProvisioningDisabled
+		}
+		if user, err = s.provisionUser(provider, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider.ID(),
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return nil, err
+	}
+	logrus.Infof("Linked %s identity %s to user %d", provider.ID(), identity.Subject, user.ID)
+	return user, nil
+}
+
+func (s *oidcService) provisionUser(provider *oidcProvider, identity *oidc.Identity) (*models.User, error) {
+	roleName := s.mappedRole(provider, identity.Groups)
+	if roleName == "" {
+		roleName = provider.cfg.DefaultRole
+	}
+	role, err := s.roleRepo.GetByName(roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role %q: %w", roleName, err)
+	}
+
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	user := &models.User{Email: identity.Email, Name: name, RoleID: role.ID}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetEmailVerified(user.ID, true); err != nil {
+		return nil, err
+	}
+	logrus.Infof("Provisioned user %d from %s identity %s", user.ID, provider.ID(), identity.Subject)
+
+	return s.userRepo.GetByID(user.ID)
+}
+
+// syncRole назначает роль по группам провайдера при каждом входе. Если ни
+// одна группа не сопоставлена, роль пользователя не меняется. Как и
+// назначение роли администратором, сопоставление не может понизить
+// последнего пользователя с правом role.manage.
+func (s *oidcService) syncRole(ctx context.Context, provider *oidcProvider, user *models.User, groups []string) error {
+	roleName := s.mappedRole(provider, groups)
+	if roleName == "" || (user.Role != nil && user.Role.Name == roleName) {
+		return nil
+	}
+
+	role, err := s.roleRepo.GetByName(roleName)
+	if err != nil {
+		return fmt.Errorf("failed to get role %q: %w", roleName, err)
+	}
+
+	previous := ""
+	if user.Role != nil {
+		previous = user.Role.Name
+		willManage, err := s.authorizer.Can(ctx, role.Name, models.PermRoleManage)
+		if err != nil {
+			return err
+		}
+		if !willManage {
+			last, err := isLastRoleManager(ctx, s.authorizer, s.permissionRepo, user)
+			if err != nil {
+				return err
+			}
+			if last {
+				return ErrLastAdmin
+			}
+		}
+	}
+
+	user.RoleID = role.ID
+	user.Role = role
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	s.authorizer.ForgetUser(user.ID)
+
+	event := &models.AuditEvent{
+		UserID:  &user.ID,
+		Action:  models.AuditRoleChanged,
+		Details: map[string]interface{}{"from": previous, "to": role.Name, "provider": provider.ID()},
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", event.Action, user.ID, err)
+	}
+	return nil
+}
+
+func (s *oidcService) mappedRole(provider *oidcProvider, groups []string) string {
+	for _, mapping := range provider.cfg.RoleMap {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role
+			}
+		}
+	}
+	return ""
+}
+
+func (s *oidcService) newLoginCode(user *models.User, mfa bool) (string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	registered := s.keys.Claims(s.keys.Issuer(), strconv.Itoa(user.ID), now, oidcLoginCodeTTL)
+	registered.ID = id
+	code, err := s.keys.Sign(oidcLoginClaims{
+		Type:             oidcLoginType,
+		MFA:              mfa,
+		RegisteredClaims: registered,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign login code: %w", err)
+	}
+	return code, nil
+}
+
+// markRedeemed делает код одноразовым в пределах экземпляра приложения.
+func (s *oidcService) markRedeemed(id string, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for codeID, expiry := range s.redeemed {
+		if now.After(expiry) {
+			delete(s.redeemed, codeID)
+		}
+	}
+
+	if _, ok := s.redeemed[id]; ok {
+		return false
+	}
+	s.redeemed[id] = expiresAt
+	return true
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"goida/internal/config"
+	"goida/internal/jwtkeys"
+	"goida/internal/models"
+	"goida/internal/oidc"
+	"goida/internal/oidc/oidctest"
+	"goida/internal/repository"
+)
+
+// Хранилища в памяти: реализованы только методы, которые вызывает вход
+// через OIDC; вызов остальных паникует на nil-интерфейсе.
+
+type fakeRoleRepository struct {
+	repository.RoleRepository
+	roles []*models.Role
+}
+
+func (r *fakeRoleRepository) GetByName(name string) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, fmt.Errorf("role not found")
+}
+
+func (r *fakeRoleRepository) byID(id int) *models.Role {
+	for _, role := range r.roles {
+		if role.ID == id {
+			return role
+		}
+	}
+	return nil
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	roles *fakeRoleRepository
+	users map[int]*models.User
+}
+
+func (r *fakeUserRepository) GetByID(id int) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	copied := *user
+	copied.Role = r.roles.byID(user.RoleID)
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*models.User, error) {
+	for id, user := range r.users {
+		if user.Email == email {
+			return r.GetByID(id)
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (r *fakeUserRepository) Create(user *models.User) error {
+	user.ID = len(r.users) + 1
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) Update(user *models.User) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) SetEmailVerified(id int, verified bool) error {
+	r.users[id].EmailVerified = verified
+	return nil
+}
+
+type fakeIdentityRepository struct {
+	repository.UserIdentityRepository
+	identities []*models.UserIdentity
+}
+
+func (r *fakeIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = len(r.identities) + 1
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, fmt.Errorf("identity not found")
+}
+
+func (r *fakeIdentityRepository) TouchLogin(ctx context.Context, id int, email string) error {
+	return nil
+}
+
+type oidcFixture struct {
+	service    OIDCService
+	server     *oidctest.Server
+	users      *fakeUserRepository
+	identities *fakeIdentityRepository
+	audit      *fakeAuditRepository
+	keys       *jwtkeys.KeySet
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	server := oidctest.NewServer("goida", "secret")
+	t.Cleanup(server.Close)
+
+	keys, err := jwtkeys.NewEphemeral("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roles := &fakeRoleRepository{roles: []*models.Role{
+		{ID: 1, Name: "admin"},
+		{ID: 2, Name: "user"},
+		{ID: 3, Name: "moderator"},
+	}}
+	f := &oidcFixture{
+		server:     server,
+		users:      &fakeUserRepository{roles: roles, users: map[int]*models.User{}},
+		identities: &fakeIdentityRepository{},
+		audit:      &fakeAuditRepository{},
+		keys:       keys,
+	}
+	authorizer := roleAuthorizer{permissions: map[string][]string{"admin": {models.PermRoleManage}}}
+	f.service = NewOIDCService(config.OIDCConfig{
+		StateTTL: 10 * time.Minute,
+		Providers: []config.OIDCProviderConfig{{
+			ID:           "corp",
+			Issuer:       server.URL,
+			ClientID:     "goida",
+			ClientSecret: "secret",
+			RoleMap: []config.OIDCRoleMapping{
+				{Group: "goida-admins", Role: "admin"},
+				{Group: "editors", Role: "moderator"},
+			},
+			DefaultRole:   "user",
+			AutoProvision: true,
+		}},
+	}, server.Client(), f.users, roles, f.identities, &fakePermissionRepository{managers: 1}, f.audit, authorizer, keys, "http://localhost:8080")
+	return f
+}
+
+// login проходит вход целиком: Begin, вход у провайдера и Complete.
+func (f *oidcFixture) login(t *testing.T, user oidctest.User) (string, error) {
+	t.Helper()
+	authURL, state, err := f.service.Begin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, stateParam, err := f.server.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return f.service.Complete(context.Background(), "corp", code, stateParam, state)
+}
+
+func (f *oidcFixture) redeem(t *testing.T, code string) *models.User {
+	t.Helper()
+	user, _, err := f.service.RedeemLoginCode(context.Background(), code)
+	if err != nil {
+		t.Fatalf("RedeemLoginCode: %v", err)
+	}
+	return user
+}
+
+func TestOIDCLoginProvisionsUserWithMappedRole(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	code, err := f.login(t, oidctest.User{
+		Subject:       "u-1",
+		Email:         "ivan@example.com",
+		EmailVerified: true,
+		Name:          "Ivan",
+		Groups:        []string{"editors", "goida-admins"},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	user := f.redeem(t, code)
+	if user.Email != "ivan@example.com" || user.Name != "Ivan" || !user.EmailVerified {
+		t.Errorf("user = %+v", user)
+	}
+	// Правила проверяются по порядку: goida-admins описано раньше editors.
+	if user.Role == nil || user.Role.Name != "admin" {
+		t.Errorf("role = %+v, want admin", user.Role)
+	}
+	if len(f.identities.identities) != 1 || f.identities.identities[0].Subject != "u-1" {
+		t.Errorf("identities = %+v", f.identities.identities)
+	}
+
+	if _, _, err := f.service.RedeemLoginCode(context.Background(), code); !errors.Is(err, ErrInvalidLoginCode) {
+		t.Errorf("second redeem err = %v, want ErrInvalidLoginCode", err)
+	}
+}
+
+func TestOIDCLoginUsesDefaultRoleWithoutMatchingGroups(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	code, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true, Groups: []string{"staff"}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if user := f.redeem(t, code); user.Role == nil || user.Role.Name != "user" {
+		t.Errorf("role = %+v, want user", user.Role)
+	}
+}
+
+func TestOIDCLoginSyncsRoleFromGroups(t *testing.T) {
+	f := newOIDCFixture(t)
+	login := oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true}
+
+	code, err := f.login(t, login)
+	if err != nil {
+		t.Fatalf("first Complete: %v", err)
+	}
+	f.redeem(t, code)
+
+	login.Groups = []string{"editors"}
+	code, err = f.login(t, login)
+	if err != nil {
+		t.Fatalf("second Complete: %v", err)
+	}
+	if user := f.redeem(t, code); user.Role == nil || user.Role.Name != "moderator" {
+		t.Errorf("role = %+v, want moderator", user.Role)
+	}
+	if len(f.audit.events) != 1 {
+		t.Fatalf("audit events = %d, want 1", len(f.audit.events))
+	}
+	if event := f.audit.events[0]; event.Action != models.AuditRoleChanged || event.Details["to"] != "moderator" || event.Details["provider"] != "corp" {
+		t.Errorf("audit event = %+v", event)
+	}
+
+	// Без сопоставленных групп роль не меняется.
+	login.Groups = []string{"staff"}
+	code, err = f.login(t, login)
+	if err != nil {
+		t.Fatalf("third Complete: %v", err)
+	}
+	if user := f.redeem(t, code); user.Role == nil || user.Role.Name != "moderator" {
+		t.Errorf("role = %+v, want moderator", user.Role)
+	}
+}
+
+func TestOIDCLoginReportsMFA(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	code, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true, AMR: []string{"pwd", "mfa"}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, mfa, err := f.service.RedeemLoginCode(context.Background(), code); err != nil || !mfa {
+		t.Errorf("mfa = %v, err = %v", mfa, err)
+	}
+}
+
+// Код входа подписан ключами приложения, но не является access-токеном.
+func TestOIDCLoginCodeIsNotAccessToken(t *testing.T) {
+	f := newOIDCFixture(t)
+	code, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	auth := NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, f.keys, config.AuthConfig{TokenAudience: "goida-api"})
+	if _, err := auth.ValidateToken(code); err == nil {
+		t.Error("login code accepted as access token")
+	}
+	if _, err := auth.CompleteTwoFactor(context.Background(), code, "000000", models.ClientInfo{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("login code as 2FA challenge: err = %v, want ErrInvalidChallenge", err)
+	}
+}
+
+func TestOIDCCompleteRejectsStateMismatch(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	authURL, state, err := f.service.Begin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, _, err := f.server.Authorize(authURL, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	if _, err := f.service.Complete(context.Background(), "corp", code, "forged", state); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("forged state param: err = %v, want ErrInvalidOIDCState", err)
+	}
+	if _, err := f.service.Complete(context.Background(), "corp", code, "forged", "not-a-token"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("forged state cookie: err = %v, want ErrInvalidOIDCState", err)
+	}
+	if len(f.users.users) != 0 {
+		t.Errorf("users created on rejected login: %d", len(f.users.users))
+	}
+}
+
+func TestOIDCCompleteRejectsNonceMismatch(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	_, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true, Nonce: "replayed"})
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want oidc.ErrInvalidIDToken", err)
+	}
+}
+
+func TestOIDCCompleteRejectsUnverifiedEmail(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	_, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: false})
+	if !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("err = %v, want ErrOIDCEmailNotVerified", err)
+	}
+	if len(f.users.users) != 0 || len(f.identities.identities) != 0 {
+		t.Errorf("login with unverified email created users %d, identities %d", len(f.users.users), len(f.identities.identities))
+	}
+}
+
+func TestOIDCCompleteRejectsUnverifiedLocalAccount(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.users.users[1] = &models.User{ID: 1, Email: "ivan@example.com", RoleID: 2}
+
+	_, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true})
+	if !errors.Is(err, ErrOIDCAccountConflict) {
+		t.Fatalf("err = %v, want ErrOIDCAccountConflict", err)
+	}
+}
+
+func TestOIDCLoginKeepsLastRoleManager(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.users.users[1] = &models.User{ID: 1, Email: "ivan@example.com", RoleID: 1, EmailVerified: true}
+
+	code, err := f.login(t, oidctest.User{Subject: "u-1", Email: "ivan@example.com", EmailVerified: true, Groups: []string{"editors"}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if user := f.redeem(t, code); user.Role == nil || user.Role.Name != "admin" {
+		t.Errorf("role = %+v, want admin", user.Role)
+	}
+	if len(f.audit.events) != 0 {
+		t.Errorf("audit events = %d, want 0", len(f.audit.events))
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/config"
+	"goida/internal/mail"
+	"goida/internal/models"
+	"goida/internal/password"
+	"goida/internal/repository"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, email, clientIP string) error
+	ResetPassword(ctx context.Context, token, password, clientIP string) error
+}
+
+type passwordResetService struct {
+	userRepo            repository.UserRepository
+	authCredentialsRepo repository.AuthCredentialsRepository
+	resetRepo           repository.PasswordResetRepository
+	sessionRepo         repository.SessionRepository
+	auditRepo           repository.AuditRepository
+	loginLimiter        *LoginLimiter
+	hasher              *password.Hasher
+	passwordPolicy      *password.Policy
+	mailer              mail.Mailer
+	publicURL           string
+	tokenTTL            time.Duration
+	emailLimit          *requestLimiter
+	ipLimit             *requestLimiter
+}
+
+func NewPasswordResetService(
+	userRepo repository.UserRepository,
+	authCredentialsRepo repository.AuthCredentialsRepository,
+	resetRepo repository.PasswordResetRepository,
+	sessionRepo repository.SessionRepository,
+	auditRepo repository.AuditRepository,
+	loginLimiter *LoginLimiter,
+	hasher *password.Hasher,
+	passwordPolicy *password.Policy,
+	mailer mail.Mailer,
+	publicURL string,
+	cfg config.AuthConfig,
+) PasswordResetService {
+	return &passwordResetService{
+		userRepo:            userRepo,
+		authCredentialsRepo: authCredentialsRepo,
+		resetRepo:           resetRepo,
+		sessionRepo:         sessionRepo,
+		auditRepo:           auditRepo,
+		loginLimiter:        loginLimiter,
+		hasher:              hasher,
+		passwordPolicy:      passwordPolicy,
+		mailer:              mailer,
+		publicURL:           publicURL,
+		tokenTTL:            cfg.ResetTokenTTL,
+		emailLimit:          newRequestLimiter(cfg.ResetEmailLimit, cfg.ResetLimitWindow),
+		ipLimit:             newRequestLimiter(cfg.ResetIPLimit, cfg.ResetLimitWindow),
+	}
+}
+
+// RequestReset отправляет ссылку для сброса пароля. Запросы ограничены по
+// email и по адресу клиента; ограничение по email действует и для
+// незарегистрированных адресов. Поиск пользователя, выпуск токена и отправка
+// письма выполняются в фоне, поэтому ни ответ, ни время ответа не выдают,
+// зарегистрирован ли адрес.
+func (s *passwordResetService) RequestReset(ctx context.Context, email, clientIP string) error {
+	now := time.Now()
+	if wait := s.ipLimit.allow(clientIP, now); wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+	if wait := s.emailLimit.allow(strings.ToLower(email), now); wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	go func() {
+		if err := s.sendReset(context.Background(), email); err != nil {
+			logrus.Errorf("Failed to request password reset: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *passwordResetService) sendReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	if _, err := s.authCredentialsRepo.GetByUserID(user.ID); err != nil {
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	err = s.resetRepo.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля GoIda",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s/reset-password?token=%s\n\nСсылка действует %s и может быть использована один раз. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Name, s.publicURL, url.QueryEscape(token), s.tokenTTL,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send password reset mail to user %d: %w", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль и отзывает все сессии
+// пользователя: если пароль сбрасывают из-за утечки, старые токены тоже
+// нельзя оставлять действующими.
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, newPassword, clientIP string) error {
+	// Пароль проверяется до использования токена, чтобы после отказа по
+	// политике не пришлось запрашивать новую ссылку.
+	userID, err := s.resetRepo.Lookup(ctx, hashToken(token))
+	if err != nil {
+		if err.Error() == "password reset token not found" {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	credentials, err := s.authCredentialsRepo.GetByUserID(userID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.passwordPolicy.Check(newPassword, credentials.Login, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := s.resetRepo.Consume(ctx, hashToken(token)); err != nil {
+		if err.Error() == "password reset token not found" {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	credentials.Password = hashedPassword
+
+	if err := s.authCredentialsRepo.Update(userID, credentials); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.loginLimiter.RegisterSuccess(ctx, credentials.Login); err != nil {
+		logrus.Errorf("Failed to reset login attempts: %v", err)
+	}
+
+	event := &models.AuditEvent{UserID: &userID, Action: models.AuditPasswordResetEmail, IP: clientIP}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", event.Action, userID, err)
+	}
+	return nil
+}
+
+// requestLimiter допускает не больше limit запросов на ключ за окно window.
+// limit <= 0 отключает ограничение.
+type requestLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*requestWindow
+	lastSweep time.Time
+}
+
+type requestWindow struct {
+	start time.Time
+	count int
+}
+
+func newRequestLimiter(limit int, window time.Duration) *requestLimiter {
+	return &requestLimiter{limit: limit, window: window, windows: make(map[string]*requestWindow)}
+}
+
+// allow учитывает запрос и возвращает 0 или время до начала следующего окна,
+// если лимит исчерпан.
+func (l *requestLimiter) allow(key string, now time.Time) time.Duration {
+	if l.limit <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Закончившиеся окна удаляются не чаще раза за окно.
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &requestWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return 0
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestLimiterAllowsLimitPerWindow(t *testing.T) {
+	limiter := newRequestLimiter(2, time.Hour)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if wait := limiter.allow("ivan@example.com", now); wait != 0 {
+			t.Fatalf("request %d: wait = %s, want 0", i+1, wait)
+		}
+	}
+	if wait := limiter.allow("ivan@example.com", now.Add(10*time.Minute)); wait != 50*time.Minute {
+		t.Errorf("over limit: wait = %s, want 50m", wait)
+	}
+	if wait := limiter.allow("petr@example.com", now); wait != 0 {
+		t.Errorf("other key: wait = %s, want 0", wait)
+	}
+	if wait := limiter.allow("ivan@example.com", now.Add(time.Hour)); wait != 0 {
+		t.Errorf("next window: wait = %s, want 0", wait)
+	}
+}
+
+func TestRequestLimiterSweepsExpiredWindows(t *testing.T) {
+	limiter := newRequestLimiter(1, time.Minute)
+	now := time.Now()
+
+	limiter.allow("a", now)
+	limiter.allow("b", now)
+	limiter.allow("c", now.Add(2*time.Minute))
+	if len(limiter.windows) != 1 {
+		t.Errorf("windows = %d, want 1 after sweep", len(limiter.windows))
+	}
+}
+
+func TestRequestLimiterDisabled(t *testing.T) {
+	limiter := newRequestLimiter(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		if wait := limiter.allow("a", time.Now()); wait != 0 {
+			t.Fatalf("wait = %s, want 0", wait)
+		}
+	}
+}
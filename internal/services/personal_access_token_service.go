@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid personal access token")
+	ErrAccessTokenNotFound = errors.New("personal access token not found")
+)
+
+const (
+	defaultAccessTokenLifetime = 30 * 24 * time.Hour
+	accessTokenHintLength      = len(models.PersonalAccessTokenPrefix) + 4
+	// accessTokenTouchInterval ограничивает частоту записи last_used_at.
+	accessTokenTouchInterval = time.Minute
+)
+
+type PersonalAccessTokenService interface {
+	Create(ctx context.Context, userID int, req *models.CreatePersonalAccessTokenRequest, clientIP string) (*models.CreatePersonalAccessTokenResponse, error)
+	List(ctx context.Context, userID int) ([]*models.PersonalAccessToken, error)
+	Revoke(ctx context.Context, actorID, userID int, id int64, clientIP string) error
+	Authenticate(ctx context.Context, token, clientIP string) (*Claims, error)
+}
+
+type personalAccessTokenService struct {
+	tokenRepo repository.PersonalAccessTokenRepository
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+}
+
+func NewPersonalAccessTokenService(tokenRepo repository.PersonalAccessTokenRepository, userRepo repository.UserRepository, auditRepo repository.AuditRepository) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// Create выпускает токен. Открытое значение возвращается только здесь, в БД
+// хранится его хеш.
+func (s *personalAccessTokenService) Create(ctx context.Context, userID int, req *models.CreatePersonalAccessTokenRequest, clientIP string) (*models.CreatePersonalAccessTokenResponse, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate personal access token: %w", err)
+	}
+	plain := models.PersonalAccessTokenPrefix + secret
+
+	lifetime := defaultAccessTokenLifetime
+	if req.ExpiresInDays > 0 {
+		lifetime = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hashToken(plain),
+		Hint:      plain[:accessTokenHintLength],
+		Scopes:    uniqueScopes(req.Scopes),
+		ExpiresAt: time.Now().Add(lifetime),
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, userID, userID, models.AuditTokenCreated, map[string]interface{}{
+		"token_id": token.ID,
+		"name":     token.Name,
+		"scopes":   token.Scopes,
+	}, clientIP)
+	return &models.CreatePersonalAccessTokenResponse{PersonalAccessToken: *token, Token: plain}, nil
+}
+
+func (s *personalAccessTokenService) List(ctx context.Context, userID int) ([]*models.PersonalAccessToken, error) {
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+// Revoke отзывает токен пользователя userID. actorID - владелец или
+// администратор, выполнивший отзыв.
+func (s *personalAccessTokenService) Revoke(ctx context.Context, actorID, userID int, id int64, clientIP string) error {
+	revoked, err := s.tokenRepo.Revoke(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAccessTokenNotFound
+	}
+
+	s.audit(ctx, userID, actorID, models.AuditTokenRevoked, map[string]interface{}{"token_id": id}, clientIP)
+	return nil
+}
+
+// Authenticate проверяет токен и возвращает Claims его владельца с областями
+// доступа токена. Роль берется из текущей записи пользователя.
+func (s *personalAccessTokenService) Authenticate(ctx context.Context, plain, clientIP string) (*Claims, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, hashToken(plain))
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil || !user.CanSignIn(now) || user.IsDeactivated() {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, token.ID, clientIP, now, now.Add(-accessTokenTouchInterval)); err != nil {
+		logrus.Errorf("Failed to update personal access token %d last use: %v", token.ID, err)
+	}
+
+	claims := &Claims{
+		UserID:  user.ID,
+		Email:   user.Email,
+		TokenID: token.ID,
+		Scopes:  token.Scopes,
+	}
+	if user.Role != nil {
+		claims.Role = user.Role.Name
+	}
+	return claims, nil
+}
+
+func (s *personalAccessTokenService) audit(ctx context.Context, userID, actorID int, action string, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", action, userID, err)
+	}
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	var result []string
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrBuiltinRole       = errors.New("built-in role cannot be renamed or deleted")
+	ErrLastAdmin         = errors.New("at least one user must keep the role.manage permission")
+	ErrInvalidRoleName   = errors.New("role name may contain only lowercase letters, digits, '-' and '_'")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserOutranks      = errors.New("user has permissions the actor lacks")
+	ErrPermissionNotHeld = errors.New("cannot grant permissions the actor lacks")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+type RoleService interface {
+	List(ctx context.Context) ([]*models.Role, error)
+	Get(ctx context.Context, id int) (*models.Role, error)
+	// Create и Update не дают роли прав, которых нет у роли actorID.
+	Create(ctx context.Context, actorID int, req *models.CreateRoleRequest) (*models.Role, error)
+	Update(ctx context.Context, actorID, id int, req *models.UpdateRoleRequest) (*models.Role, error)
+	Delete(ctx context.Context, id int) error
+	// AssignRole назначает пользователю роль. Новая роль действует сразу,
+	// без ожидания истечения access-токена.
+	AssignRole(ctx context.Context, actorID, userID int, roleName, clientIP string) (*models.User, error)
+}
+
+type roleService struct {
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	userRepo       repository.UserRepository
+	auditRepo      repository.AuditRepository
+	authorizer     Authorizer
+}
+
+func NewRoleService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, userRepo repository.UserRepository, auditRepo repository.AuditRepository, authorizer Authorizer) RoleService {
+	return &roleService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		authorizer:     authorizer,
+	}
+}
+
+func (s *roleService) List(ctx context.Context) ([]*models.Role, error) {
+	roles, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Permissions, err = s.permissionRepo.ListByRole(ctx, role.ID); err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+func (s *roleService) Get(ctx context.Context, id int) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.Permissions, err = s.permissionRepo.ListByRole(ctx, role.ID); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *roleService) Create(ctx context.Context, actorID int, req *models.CreateRoleRequest) (*models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrInvalidRoleName
+	}
+	if _, err := s.roleRepo.GetByName(req.Name); err == nil {
+		return nil, ErrRoleExists
+	}
+	if err := checkGrantable(ctx, s.authorizer, actorID, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{Name: req.Name, Description: req.Description}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	if err := s.permissionRepo.SetRolePermissions(ctx, role.ID, req.Permissions); err != nil {
+		return nil, err
+	}
+	s.authorizer.Reload()
+
+	return s.Get(ctx, role.ID)
+}
+
+func (s *roleService) Update(ctx context.Context, actorID, id int, req *models.UpdateRoleRequest) (*models.Role, error) {
+	role, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && *req.Name != role.Name {
+		if models.IsBuiltinRole(role.Name) {
+			return nil, ErrBuiltinRole
+		}
+		if !roleNamePattern.MatchString(*req.Name) {
+			return nil, ErrInvalidRoleName
+		}
+		if _, err := s.roleRepo.GetByName(*req.Name); err == nil {
+			return nil, ErrRoleExists
+		}
+		role.Name = *req.Name
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+
+	if req.Permissions != nil {
+		// Проверяются только добавляемые права: снять с роли право, которого
+		// у самого администратора нет, не значит его получить.
+		var added []string
+		for _, permission := range *req.Permissions {
+			if !containsString(role.Permissions, permission) {
+				added = append(added, permission)
+			}
+		}
+		if err := checkGrantable(ctx, s.authorizer, actorID, added); err != nil {
+			return nil, err
+		}
+		if err := s.checkKeepsRoleManager(ctx, role, *req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	if req.Permissions != nil {
+		if err := s.permissionRepo.SetRolePermissions(ctx, role.ID, *req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	s.authorizer.Reload()
+
+	return s.Get(ctx, role.ID)
+}
+
+// Delete удаляет роль, которая никому не назначена.
+func (s *roleService) Delete(ctx context.Context, id int) error {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	if models.IsBuiltinRole(role.Name) {
+		return ErrBuiltinRole
+	}
+
+	users, err := s.roleRepo.CountUsers(id)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.roleRepo.Delete(id); err != nil {
+		return err
+	}
+	s.authorizer.Reload()
+	return nil
+}
+
+func (s *roleService) AssignRole(ctx context.Context, actorID, userID int, roleName, clientIP string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	role, err := s.roleRepo.GetByName(roleName)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	if user.RoleID == role.ID {
+		return user, nil
+	}
+
+	// Нельзя менять роль тому, у кого прав больше, и назначать роль с
+	// правами, которых нет у самого администратора.
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+	rolePermissions, err := s.authorizer.Permissions(ctx, role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantable(ctx, s.authorizer, actorID, rolePermissions); err != nil {
+		return nil, err
+	}
+
+	willManage, err := s.authorizer.Can(ctx, role.Name, models.PermRoleManage)
+	if err != nil {
+		return nil, err
+	}
+	if !willManage {
+		last, err := isLastRoleManager(ctx, s.authorizer, s.permissionRepo, user)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	previous := user.Role.Name
+	user.RoleID = role.ID
+	user.Role = role
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	s.authorizer.ForgetUser(userID)
+
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  models.AuditRoleChanged,
+		Details: map[string]interface{}{"from": previous, "to": role.Name},
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", event.Action, userID, err)
+	}
+
+	return user, nil
+}
+
+// checkKeepsRoleManager не дает отнять право role.manage у единственной роли,
+// через которую кто-то еще может управлять ролями.
+func (s *roleService) checkKeepsRoleManager(ctx context.Context, role *models.Role, permissions []string) error {
+	if !containsString(role.Permissions, models.PermRoleManage) || containsString(permissions, models.PermRoleManage) {
+		return nil
+	}
+
+	others, err := s.permissionRepo.CountUsersWithPermission(ctx, models.PermRoleManage, role.ID)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// isLastRoleManager сообщает, что user - единственный активный пользователь с
+// правом role.manage: понизив, удалив или заблокировав его, управлять ролями
+// станет некому.
+func isLastRoleManager(ctx context.Context, authorizer Authorizer, permissionRepo repository.PermissionRepository, user *models.User) (bool, error) {
+	if !user.CanSignIn(time.Now()) || user.DeletionScheduledAt != nil {
+		return false, nil
+	}
+	canManage, err := authorizer.Can(ctx, user.Role.Name, models.PermRoleManage)
+	if err != nil || !canManage {
+		return false, err
+	}
+
+	managers, err := permissionRepo.CountUsersWithPermission(ctx, models.PermRoleManage, 0)
+	if err != nil {
+		return false, err
+	}
+	return managers <= 1, nil
+}
+
+// checkOutranks возвращает ErrUserOutranks, если у роли target есть право,
+// которого нет у роли actorID: иначе, сменив email или пароль такого
+// пользователя, можно получить его права.
+func checkOutranks(ctx context.Context, authorizer Authorizer, actorID int, target *models.User) error {
+	if target.Role == nil {
+		return nil
+	}
+	targetPermissions, err := authorizer.Permissions(ctx, target.Role.Name)
+	if err != nil {
+		return err
+	}
+	missing, err := missingPermissions(ctx, authorizer, actorID, targetPermissions)
+	if err != nil {
+		return err
+	}
+	if missing {
+		return ErrUserOutranks
+	}
+	return nil
+}
+
+// checkGrantable возвращает ErrPermissionNotHeld, если среди permissions есть
+// право, которого нет у роли actorID: иначе через новую роль можно выдать
+// себе или другому больше прав, чем есть у себя.
+func checkGrantable(ctx context.Context, authorizer Authorizer, actorID int, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	missing, err := missingPermissions(ctx, authorizer, actorID, permissions)
+	if err != nil {
+		return err
+	}
+	if missing {
+		return ErrPermissionNotHeld
+	}
+	return nil
+}
+
+// missingPermissions сообщает, есть ли среди permissions право, которого нет
+// у роли actorID.
+func missingPermissions(ctx context.Context, authorizer Authorizer, actorID int, permissions []string) (bool, error) {
+	actorRole, err := authorizer.UserRole(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	actorPermissions, err := authorizer.Permissions(ctx, actorRole)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !containsString(actorPermissions, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+// roleAuthorizer выдает права по названию роли, а роль - по id пользователя.
+type roleAuthorizer struct {
+	Authorizer
+	permissions map[string][]string
+	userRoles   map[int]string
+}
+
+func (a roleAuthorizer) Can(ctx context.Context, role, permission string) (bool, error) {
+	return containsString(a.permissions[role], permission), nil
+}
+
+func (a roleAuthorizer) Permissions(ctx context.Context, role string) ([]string, error) {
+	return a.permissions[role], nil
+}
+
+func (a roleAuthorizer) UserRole(ctx context.Context, userID int) (string, error) {
+	return a.userRoles[userID], nil
+}
+
+func (roleAuthorizer) ForgetUser(userID int) {}
+
+type fakePermissionRepository struct {
+	repository.PermissionRepository
+	managers int
+}
+
+func (r *fakePermissionRepository) CountUsersWithPermission(ctx context.Context, permission string, exceptRoleID int) (int, error) {
+	return r.managers, nil
+}
+
+type fakeAuditRepository struct {
+	repository.AuditRepository
+	events []*models.AuditEvent
+}
+
+func (r *fakeAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type roleFixture struct {
+	service    RoleService
+	users      *fakeUserRepository
+	audit      *fakeAuditRepository
+	authorizer roleAuthorizer
+}
+
+// newRoleFixture: 1 - администратор пользователей (user.manage), 2 - обычный
+// пользователь, 3 - администратор со всеми правами.
+func newRoleFixture() *roleFixture {
+	roles := &fakeRoleRepository{roles: []*models.Role{
+		{ID: 1, Name: "user"},
+		{ID: 2, Name: "moderator"},
+		{ID: 3, Name: "user-admin"},
+		{ID: 4, Name: "admin"},
+	}}
+	users := &fakeUserRepository{roles: roles, users: map[int]*models.User{
+		1: {ID: 1, RoleID: 3},
+		2: {ID: 2, RoleID: 1},
+		3: {ID: 3, RoleID: 4},
+	}}
+	authorizer := roleAuthorizer{
+		permissions: map[string][]string{
+			"moderator":  {models.PermCommentDeleteAny},
+			"user-admin": {models.PermUserManage, models.PermCommentDeleteAny},
+			"admin":      {models.PermUserManage, models.PermCommentDeleteAny, models.PermRoleManage},
+		},
+		userRoles: map[int]string{1: "user-admin", 2: "user", 3: "admin"},
+	}
+	audit := &fakeAuditRepository{}
+	service := NewRoleService(roles, &fakePermissionRepository{managers: 1}, users, audit, authorizer)
+	return &roleFixture{service: service, users: users, audit: audit, authorizer: authorizer}
+}
+
+func TestAssignRoleRequiresActorToHoldRolePermissions(t *testing.T) {
+	f := newRoleFixture()
+	ctx := context.Background()
+
+	if _, err := f.service.AssignRole(ctx, 1, 2, "moderator", ""); err != nil {
+		t.Fatalf("assign role with held permissions: %v", err)
+	}
+	if _, err := f.service.AssignRole(ctx, 1, 2, "admin", ""); !errors.Is(err, ErrPermissionNotHeld) {
+		t.Errorf("assign admin: err = %v, want ErrPermissionNotHeld", err)
+	}
+	if _, err := f.service.AssignRole(ctx, 1, 3, "user", ""); !errors.Is(err, ErrUserOutranks) {
+		t.Errorf("demote admin: err = %v, want ErrUserOutranks", err)
+	}
+	if got := f.users.users[2].RoleID; got != 2 {
+		t.Errorf("user 2 role = %d, want moderator", got)
+	}
+	if len(f.audit.events) != 1 {
+		t.Errorf("audit events = %d, want 1", len(f.audit.events))
+	}
+}
+
+func TestCreateRoleRejectsPermissionsActorLacks(t *testing.T) {
+	f := newRoleFixture()
+	req := &models.CreateRoleRequest{Name: "auditor", Permissions: []string{models.PermAuditRead}}
+
+	if _, err := f.service.Create(context.Background(), 1, req); !errors.Is(err, ErrPermissionNotHeld) {
+		t.Errorf("err = %v, want ErrPermissionNotHeld", err)
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+	"goida/internal/totp"
+)
+
+const (
+	recoveryCodeCount         = 10
+	settingRequire2FAForAdmin = "require_2fa_for_admin"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for this role")
+)
+
+type TwoFactorService interface {
+	Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error)
+	Enroll(ctx context.Context, user *models.User) (*models.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, role string, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	Verify(ctx context.Context, userID int, code string) error
+	GetPolicy(ctx context.Context) (*models.TwoFactorPolicy, error)
+	SetPolicy(ctx context.Context, policy *models.TwoFactorPolicy) error
+	RequiredForRole(ctx context.Context, role string) (bool, error)
+}
+
+type twoFactorService struct {
+	twoFactorRepo repository.TwoFactorRepository
+	settingsRepo  repository.SettingsRepository
+	authorizer    Authorizer
+	issuer        string
+}
+
+func NewTwoFactorService(twoFactorRepo repository.TwoFactorRepository, settingsRepo repository.SettingsRepository, authorizer Authorizer, issuer string) TwoFactorService {
+	return &twoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		settingsRepo:  settingsRepo,
+		authorizer:    authorizer,
+		issuer:        issuer,
+	}
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Enabled: enabled}
+	if enabled {
+		if status.RecoveryCodesLeft, err = s.twoFactorRepo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll генерирует новый секрет. 2FA включается только после Confirm, так
+// что незавершенное подключение не мешает входу.
+func (s *twoFactorService) Enroll(ctx context.Context, user *models.User) (*models.TwoFactorEnrollment, error) {
+	enabled, err := s.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.SaveTOTP(ctx, &models.TOTPSecret{UserID: user.ID, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *twoFactorService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	secret, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if secret.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, secret, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.ConfirmTOTP(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userID int, role string, code string) error {
+	required, err := s.RequiredForRole(ctx, role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return s.twoFactorRepo.DeleteTOTP(ctx, userID)
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	secret, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil || secret.ConfirmedAt == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyTOTP(ctx, secret, code); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *twoFactorService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	secret, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if err.Error() == "totp secret not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	return secret.ConfirmedAt != nil, nil
+}
+
+// Verify принимает либо текущий TOTP-код, либо неиспользованный код
+// восстановления.
+func (s *twoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	secret, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil || secret.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if _, err := strconv.Atoi(code); err == nil && len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, secret, code)
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *twoFactorService) GetPolicy(ctx context.Context) (*models.TwoFactorPolicy, error) {
+	value, err := s.settingsRepo.Get(ctx, settingRequire2FAForAdmin)
+	if err != nil && err.Error() != "setting not found" {
+		return nil, err
+	}
+	return &models.TwoFactorPolicy{RequireForAdmin: value == "true"}, nil
+}
+
+func (s *twoFactorService) SetPolicy(ctx context.Context, policy *models.TwoFactorPolicy) error {
+	return s.settingsRepo.Set(ctx, settingRequire2FAForAdmin, strconv.FormatBool(policy.RequireForAdmin))
+}
+
+// RequiredForRole - политика распространяется на роли с административными
+// правами.
+func (s *twoFactorService) RequiredForRole(ctx context.Context, role string) (bool, error) {
+	privileged, err := s.authorizer.IsPrivileged(ctx, role)
+	if err != nil || !privileged {
+		return false, err
+	}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return policy.RequireForAdmin, nil
+}
+
+func (s *twoFactorService) verifyTOTP(ctx context.Context, secret *models.TOTPSecret, code string) error {
+	step, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := s.twoFactorRepo.UseTOTPStep(ctx, secret.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *twoFactorService) issueRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+	"goida/internal/totp"
+)
+
+// fakeTwoFactorRepository повторяет условие last_used_step < step из
+// репозитория.
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	secret *models.TOTPSecret
+}
+
+func (r *fakeTwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*models.TOTPSecret, error) {
+	return r.secret, nil
+}
+
+func (r *fakeTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	if r.secret.LastUsedStep >= step {
+		return false, nil
+	}
+	r.secret.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func TestVerifyRejectsReusedTOTPCode(t *testing.T) {
+	confirmedAt := time.Now()
+	repo := &fakeTwoFactorRepository{secret: &models.TOTPSecret{UserID: 1, Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ConfirmedAt: &confirmedAt}}
+	service := NewTwoFactorService(repo, nil, nil, "GoIda")
+	ctx := context.Background()
+
+	step := totp.Step(time.Now())
+	previous, _ := totp.Code(repo.secret.Secret, step-1)
+	current, _ := totp.Code(repo.secret.Secret, step)
+
+	if err := service.Verify(ctx, 1, current); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := service.Verify(ctx, 1, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("reused code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	// Код предыдущего шага еще в окне, но уже не новее принятого.
+	if err := service.Verify(ctx, 1, previous); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("older code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
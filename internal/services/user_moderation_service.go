@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"goida/internal/models"
+	"goida/internal/repository"
+)
+
+var (
+	ErrAccountDisabled    = errors.New("account is deleted or suspended")
+	ErrCannotModerateSelf = errors.New("administrators cannot delete or suspend themselves")
+	ErrEmailTaken         = errors.New("email is already taken")
+	ErrSuspensionExpired  = errors.New("suspension end must be in the future")
+)
+
+// UserModerationService - административное управление пользователями:
+// редактирование, мягкое удаление, восстановление и блокировка.
+type UserModerationService interface {
+	Update(ctx context.Context, actorID, userID int, req *models.AdminUpdateUserRequest, clientIP string) (*models.User, error)
+	Delete(ctx context.Context, actorID, userID int, clientIP string) error
+	Restore(ctx context.Context, actorID, userID int, clientIP string) (*models.User, error)
+	Suspend(ctx context.Context, actorID, userID int, req *models.SuspendUserRequest, clientIP string) (*models.User, error)
+	Unsuspend(ctx context.Context, actorID, userID int, clientIP string) (*models.User, error)
+}
+
+type userModerationService struct {
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	permissionRepo repository.PermissionRepository
+	auditRepo      repository.AuditRepository
+	authorizer     Authorizer
+}
+
+func NewUserModerationService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, permissionRepo repository.PermissionRepository, auditRepo repository.AuditRepository, authorizer Authorizer) UserModerationService {
+	return &userModerationService{
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		permissionRepo: permissionRepo,
+		auditRepo:      auditRepo,
+		authorizer:     authorizer,
+	}
+}
+
+// Update меняет имя и email. Новый email считается неподтвержденным.
+func (s *userModerationService) Update(ctx context.Context, actorID, userID int, req *models.AdminUpdateUserRequest, clientIP string) (*models.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	details, _, err := updateUserFields(s.userRepo, user, req.Name, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return user, nil
+	}
+	s.authorizer.ForgetUser(user.ID)
+	s.audit(ctx, userID, actorID, models.AuditUserUpdated, details, clientIP)
+
+	return s.getUser(userID)
+}
+
+// Delete выполняет мягкое удаление: пользователь не может войти, его сессии
+// завершаются, а контент показывается согласно DELETED_USER_CONTENT_POLICY.
+func (s *userModerationService) Delete(ctx context.Context, actorID, userID int, clientIP string) error {
+	user, err := s.prepareDisable(ctx, actorID, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted {
+		return nil
+	}
+
+	if err := s.userRepo.SetDeleted(userID, true); err != nil {
+		return err
+	}
+	s.signOut(ctx, userID)
+	s.audit(ctx, userID, actorID, models.AuditUserDeleted, nil, clientIP)
+	return nil
+}
+
+func (s *userModerationService) Restore(ctx context.Context, actorID, userID int, clientIP string) (*models.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsDeleted {
+		return user, nil
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetDeleted(userID, false); err != nil {
+		return nil, err
+	}
+	s.authorizer.ForgetUser(userID)
+	s.audit(ctx, userID, actorID, models.AuditUserRestored, nil, clientIP)
+
+	return s.getUser(userID)
+}
+
+func (s *userModerationService) Suspend(ctx context.Context, actorID, userID int, req *models.SuspendUserRequest, clientIP string) (*models.User, error) {
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return nil, ErrSuspensionExpired
+	}
+	if _, err := s.prepareDisable(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Suspend(userID, req.Until, req.Reason); err != nil {
+		return nil, err
+	}
+	s.signOut(ctx, userID)
+
+	details := map[string]interface{}{"reason": req.Reason}
+	if req.Until != nil {
+		details["until"] = req.Until.UTC().Format(time.RFC3339)
+	}
+	s.audit(ctx, userID, actorID, models.AuditUserSuspended, details, clientIP)
+
+	return s.getUser(userID)
+}
+
+func (s *userModerationService) Unsuspend(ctx context.Context, actorID, userID int, clientIP string) (*models.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt == nil {
+		return user, nil
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Unsuspend(userID); err != nil {
+		return nil, err
+	}
+	s.authorizer.ForgetUser(userID)
+	s.audit(ctx, userID, actorID, models.AuditUserUnsuspended, nil, clientIP)
+
+	return s.getUser(userID)
+}
+
+// updateUserFields сохраняет переданные имя и email. Смена email сбрасывает
+// подтверждение. details - запись для журнала, nil - ничего не изменилось.
+func updateUserFields(userRepo repository.UserRepository, user *models.User, name, email *string) (map[string]interface{}, bool, error) {
+	details := map[string]interface{}{}
+	if name != nil && *name != user.Name {
+		user.Name = *name
+		details["name_changed"] = true
+	}
+
+	emailChanged := false
+	if email != nil {
+		newEmail := strings.TrimSpace(*email)
+		if newEmail != user.Email {
+			if existing, err := userRepo.GetByEmail(newEmail); err == nil && existing.ID != user.ID {
+				return nil, false, ErrEmailTaken
+			}
+			details["old_email"] = user.Email
+			details["new_email"] = newEmail
+			user.Email = newEmail
+			emailChanged = true
+		}
+	}
+
+	if len(details) == 0 {
+		return nil, false, nil
+	}
+
+	if err := userRepo.Update(user); err != nil {
+		return nil, false, err
+	}
+	if emailChanged {
+		if err := userRepo.SetEmailVerified(user.ID, false); err != nil {
+			return nil, false, err
+		}
+	}
+	return details, emailChanged, nil
+}
+
+// prepareDisable проверяет, что пользователя можно удалить или
+// заблокировать: не себя, не пользователя с правами, которых нет у actorID,
+// и не последнего администратора.
+func (s *userModerationService) prepareDisable(ctx context.Context, actorID, userID int) (*models.User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModerateSelf
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutranks(ctx, s.authorizer, actorID, user); err != nil {
+		return nil, err
+	}
+
+	last, err := isLastRoleManager(ctx, s.authorizer, s.permissionRepo, user)
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		return nil, ErrLastAdmin
+	}
+	return user, nil
+}
+
+// signOut завершает сессии пользователя. Персональные токены не отзываются:
+// они отклоняются, пока учетная запись неактивна, и снова работают после
+// восстановления.
+func (s *userModerationService) signOut(ctx context.Context, userID int) {
+	if err := s.sessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+		logrus.Errorf("Failed to revoke sessions of user %d: %v", userID, err)
+	}
+	s.authorizer.ForgetUser(userID)
+}
+
+func (s *userModerationService) getUser(userID int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *userModerationService) audit(ctx context.Context, userID, actorID int, action string, details map[string]interface{}, clientIP string) {
+	event := &models.AuditEvent{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+		IP:      clientIP,
+	}
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logrus.Errorf("Failed to record audit event %s for user %d: %v", action, userID, err)
+	}
+}
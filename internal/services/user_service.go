@@ -1,11 +1,15 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sirupsen/logrus"
 
 	"goida/internal/models"
+	"goida/internal/pagination"
+	"goida/internal/password"
 	"goida/internal/repository"
 )
 
@@ -14,20 +18,26 @@ type UserService interface {
 	CreateUserWithCredentials(req *models.CreateUserRequest) (*models.User, error)
 	GetUser(id int) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
-	ListUsers(limit, offset int) ([]*models.User, error)
+	ListUsers(page pagination.Params) (pagination.Page[*models.User], error)
 }
 
 type userService struct {
 	userRepo            repository.UserRepository
 	roleRepo            repository.RoleRepository
 	authCredentialsRepo repository.AuthCredentialsRepository
+	hasher              *password.Hasher
+	passwordPolicy      *password.Policy
+	emailVerification   EmailVerificationService
 }
 
-func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, authCredentialsRepo repository.AuthCredentialsRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, authCredentialsRepo repository.AuthCredentialsRepository, hasher *password.Hasher, passwordPolicy *password.Policy, emailVerification EmailVerificationService) UserService {
 	return &userService{
 		userRepo:            userRepo,
 		roleRepo:            roleRepo,
 		authCredentialsRepo: authCredentialsRepo,
+		hasher:              hasher,
+		passwordPolicy:      passwordPolicy,
+		emailVerification:   emailVerification,
 	}
 }
 
@@ -56,6 +66,10 @@ func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 }
 
 func (s *userService) CreateUserWithCredentials(req *models.CreateUserRequest) (*models.User, error) {
+	if err := s.passwordPolicy.Check(req.Password, req.Login, req.Email); err != nil {
+		return nil, err
+	}
+
 	existingUser, err := s.userRepo.GetByEmail(req.Email)
 	if err == nil && existingUser != nil {
 		return nil, fmt.Errorf("user with email %s already exists", req.Email)
@@ -81,16 +95,16 @@ func (s *userService) CreateUserWithCredentials(req *models.CreateUserRequest) (
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		s.userRepo.Delete(user.ID)
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, err
 	}
 
 	credentials := &models.AuthCredentials{
 		UserID:   user.ID,
 		Login:    req.Login,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
 	if err := s.authCredentialsRepo.Create(credentials); err != nil {
@@ -103,6 +117,12 @@ func (s *userService) CreateUserWithCredentials(req *models.CreateUserRequest) (
 		return nil, fmt.Errorf("failed to get user with role: %w", err)
 	}
 
+	go func() {
+		if err := s.emailVerification.SendVerification(context.Background(), userWithRole); err != nil {
+			logrus.Errorf("Failed to send verification mail to user %d: %v", userWithRole.ID, err)
+		}
+	}()
+
 	return userWithRole, nil
 }
 
@@ -124,11 +144,17 @@ func (s *userService) GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (s *userService) ListUsers(limit, offset int) ([]*models.User, error) {
-	users, err := s.userRepo.List(limit, offset)
+func (s *userService) ListUsers(page pagination.Params) (pagination.Page[*models.User], error) {
+	users, err := s.userRepo.List(page)
+	if err != nil {
+		return pagination.Page[*models.User]{}, fmt.Errorf("failed to list users: %w", err)
+	}
+	total, err := s.userRepo.Count()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return pagination.Page[*models.User]{}, err
 	}
 
-	return users, nil
+	return pagination.NewPage(users, page, total, func(u *models.User) (time.Time, int64) {
+		return u.CreatedAt, int64(u.ID)
+	}), nil
 }
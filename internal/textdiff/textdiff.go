@@ -0,0 +1,213 @@
+// Package textdiff строит построчный diff двух текстов алгоритмом Майерса в
+// варианте с линейной памятью (поиск средней змейки).
+package textdiff
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxLines - наибольшее число различающихся строк (после отбрасывания общих
+// начала и конца) в обоих текстах вместе. Время сравнения растет как
+// произведение этого числа на число правок, поэтому большие тексты не
+// сравниваются.
+const MaxLines = 10000
+
+var ErrTooLarge = errors.New("texts are too large to diff")
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Edit - строка результата. OldLine и NewLine - номера строки (с 1) в
+// старом и новом тексте; для вставки OldLine равен 0, для удаления - NewLine.
+type Edit struct {
+	Op      Op
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// Lines сравнивает тексты построчно. Общие начало и конец отбрасываются до
+// запуска алгоритма, поэтому небольшая правка длинного текста обходится
+// дешево. Если остаток длиннее MaxLines строк, возвращается ErrTooLarge.
+func Lines(oldText, newText string) ([]Edit, error) {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if len(a)+len(b)-2*(prefix+suffix) > MaxLines {
+		return nil, ErrTooLarge
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, Edit{Op: Equal, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	for _, s := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		edit := Edit{Op: s.op}
+		if s.op != Insert {
+			edit.Text = a[prefix+s.ai]
+			edit.OldLine = prefix + s.ai + 1
+		}
+		if s.op != Delete {
+			edit.Text = b[prefix+s.bi]
+			edit.NewLine = prefix + s.bi + 1
+		}
+		edits = append(edits, edit)
+	}
+	for i := suffix; i > 0; i-- {
+		ai, bi := len(a)-i, len(b)-i
+		edits = append(edits, Edit{Op: Equal, Text: a[ai], OldLine: ai + 1, NewLine: bi + 1})
+	}
+	return edits, nil
+}
+
+// Changed сообщает, есть ли в diff вставки или удаления.
+func Changed(edits []Edit) bool {
+	for _, e := range edits {
+		if e.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+type step struct {
+	op     Op
+	ai, bi int
+}
+
+// differ хранит сравниваемые строки, накопленный сценарий и рабочие массивы
+// средней змейки, общие для всех уровней рекурсии.
+type differ struct {
+	a, b   []string
+	steps  []step
+	vf, vb []int
+}
+
+// myers ищет кратчайший сценарий правки за память O(len(a)+len(b)).
+func myers(a, b []string) []step {
+	d := &differ{a: a, b: b, steps: make([]step, 0, len(a)+len(b))}
+	d.compare(0, len(a), 0, len(b))
+	return d.steps
+}
+
+// compare дописывает сценарий для a[a0:a1] и b[b0:b1]: отбрасывает общие
+// начало и конец, делит остаток средней змейкой и сравнивает половины.
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.steps = append(d.steps, step{op: Equal, ai: a0, bi: b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	switch {
+	case a0 == a1:
+		for bi := b0; bi < b1; bi++ {
+			d.steps = append(d.steps, step{op: Insert, bi: bi})
+		}
+	case b0 == b1:
+		for ai := a0; ai < a1; ai++ {
+			d.steps = append(d.steps, step{op: Delete, ai: ai})
+		}
+	default:
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.steps = append(d.steps, step{op: Equal, ai: x, bi: y})
+		}
+		d.compare(u, a1, v, b1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.steps = append(d.steps, step{op: Equal, ai: a1 + i, bi: b1 + i})
+	}
+}
+
+// middleSnake ищет змейку (x, y)-(u, v) посередине кратчайшего пути от
+// начала к концу a[a0:a1] и b[b0:b1], ведя поиск с обоих концов. vf[k] -
+// самый дальний x на диагонали k = x - y прямого поиска, vb[c] - то же для
+// обратного поиска по перевернутым строкам.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	off := m + 1
+
+	size := n + m + 3
+	if cap(d.vf) < size {
+		d.vf, d.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := d.vf[:size], d.vb[:size]
+	clear(vf)
+	clear(vb)
+
+	for h := 0; h <= (n+m+1)/2; h++ {
+		// Диагонали вне прямоугольника n x m пропускаются.
+		lo, hi := -(h - 2*max(0, h-m)), h-2*max(0, h-n)
+
+		for k := lo; k <= hi; k += 2 {
+			var s int
+			if k == -h || (k != h && vf[k-1+off] < vf[k+1+off]) {
+				s = vf[k+1+off]
+			} else {
+				s = vf[k-1+off] + 1
+			}
+			t := s - k
+			x, y := s, t
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			vf[k+off] = x
+			if c := delta - k; odd && c >= -(h-1) && c <= h-1 && x+vb[c+off] >= n {
+				return a0 + s, b0 + t, a0 + x, b0 + y
+			}
+		}
+
+		for c := lo; c <= hi; c += 2 {
+			var s int
+			if c == -h || (c != h && vb[c-1+off] < vb[c+1+off]) {
+				s = vb[c+1+off]
+			} else {
+				s = vb[c-1+off] + 1
+			}
+			t := s - c
+			x, y := s, t
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			vb[c+off] = x
+			if k := delta - c; !odd && k >= -h && k <= h && x+vf[k+off] >= n {
+				return a0 + n - x, b0 + m - y, a0 + n - s, b0 + m - t
+			}
+		}
+	}
+	panic("textdiff: middle snake not found")
+}
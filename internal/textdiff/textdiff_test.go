@@ -0,0 +1,149 @@
+package textdiff
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// apply восстанавливает оба текста из diff и проверяет номера строк.
+func apply(t *testing.T, edits []Edit) (oldLines, newLines []string) {
+	t.Helper()
+	for _, e := range edits {
+		if e.Op != Insert {
+			oldLines = append(oldLines, e.Text)
+			if e.OldLine != len(oldLines) {
+				t.Fatalf("edit %+v: old line = %d, want %d", e, e.OldLine, len(oldLines))
+			}
+		}
+		if e.Op != Delete {
+			newLines = append(newLines, e.Text)
+			if e.NewLine != len(newLines) {
+				t.Fatalf("edit %+v: new line = %d, want %d", e, e.NewLine, len(newLines))
+			}
+		}
+	}
+	return oldLines, newLines
+}
+
+// lcs - длина наибольшей общей подпоследовательности: кратчайший diff
+// содержит ровно столько строк equal.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func countOps(edits []Edit) map[Op]int {
+	counts := map[Op]int{}
+	for _, e := range edits {
+		counts[e.Op]++
+	}
+	return counts
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb", "=a =b"},
+		{"both empty", "", "", ""},
+		{"insert into empty", "", "a\nb", "+a +b"},
+		{"delete all", "a\nb", "", "-a -b"},
+		{"change in the middle", "a\nb\nc", "a\nx\nc", "=a -b +x =c"},
+		{"common prefix", "a\nb\nc", "a\nb\nc\nd", "=a =b =c +d"},
+		{"common suffix", "x\nb\nc", "b\nc", "-x =b =c"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", "=a =b"},
+		{"moved line", "a\nb\nc\nd", "b\nc\nd\na", "-a =b =c =d +a"},
+	}
+	symbols := map[Op]string{Equal: "=", Insert: "+", Delete: "-"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits, err := Lines(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range edits {
+				got = append(got, symbols[e.Op]+e.Text)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Lines = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+			apply(t, edits)
+		})
+	}
+}
+
+func TestLinesIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		edits, err := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldLines, newLines := apply(t, edits)
+		if strings.Join(oldLines, "\n") != strings.Join(a, "\n") || strings.Join(newLines, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diff of %q and %q does not reproduce the texts", a, b)
+		}
+		if got, want := countOps(edits)[Equal], lcs(a, b); got != want {
+			t.Fatalf("diff of %q and %q keeps %d lines, want %d", a, b, got, want)
+		}
+	}
+}
+
+func TestLinesTrimsCommonPrefixAndSuffix(t *testing.T) {
+	// Одинаковые начало и конец не учитываются в MaxLines.
+	common := strings.Repeat("same\n", MaxLines)
+	edits, err := Lines(common+"old\n"+common, common+"new\n"+common)
+	if err != nil {
+		t.Fatalf("Lines: %v", err)
+	}
+	counts := countOps(edits)
+	if counts[Delete] != 1 || counts[Insert] != 1 || counts[Equal] != 2*MaxLines {
+		t.Errorf("ops = %v", counts)
+	}
+	if e := edits[MaxLines]; e.Op != Delete || e.Text != "old" || e.OldLine != MaxLines+1 {
+		t.Errorf("edit at the change = %+v", e)
+	}
+}
+
+func TestLinesRejectsLargeDiffs(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "%s%d\n", prefix, i)
+		}
+		return sb.String()
+	}
+
+	if _, err := Lines(lines("a", MaxLines/2), lines("b", MaxLines/2)); err != nil {
+		t.Errorf("diff at the limit: %v", err)
+	}
+	if _, err := Lines(lines("a", MaxLines/2+1), lines("b", MaxLines/2)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
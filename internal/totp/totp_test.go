@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ "12345678901234567890" из приложения B RFC 6238 (SHA-1)
+// в base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Коды RFC 6238 восьмизначные; шестизначный код - их последние шесть цифр.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %q, %v; want 287082", got, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateAllowsOneStepOfSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for _, offset := range []int64{-1, 0, 1} {
+		code, _ := Code(rfcSecret, current+offset)
+		step, ok := Validate(rfcSecret, code, now)
+		if !ok || step != current+offset {
+			t.Errorf("offset %d: Validate = %d, %v; want %d, true", offset, step, ok, current+offset)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code, _ := Code(rfcSecret, current+offset)
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("offset %d: code accepted", offset)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := Validate(rfcSecret, " 287082 ", now); !ok {
+		t.Error("code with surrounding spaces rejected")
+	}
+	for _, code := range []string{"", "28708", "2870820", "94287082", "000000"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate(%q) accepted", code)
+		}
+	}
+}
+
+// Validate возвращает один и тот же шаг для повторного кода - по нему
+// вызывающий запрещает повторное использование.
+func TestValidateReportsStepForReuseCheck(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	first, ok1 := Validate(rfcSecret, "081804", now)
+	second, ok2 := Validate(rfcSecret, "081804", now.Add(Period*time.Second))
+	if !ok1 || !ok2 || first != second {
+		t.Errorf("steps = %d (%v), %d (%v); want the same step", first, ok1, second, ok2)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("GoIda", "ivan@example.com", rfcSecret)
+	want := "otpauth://totp/GoIda:ivan@example.com?algorithm=SHA1&digits=6&issuer=GoIda&period=30&secret=" + rfcSecret
+	if uri != want {
+		t.Errorf("URI = %q, want %q", uri, want)
+	}
+}
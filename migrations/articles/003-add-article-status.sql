@@ -0,0 +1,14 @@
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published';
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS chk_articles_status;
+ALTER TABLE articles ADD CONSTRAINT chk_articles_status
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+
+UPDATE articles SET publish_at = created_at WHERE status = 'published' AND publish_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_articles_status ON articles(status);
+CREATE INDEX IF NOT EXISTS idx_articles_scheduled ON articles(publish_at) WHERE status = 'scheduled';
+
+COMMENT ON COLUMN articles.status IS 'Состояние статьи: draft, scheduled, published, archived';
+COMMENT ON COLUMN articles.publish_at IS 'Время публикации: запланированное для scheduled, фактическое для published';
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS article_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    restored_from INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (article_id, revision)
+);
+
+COMMENT ON TABLE article_revisions IS 'Неизменяемые версии статей: новая версия создается при каждом изменении';
+COMMENT ON COLUMN article_revisions.revision IS 'Номер версии в пределах статьи, начиная с 1';
+COMMENT ON COLUMN article_revisions.editor_id IS 'Кто внес изменение';
+COMMENT ON COLUMN article_revisions.restored_from IS 'Номер версии, из которой восстановлен текст';
+
+-- Текущий текст существующих статей становится их первой версией.
+INSERT INTO article_revisions (article_id, revision, title, content, editor_id, created_at)
+SELECT id, 1, title, content, author_id, COALESCE(updated_at, created_at, NOW())
+FROM articles
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,13 @@
+-- Заголовок весит больше текста. Статьи индексируются и русской, и
+-- английской конфигурацией, чтобы поиск находил обе морфологии.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(content, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(content, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING GIN (search_vector);
+
+COMMENT ON COLUMN articles.search_vector IS 'Полнотекстовый индекс заголовка и текста (russian + english)';
@@ -0,0 +1,44 @@
+-- Slug статьи строится из заголовка с транслитерацией кириллицы и уникален.
+-- Прежние slug переименованных статей хранятся в article_slugs и продолжают
+-- открывать статью.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS slug VARCHAR(120);
+
+-- Существующие статьи получают slug по тем же правилам, что и в приложении;
+-- совпадающие slug дополняются id статьи.
+WITH base AS (
+    SELECT id,
+        rtrim(left(trim(both '-' FROM regexp_replace(
+            translate(
+                replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+                    lower(title),
+                    'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'), 'ш', 'sh'),
+                    'ю', 'yu'), 'я', 'ya'), 'ё', 'yo'), 'ъ', ''), 'ь', ''), 'й', 'y'),
+                'абвгдезиклмнопрстуфыэ', 'abvgdeziklmnoprstufye'),
+            '[^a-z0-9]+', '-', 'g')), 100), '-') AS slug
+    FROM articles
+    WHERE slug IS NULL
+), ranked AS (
+    SELECT id,
+        CASE WHEN slug = '' THEN 'article' ELSE slug END AS slug,
+        row_number() OVER (PARTITION BY CASE WHEN slug = '' THEN 'article' ELSE slug END ORDER BY id) AS rn
+    FROM base
+)
+UPDATE articles a
+SET slug = CASE WHEN r.rn = 1 AND r.slug <> 'article' THEN r.slug ELSE r.slug || '-' || a.id END
+FROM ranked r
+WHERE r.id = a.id;
+
+ALTER TABLE articles ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_articles_slug ON articles(slug);
+
+COMMENT ON COLUMN articles.slug IS 'Человекочитаемый идентификатор статьи в URL';
+
+CREATE TABLE IF NOT EXISTS article_slugs (
+    slug VARCHAR(120) PRIMARY KEY,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_slugs_article_id ON article_slugs(article_id);
+
+COMMENT ON TABLE article_slugs IS 'Прежние slug статей: по ним отдается перенаправление на текущий';
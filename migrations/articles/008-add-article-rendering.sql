@@ -0,0 +1,17 @@
+-- Формат текста статьи и отрисованный из него HTML. HTML, анонс и оглавление
+-- перестраиваются приложением, если render_version отстает от версии правил
+-- отрисовки; у существующих статей они заполнятся при первом чтении.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS content_html TEXT;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS excerpt TEXT;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS toc JSONB;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS render_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_content_format_check;
+ALTER TABLE articles ADD CONSTRAINT articles_content_format_check CHECK (content_format IN ('plain', 'markdown'));
+
+COMMENT ON COLUMN articles.content_format IS 'Формат текста: plain или markdown';
+COMMENT ON COLUMN articles.content_html IS 'Безопасный HTML, отрисованный из content';
+COMMENT ON COLUMN articles.excerpt IS 'Анонс - начало текста без разметки';
+COMMENT ON COLUMN articles.toc IS 'Оглавление по заголовкам: [{"level":1,"text":"...","id":"..."}]';
+COMMENT ON COLUMN articles.render_version IS 'Версия правил отрисовки, которой построен content_html';
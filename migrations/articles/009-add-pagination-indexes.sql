@@ -0,0 +1,7 @@
+-- Индексы по ключам сортировки списков для keyset-пагинации: лента статей
+-- сортируется по времени публикации (или создания) и id, комментарии статьи -
+-- по времени создания и id, как и версии статьи.
+CREATE INDEX IF NOT EXISTS idx_articles_feed ON articles ((COALESCE(publish_at, created_at)) DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_author_feed ON articles (author_id, (COALESCE(publish_at, created_at)) DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_comments_article_created ON comments (article_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_article_revisions_article_created ON article_revisions (article_id, created_at DESC, id DESC);
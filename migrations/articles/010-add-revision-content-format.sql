@@ -0,0 +1,17 @@
+-- Формат текста версии: при восстановлении версии статья получает и текст, и
+-- формат, в котором он был написан. Существующим версиям достается текущий
+-- формат статьи - смена формата посреди истории маловероятна.
+ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS content_format VARCHAR(16);
+
+UPDATE article_revisions r
+SET content_format = a.content_format
+FROM articles a
+WHERE r.article_id = a.id AND r.content_format IS NULL;
+
+ALTER TABLE article_revisions ALTER COLUMN content_format SET DEFAULT 'plain';
+ALTER TABLE article_revisions ALTER COLUMN content_format SET NOT NULL;
+
+ALTER TABLE article_revisions DROP CONSTRAINT IF EXISTS article_revisions_content_format_check;
+ALTER TABLE article_revisions ADD CONSTRAINT article_revisions_content_format_check CHECK (content_format IN ('plain', 'markdown'));
+
+COMMENT ON COLUMN article_revisions.content_format IS 'Формат текста версии: plain или markdown';
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE auth_sessions IS 'Сессии пользователей (семейства refresh-токенов)';
+COMMENT ON COLUMN auth_sessions.id IS 'Идентификатор сессии (передается в claims access-токена)';
+COMMENT ON COLUMN auth_sessions.user_id IS 'Ссылка на пользователя';
+COMMENT ON COLUMN auth_sessions.created_at IS 'Дата и время входа в систему';
+COMMENT ON COLUMN auth_sessions.revoked_at IS 'Дата и время отзыва сессии (NULL - сессия активна)';
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user_id ON auth_sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rotated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES auth_sessions(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE refresh_tokens IS 'Refresh-токены (хранятся только хеши)';
+COMMENT ON COLUMN refresh_tokens.id IS 'Уникальный идентификатор токена';
+COMMENT ON COLUMN refresh_tokens.session_id IS 'Ссылка на сессию, к которой относится токен';
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'SHA-256 хеш токена';
+COMMENT ON COLUMN refresh_tokens.expires_at IS 'Дата и время истечения токена';
+COMMENT ON COLUMN refresh_tokens.rotated_at IS 'Дата и время обмена токена на новый (повторное использование - признак утечки)';
+COMMENT ON COLUMN refresh_tokens.created_at IS 'Дата и время выпуска токена';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE login_attempts IS 'Счетчики неудачных попыток входа (используются при LOGIN_ATTEMPT_STORE=postgres)';
+COMMENT ON COLUMN login_attempts.key IS 'Ключ счетчика: login:<логин> или ip:<адрес клиента>';
+COMMENT ON COLUMN login_attempts.failures IS 'Количество неудачных попыток подряд';
+COMMENT ON COLUMN login_attempts.last_failure_at IS 'Дата и время последней неудачной попытки';
+COMMENT ON COLUMN login_attempts.locked_until IS 'До какого момента попытки входа отклоняются';
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts(locked_until);
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE user_totp IS 'TOTP-секреты для двухфакторной аутентификации (RFC 6238)';
+COMMENT ON COLUMN user_totp.user_id IS 'Ссылка на пользователя';
+COMMENT ON COLUMN user_totp.secret IS 'Секрет в base32';
+COMMENT ON COLUMN user_totp.confirmed_at IS 'Дата и время подтверждения (NULL - подключение не завершено, 2FA выключена)';
+COMMENT ON COLUMN user_totp.last_used_step IS 'Номер последнего принятого 30-секундного шага (защита от повторного использования кода)';
+COMMENT ON COLUMN user_totp.created_at IS 'Дата и время генерации секрета';
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE recovery_codes IS 'Одноразовые коды восстановления для входа без TOTP';
+COMMENT ON COLUMN recovery_codes.id IS 'Уникальный идентификатор кода';
+COMMENT ON COLUMN recovery_codes.user_id IS 'Ссылка на пользователя';
+COMMENT ON COLUMN recovery_codes.code_hash IS 'SHA-256 хеш кода';
+COMMENT ON COLUMN recovery_codes.used_at IS 'Дата и время использования (NULL - код еще действует)';
+COMMENT ON COLUMN recovery_codes.created_at IS 'Дата и время выпуска кода';
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE;
+COMMENT ON COLUMN auth_sessions.mfa IS 'Вход выполнен с подтверждением второго фактора';
+
+CREATE TABLE IF NOT EXISTS security_settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE security_settings IS 'Политики безопасности, изменяемые администратором';
+COMMENT ON COLUMN security_settings.key IS 'Название настройки';
+COMMENT ON COLUMN security_settings.value IS 'Значение настройки';
+COMMENT ON COLUMN security_settings.updated_at IS 'Дата и время последнего изменения';
+
+INSERT INTO security_settings (key, value) VALUES ('require_2fa_for_admin', 'false')
+ON CONFLICT (key) DO NOTHING;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE password_reset_tokens IS 'Одноразовые токены сброса пароля (хранятся только хеши)';
+COMMENT ON COLUMN password_reset_tokens.id IS 'Уникальный идентификатор токена';
+COMMENT ON COLUMN password_reset_tokens.user_id IS 'Ссылка на пользователя';
+COMMENT ON COLUMN password_reset_tokens.token_hash IS 'SHA-256 хеш токена';
+COMMENT ON COLUMN password_reset_tokens.expires_at IS 'Дата и время истечения токена';
+COMMENT ON COLUMN password_reset_tokens.used_at IS 'Дата и время использования (NULL - токен не использован)';
+COMMENT ON COLUMN password_reset_tokens.created_at IS 'Дата и время выпуска токена';
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
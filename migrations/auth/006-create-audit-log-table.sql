@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER,
+    actor_id INTEGER,
+    action VARCHAR(100) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    ip VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+COMMENT ON TABLE audit_log IS 'Журнал действий, затрагивающих безопасность учетных записей';
+COMMENT ON COLUMN audit_log.id IS 'Уникальный идентификатор записи';
+COMMENT ON COLUMN audit_log.user_id IS 'Пользователь, над учетной записью которого выполнено действие';
+COMMENT ON COLUMN audit_log.actor_id IS 'Пользователь, выполнивший действие (NULL - действие по ссылке из письма)';
+COMMENT ON COLUMN audit_log.action IS 'Тип действия, например credentials.password_changed';
+COMMENT ON COLUMN audit_log.details IS 'Дополнительные сведения о действии (без паролей и хешей)';
+COMMENT ON COLUMN audit_log.ip IS 'IP-адрес, с которого выполнено действие';
+COMMENT ON COLUMN audit_log.created_at IS 'Дата и время действия';
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id, created_at DESC);
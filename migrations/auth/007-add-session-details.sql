@@ -0,0 +1,9 @@
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS ip VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+COMMENT ON COLUMN auth_sessions.user_agent IS 'User-Agent клиента, с которого выполнен вход';
+COMMENT ON COLUMN auth_sessions.ip IS 'IP-адрес клиента при входе или последнем обращении';
+COMMENT ON COLUMN auth_sessions.last_seen_at IS 'Дата и время последнего обращения (обновляется не чаще SESSION_TOUCH_INTERVAL)';
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user_last_seen ON auth_sessions(user_id, last_seen_at DESC) WHERE revoked_at IS NULL;
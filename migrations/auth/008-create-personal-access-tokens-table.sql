@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    token_hint VARCHAR(32) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE personal_access_tokens IS 'Персональные токены доступа для скриптов и CI (хранятся только хеши)';
+COMMENT ON COLUMN personal_access_tokens.id IS 'Уникальный идентификатор токена';
+COMMENT ON COLUMN personal_access_tokens.user_id IS 'Владелец токена';
+COMMENT ON COLUMN personal_access_tokens.name IS 'Название токена, заданное владельцем';
+COMMENT ON COLUMN personal_access_tokens.token_hash IS 'SHA-256 хеш токена';
+COMMENT ON COLUMN personal_access_tokens.token_hint IS 'Начало токена, по которому владелец узнает его в списке';
+COMMENT ON COLUMN personal_access_tokens.scopes IS 'Разрешенные области доступа (articles:write, comments:write, ...)';
+COMMENT ON COLUMN personal_access_tokens.expires_at IS 'Дата и время истечения токена';
+COMMENT ON COLUMN personal_access_tokens.last_used_at IS 'Дата и время последнего использования';
+COMMENT ON COLUMN personal_access_tokens.last_used_ip IS 'IP-адрес клиента при последнем использовании';
+COMMENT ON COLUMN personal_access_tokens.created_at IS 'Дата и время выпуска токена';
+COMMENT ON COLUMN personal_access_tokens.revoked_at IS 'Дата и время отзыва (NULL - токен действует)';
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
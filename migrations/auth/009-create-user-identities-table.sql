@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE user_identities IS 'Учетные записи внешних OIDC-провайдеров, привязанные к пользователям';
+COMMENT ON COLUMN user_identities.id IS 'Уникальный идентификатор привязки';
+COMMENT ON COLUMN user_identities.user_id IS 'Ссылка на пользователя';
+COMMENT ON COLUMN user_identities.provider IS 'Идентификатор провайдера из OIDC_PROVIDERS';
+COMMENT ON COLUMN user_identities.subject IS 'Идентификатор пользователя у провайдера (claim sub)';
+COMMENT ON COLUMN user_identities.email IS 'Email, полученный от провайдера при последнем входе';
+COMMENT ON COLUMN user_identities.created_at IS 'Дата и время привязки';
+COMMENT ON COLUMN user_identities.last_login_at IS 'Дата и время последнего входа через провайдера';
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,4 @@
+-- Журнал листается курсором по (created_at, id): индекс по user_id и
+-- created_at не упорядочивает события с одинаковым временем.
+DROP INDEX IF EXISTS idx_audit_log_user_id;
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_created ON audit_log (user_id, created_at DESC, id DESC);
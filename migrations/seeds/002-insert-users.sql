@@ -1,7 +1,7 @@
-INSERT INTO users (email, name, role_id) VALUES 
-    ('admin@example.com', 'Admin User', (SELECT id FROM roles WHERE name = 'admin')),
-    ('user@example.com', 'Regular User', (SELECT id FROM roles WHERE name = 'user')),
-    ('user2@example.com', 'Regular User 2', (SELECT id FROM roles WHERE name = 'user'))
+INSERT INTO users (email, name, role_id, email_verified_at) VALUES 
+    ('admin@example.com', 'Admin User', (SELECT id FROM roles WHERE name = 'admin'), CURRENT_TIMESTAMP),
+    ('user@example.com', 'Regular User', (SELECT id FROM roles WHERE name = 'user'), CURRENT_TIMESTAMP),
+    ('user2@example.com', 'Regular User 2', (SELECT id FROM roles WHERE name = 'user'), CURRENT_TIMESTAMP)
 ON CONFLICT (email) DO UPDATE SET 
     name = EXCLUDED.name,
     role_id = EXCLUDED.role_id;
\ No newline at end of file
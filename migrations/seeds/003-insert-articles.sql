@@ -1,5 +1,5 @@
-INSERT INTO articles (title, content, author_id) VALUES 
-('Первая статья', 'Содержимое первой статьи для тестирования системы.', 1),
-('Вторая статья', 'Содержимое второй статьи с более подробным описанием.', 1),
-('Статья от другого автора', 'Статья, созданная другим пользователем.', 2)
+INSERT INTO articles (title, slug, content, author_id) VALUES 
+('Первая статья', 'pervaya-statya', 'Содержимое первой статьи для тестирования системы.', 1),
+('Вторая статья', 'vtoraya-statya', 'Содержимое второй статьи с более подробным описанием.', 1),
+('Статья от другого автора', 'statya-ot-drugogo-avtora', 'Статья, созданная другим пользователем.', 2)
 ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Уже существующие пользователи считаются подтвержденными: значение по
+-- умолчанию заполняет старые строки и сразу снимается для новых.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+
+COMMENT ON COLUMN users.email_verified_at IS 'Дата и время подтверждения email (NULL - email не подтвержден)';
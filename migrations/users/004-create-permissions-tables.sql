@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+COMMENT ON TABLE permissions IS 'Справочник прав доступа';
+COMMENT ON COLUMN permissions.name IS 'Код права (например, article.update.any)';
+COMMENT ON COLUMN permissions.description IS 'Описание права';
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission) REFERENCES permissions(name) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE role_permissions IS 'Права, выданные ролям';
+COMMENT ON COLUMN role_permissions.role_id IS 'Ссылка на роль';
+COMMENT ON COLUMN role_permissions.permission IS 'Ссылка на право';
+
+INSERT INTO permissions (name, description) VALUES
+('article.update.any', 'Редактирование любых статей'),
+('article.delete.any', 'Удаление любых статей'),
+('comment.delete.any', 'Удаление любых комментариев'),
+('user.manage', 'Управление пользователями, их учетными данными и токенами'),
+('role.manage', 'Просмотр и управление ролями'),
+('audit.read', 'Просмотр журнала аудита'),
+('security.manage', 'Блокировки входа и политика двухфакторной аутентификации')
+ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description;
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN users.deleted_at IS 'Дата и время мягкого удаления';
+COMMENT ON COLUMN users.suspended_at IS 'Дата и время блокировки пользователя (NULL - не заблокирован)';
+COMMENT ON COLUMN users.suspended_until IS 'Срок блокировки (NULL - бессрочно)';
+COMMENT ON COLUMN users.suspension_reason IS 'Причина блокировки';
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+COMMENT ON COLUMN users.deactivated_at IS 'Дата и время деактивации учетной записи самим пользователем (NULL - активна)';
+COMMENT ON COLUMN users.deletion_scheduled_at IS 'Когда учетная запись будет удалена окончательно (NULL - удаление не запрошено)';
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    requested_by INTEGER,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    archive BYTEA,
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (requested_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+COMMENT ON TABLE data_exports IS 'Выгрузки персональных данных пользователей';
+COMMENT ON COLUMN data_exports.id IS 'Уникальный идентификатор выгрузки';
+COMMENT ON COLUMN data_exports.user_id IS 'Пользователь, чьи данные выгружаются';
+COMMENT ON COLUMN data_exports.requested_by IS 'Кто запросил выгрузку (сам пользователь или администратор)';
+COMMENT ON COLUMN data_exports.status IS 'pending - формируется, ready - готова, failed - ошибка';
+COMMENT ON COLUMN data_exports.error IS 'Причина ошибки формирования';
+COMMENT ON COLUMN data_exports.archive IS 'ZIP-архив с JSON-файлами';
+COMMENT ON COLUMN data_exports.size IS 'Размер архива в байтах';
+COMMENT ON COLUMN data_exports.created_at IS 'Дата и время запроса';
+COMMENT ON COLUMN data_exports.completed_at IS 'Дата и время завершения формирования';
+COMMENT ON COLUMN data_exports.expires_at IS 'После этого момента выгрузка удаляется';
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at);
@@ -0,0 +1,10 @@
+INSERT INTO permissions (name, description) VALUES
+('user.impersonate', 'Вход от имени другого пользователя для поддержки')
+ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description;
+
+-- На существующей базе право сразу получает admin; на новой роли еще не
+-- созданы, и права администратору выдает seeds/005.
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'user.impersonate' FROM roles r
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
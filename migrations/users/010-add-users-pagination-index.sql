@@ -0,0 +1,2 @@
+-- Индекс для keyset-пагинации списка пользователей по времени создания и id.
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN users.verification_sent_at IS 'Дата и время отправки последнего письма подтверждения email';